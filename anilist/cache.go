@@ -72,6 +72,16 @@ var relationCacher = &cacher[string, int]{
 	keyWrapper: normalizedName,
 }
 
+var mappingCacher = &cacher[string, *Mapping]{
+	internal: gache.New[*cacheData[string, *Mapping]](
+		&gache.Options{
+			Path:       where.AnilistMappings(),
+			FileSystem: &filesystem.GacheFs{},
+		},
+	),
+	keyWrapper: normalizedName,
+}
+
 var searchCacher = &cacher[string, []int]{
 	internal: gache.New[*cacheData[string, []int]](
 		&gache.Options{
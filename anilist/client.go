@@ -0,0 +1,319 @@
+package anilist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/network"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/mo"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Endpoint is the Anilist GraphQL API endpoint
+	Endpoint = "https://graphql.anilist.co"
+
+	// requestsPerMinute is the maximum amount of requests Anilist allows per minute
+	requestsPerMinute = 90
+
+	// maxRetries is how many times rate limited request will be retried
+	maxRetries = 3
+
+	// defaultRetryAfter is used when Anilist does not specify Retry-After header
+	defaultRetryAfter = time.Minute
+)
+
+// Error is a single error returned by the Anilist GraphQL API
+type Error struct {
+	Message   string `json:"message"`
+	Status    int    `json:"status"`
+	Locations []struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"locations"`
+}
+
+func (e *Error) Error() string {
+	if e.Status == 0 {
+		return "anilist: " + e.Message
+	}
+
+	return fmt.Sprintf("anilist: %s (status %d)", e.Message, e.Status)
+}
+
+// Errors is a list of errors returned in the "errors" field of the GraphQL response
+type Errors []*Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// NotFound returns true if every error is a "not found" error.
+// Anilist returns them for missing media in the aliased batch queries.
+func (e Errors) NotFound() bool {
+	for _, err := range e {
+		if err.Status != http.StatusNotFound {
+			return false
+		}
+	}
+
+	return len(e) > 0
+}
+
+// StatusError is returned when Anilist responds with unexpected status code
+// and without any GraphQL errors to explain it
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("invalid response code %d", e.Code)
+}
+
+func (e *StatusError) Kind() errs.Kind {
+	return errs.Network
+}
+
+// RateLimitError is returned when the request was still rate limited after all retries
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("anilist rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+func (e *RateLimitError) Kind() errs.Kind {
+	return errs.RateLimited
+}
+
+// limiter queues requests so that no more than limit requests are sent within window
+type limiter struct {
+	mutex        sync.Mutex
+	limit        int
+	window       time.Duration
+	sent         []time.Time
+	blockedUntil time.Time
+}
+
+func newLimiter(limit int, window time.Duration) *limiter {
+	return &limiter{
+		limit:  limit,
+		window: window,
+	}
+}
+
+// wait blocks until the next request can be sent.
+// The delay is computed under the lock and slept without it,
+// so that block is not delayed by the waiting callers.
+func (l *limiter) wait() {
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return
+		}
+
+		time.Sleep(delay)
+	}
+}
+
+// reserve takes a slot for the request and returns 0 if it can be sent now.
+// Otherwise, it returns how long to wait before trying again.
+func (l *limiter) reserve() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if now.Before(l.blockedUntil) {
+		return l.blockedUntil.Sub(now)
+	}
+
+	// forget requests that are out of the window
+	for len(l.sent) > 0 && now.Sub(l.sent[0]) >= l.window {
+		l.sent = l.sent[1:]
+	}
+
+	if len(l.sent) < l.limit {
+		l.sent = append(l.sent, now)
+		return 0
+	}
+
+	return l.window - now.Sub(l.sent[0])
+}
+
+// block prevents any requests from being sent for the given duration
+func (l *limiter) block(d time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// Client is a GraphQL client for Anilist that respects its rate limits
+type Client struct {
+	http     *http.Client
+	endpoint string
+	limiter  *limiter
+}
+
+// NewClient creates a new Anilist client that sends requests to the given endpoint
+func NewClient(httpClient *http.Client, endpoint string) *Client {
+	return &Client{
+		http:     httpClient,
+		endpoint: endpoint,
+		limiter:  newLimiter(requestsPerMinute, time.Minute),
+	}
+}
+
+// DefaultClient is the client shared by everything that talks to Anilist
+var DefaultClient = NewClient(network.Client, Endpoint)
+
+// Query sends the query with the given variables and decodes the "data" field into dst.
+// If token is not empty, it will be used for authorization.
+// GraphQL errors are returned as Errors. If the response also contains data,
+// it is still decoded into dst so that partial results of batched queries can be used.
+func (c *Client) Query(query string, variables map[string]any, token string, dst any) error {
+	body, err := json.Marshal(map[string]any{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	for try := 0; ; try++ {
+		c.limiter.wait()
+
+		retryAfter, err := c.send(body, token, dst)
+		if retryAfter.IsAbsent() {
+			return err
+		}
+
+		wait := retryAfter.MustGet()
+		if try >= maxRetries {
+			return &RateLimitError{RetryAfter: wait}
+		}
+
+		log.Warnf("Anilist rate limit exceeded, retrying in %s", wait)
+		c.limiter.block(wait)
+	}
+}
+
+// send performs a single request.
+// If request was rate limited, duration to wait before the next try is returned.
+func (c *Client) send(body []byte, token string, dst any) (retryAfter mo.Option[time.Duration], err error) {
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	log.Info("Sending request to Anilist")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	defer util.Ignore(resp.Body.Close)
+
+	c.observe(resp.Header)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter = mo.Some(parseRetryAfter(resp.Header.Get("Retry-After")))
+		return
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors Errors          `json:"errors"`
+	}
+
+	if err = json.Unmarshal(raw, &response); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			err = &StatusError{Code: resp.StatusCode}
+		}
+
+		log.Error(err)
+		return
+	}
+
+	hasData := len(response.Data) > 0 && string(response.Data) != "null"
+	if hasData && dst != nil {
+		if err = json.Unmarshal(response.Data, dst); err != nil {
+			log.Error(err)
+			return
+		}
+	}
+
+	if len(response.Errors) > 0 {
+		err = response.Errors
+		log.Error(err)
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err = &StatusError{Code: resp.StatusCode}
+		log.Error(err)
+	}
+
+	return
+}
+
+// observe checks rate limit headers and pauses the queue if there are no requests left
+func (c *Client) observe(header http.Header) {
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	c.limiter.block(time.Until(time.Unix(reset, 0)))
+}
+
+// parseRetryAfter parses Retry-After header which is either seconds or http date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return defaultRetryAfter
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(util.Max(seconds, 0)) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return util.Max(time.Until(date), 0)
+	}
+
+	return defaultRetryAfter
+}
@@ -0,0 +1,203 @@
+package anilist
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/metafates/mangal/filesystem"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func withServer(handler http.HandlerFunc, f func(client *Client)) {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	f(NewClient(server.Client(), server.URL))
+}
+
+func TestClient_Query(t *testing.T) {
+	Convey("Given a rate limited Anilist", t, func() {
+		var (
+			requests      int32
+			authorization string
+		)
+		withServer(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			authorization = r.Header.Get("Authorization")
+			_, _ = w.Write([]byte(`{"data": {"Viewer": {"id": 1}}}`))
+		}, func(client *Client) {
+			Convey("When I send a query", func() {
+				var response struct {
+					Viewer struct {
+						ID int `json:"id"`
+					} `json:"Viewer"`
+				}
+
+				err := client.Query("query { Viewer { id } }", nil, "token", &response)
+				Convey("Then it should be retried", func() {
+					So(err, ShouldBeNil)
+					So(requests, ShouldEqual, 2)
+					So(authorization, ShouldEqual, "Bearer token")
+					So(response.Viewer.ID, ShouldEqual, 1)
+				})
+			})
+		})
+	})
+
+	Convey("Given an Anilist that is always rate limited", t, func() {
+		withServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, func(client *Client) {
+			Convey("When I send a query", func() {
+				err := client.Query("query { Viewer { id } }", nil, "", nil)
+				Convey("Then it should return rate limit error", func() {
+					var rateLimitErr *RateLimitError
+					So(errors.As(err, &rateLimitErr), ShouldBeTrue)
+				})
+			})
+		})
+	})
+
+	Convey("Given an Anilist that returns GraphQL errors", t, func() {
+		withServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "Invalid token", "status": 400}]}`))
+		}, func(client *Client) {
+			Convey("When I send a query", func() {
+				err := client.Query("query { Viewer { id } }", nil, "", nil)
+				Convey("Then typed errors should be returned", func() {
+					var errs Errors
+					So(errors.As(err, &errs), ShouldBeTrue)
+					So(errs, ShouldHaveLength, 1)
+					So(errs[0].Message, ShouldEqual, "Invalid token")
+					So(errs[0].Status, ShouldEqual, http.StatusBadRequest)
+				})
+			})
+		})
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	Convey("Given a limiter with limit of 2 requests", t, func() {
+		l := newLimiter(2, time.Millisecond*100)
+		Convey("When I send 3 requests", func() {
+			start := time.Now()
+			for i := 0; i < 3; i++ {
+				l.wait()
+			}
+
+			Convey("Then the last one should be delayed", func() {
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, time.Millisecond*100)
+			})
+		})
+	})
+}
+
+func TestLimiter_Block(t *testing.T) {
+	Convey("Given a limiter with a caller waiting for the window", t, func() {
+		l := newLimiter(1, time.Millisecond*200)
+		l.wait()
+
+		done := make(chan struct{})
+		go func() {
+			l.wait()
+			close(done)
+		}()
+
+		time.Sleep(time.Millisecond * 20)
+
+		Convey("When the limiter is blocked", func() {
+			start := time.Now()
+			l.block(time.Millisecond * 300)
+
+			Convey("Then it should not wait for the sleeping caller", func() {
+				So(time.Since(start), ShouldBeLessThan, time.Millisecond*100)
+			})
+
+			Convey("Then the waiting caller should respect the block", func() {
+				<-done
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, time.Millisecond*300)
+			})
+		})
+	})
+}
+
+func TestSearchByName_RateLimited(t *testing.T) {
+	Convey("Given an Anilist that is always rate limited", t, func() {
+		filesystem.SetMemMapFs()
+		defaultClient := DefaultClient
+		defer func() { DefaultClient = defaultClient }()
+
+		withServer(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, func(client *Client) {
+			DefaultClient = client
+
+			Convey("When I search for a manga", func() {
+				_, err := SearchByName("rate limited")
+
+				Convey("Then the failure should not be cached", func() {
+					So(err, ShouldNotBeNil)
+					So(failCacher.Get(normalizedName("rate limited")).IsPresent(), ShouldBeFalse)
+				})
+			})
+		})
+	})
+}
+
+func TestGetByIDs(t *testing.T) {
+	Convey("Given an Anilist with two mangas", t, func() {
+		filesystem.SetMemMapFs()
+		defaultClient := DefaultClient
+		defer func() { DefaultClient = defaultClient }()
+
+		var (
+			requests int32
+			query    string
+		)
+		withServer(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+
+			body, _ := io.ReadAll(r.Body)
+			var request struct {
+				Query string `json:"query"`
+			}
+			_ = json.Unmarshal(body, &request)
+			query = request.Query
+
+			_, _ = w.Write([]byte(`{
+				"data": {"m0": {"id": 1}, "m1": {"id": 2}, "m2": null},
+				"errors": [{"message": "Not Found.", "status": 404}]
+			}`))
+		}, func(client *Client) {
+			DefaultClient = client
+
+			Convey("When I get them by ids in a single call", func() {
+				mangas, err := GetByIDs([]int{1, 2, 3})
+				Convey("Then found mangas should be returned from a single request", func() {
+					So(err, ShouldBeNil)
+					So(requests, ShouldEqual, 1)
+					So(query, ShouldContainSubstring, "m0: Media")
+					So(query, ShouldContainSubstring, "m2: Media")
+					So(strings.Count(query, "Media ("), ShouldEqual, 3)
+					So(mangas, ShouldHaveLength, 2)
+					So(mangas[1].ID, ShouldEqual, 1)
+					So(mangas[2].ID, ShouldEqual, 2)
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,117 @@
+package anilist
+
+import (
+	"github.com/samber/mo"
+	"strings"
+)
+
+// Mapping converts source chapter numbering to the Anilist one.
+// Sources often number chapters differently, e.g. include prologues or restart numbering per season.
+type Mapping struct {
+	// Offset is added to the source chapter index
+	Offset int `json:"offset,omitempty" jsonschema:"description=Offset is added to the source chapter index"`
+	// IgnoreBefore chapters with source index less than this are not tracked
+	IgnoreBefore int `json:"ignore_before,omitempty" jsonschema:"description=Chapters with source index less than this are not tracked"`
+	// Volumes maps volume name to the last Anilist chapter number in it.
+	// Useful for sources that publish whole volumes as single chapters
+	Volumes map[string]int `json:"volumes,omitempty" jsonschema:"description=Volume name to the last Anilist chapter number in it"`
+}
+
+// Map converts source chapter index to the Anilist chapter number.
+// Returns false if the chapter should not be tracked.
+func (m *Mapping) Map(index int, volume string) (int, bool) {
+	if index < m.IgnoreBefore {
+		return 0, false
+	}
+
+	if number, ok := m.Volumes[strings.TrimSpace(volume)]; ok {
+		return number, true
+	}
+
+	number := index + m.Offset
+	return number, number > 0
+}
+
+// Unmap converts Anilist chapter number back to the source chapter index.
+// Returns false if there is no such index or the chapter with it is not tracked.
+// Chapters of the mapped volumes are found with UnmapVolume.
+func (m *Mapping) Unmap(number int) (int, bool) {
+	index := number - m.Offset
+	if number < 1 || index < 1 || index < m.IgnoreBefore {
+		return 0, false
+	}
+
+	return index, true
+}
+
+// UnmapVolume returns the mapped volume that contains Anilist chapter number,
+// that is the one with the smallest last chapter that is not less than the number
+func (m *Mapping) UnmapVolume(number int) (string, bool) {
+	if number < 1 {
+		return "", false
+	}
+
+	var (
+		volume string
+		last   int
+		found  bool
+	)
+
+	for name, l := range m.Volumes {
+		if l >= number && (!found || l < last || l == last && name < volume) {
+			volume, last, found = name, l, true
+		}
+	}
+
+	return volume, found
+}
+
+// GetMapping returns the chapter mapping rules for the manga name
+func GetMapping(name string) mo.Option[*Mapping] {
+	return mappingCacher.Get(name)
+}
+
+// SetMapping sets the chapter mapping rules for the manga name
+func SetMapping(name string, mapping *Mapping) error {
+	return mappingCacher.Set(name, mapping)
+}
+
+// RemoveMapping removes the chapter mapping rules for the manga name
+func RemoveMapping(name string) error {
+	if GetMapping(name).IsAbsent() {
+		return nil
+	}
+
+	return mappingCacher.Delete(name)
+}
+
+// MapChapter converts source chapter index to the Anilist chapter number
+// according to the mapping rules of the manga, if any.
+// Returns false if the chapter should not be tracked.
+func MapChapter(name string, index int, volume string) (int, bool) {
+	if mapping, ok := GetMapping(name).Get(); ok {
+		return mapping.Map(index, volume)
+	}
+
+	return index, true
+}
+
+// UnmapChapter converts Anilist chapter number back to the source chapter index
+// according to the mapping rules of the manga, if any.
+// Returns false if the number can not be converted.
+func UnmapChapter(name string, number int) (int, bool) {
+	if mapping, ok := GetMapping(name).Get(); ok {
+		return mapping.Unmap(number)
+	}
+
+	return number, number > 0
+}
+
+// UnmapVolume returns the mapped volume of the manga that contains Anilist chapter number, see Mapping.UnmapVolume
+func UnmapVolume(name string, number int) (string, bool) {
+	if mapping, ok := GetMapping(name).Get(); ok {
+		return mapping.UnmapVolume(number)
+	}
+
+	return "", false
+}
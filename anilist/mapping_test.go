@@ -0,0 +1,105 @@
+package anilist
+
+import (
+	"github.com/metafates/mangal/filesystem"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestMapping_Map(t *testing.T) {
+	Convey("Given a mapping with offset, ignored prologue and volumes", t, func() {
+		mapping := &Mapping{
+			Offset:       -1,
+			IgnoreBefore: 2,
+			Volumes:      map[string]int{"Vol. 3": 24},
+		}
+
+		Convey("When mapping the prologue", func() {
+			_, ok := mapping.Map(1, "")
+			Convey("Then it should be ignored", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When mapping a regular chapter", func() {
+			number, ok := mapping.Map(5, "Vol. 1")
+			Convey("Then offset should be applied", func() {
+				So(ok, ShouldBeTrue)
+				So(number, ShouldEqual, 4)
+				index, ok := mapping.Unmap(number)
+				So(ok, ShouldBeTrue)
+				So(index, ShouldEqual, 5)
+			})
+		})
+
+		Convey("When unmapping numbers without the source chapter", func() {
+			Convey("Then they should not be converted", func() {
+				// index 1 is the ignored prologue
+				_, ok := mapping.Unmap(0)
+				So(ok, ShouldBeFalse)
+				_, ok = mapping.Unmap(-5)
+				So(ok, ShouldBeFalse)
+
+				index, ok := mapping.Unmap(1)
+				So(ok, ShouldBeTrue)
+				So(index, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When unmapping a chapter of the mapped volume", func() {
+			mapping.Volumes["Vol. 4"] = 30
+
+			Convey("Then the first volume that contains it should be returned", func() {
+				volume, ok := mapping.UnmapVolume(20)
+				So(ok, ShouldBeTrue)
+				So(volume, ShouldEqual, "Vol. 3")
+
+				volume, ok = mapping.UnmapVolume(25)
+				So(ok, ShouldBeTrue)
+				So(volume, ShouldEqual, "Vol. 4")
+
+				_, ok = mapping.UnmapVolume(31)
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When mapping a chapter of the mapped volume", func() {
+			number, ok := mapping.Map(10, "Vol. 3")
+			Convey("Then volume mapping should be used", func() {
+				So(ok, ShouldBeTrue)
+				So(number, ShouldEqual, 24)
+			})
+		})
+	})
+}
+
+func TestMapChapter(t *testing.T) {
+	Convey("Given a manga with mapping rules", t, func() {
+		filesystem.SetMemMapFs()
+		So(SetMapping("Some Manga", &Mapping{Offset: 10}), ShouldBeNil)
+
+		Convey("When mapping its chapter", func() {
+			number, ok := MapChapter("some manga ", 1, "")
+			Convey("Then the rules should be applied", func() {
+				So(ok, ShouldBeTrue)
+				So(number, ShouldEqual, 11)
+			})
+		})
+
+		Convey("When mapping chapter of another manga", func() {
+			number, ok := MapChapter("another manga", 1, "")
+			Convey("Then it should be left as is", func() {
+				So(ok, ShouldBeTrue)
+				So(number, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the rules are removed", func() {
+			So(RemoveMapping("Some Manga"), ShouldBeNil)
+			Convey("Then chapters should be left as is", func() {
+				number, _ := MapChapter("some manga", 1, "")
+				So(number, ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -1,29 +1,32 @@
 package anilist
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/log"
-	"github.com/metafates/mangal/network"
 	"github.com/metafates/mangal/query"
 	"github.com/samber/lo"
-	"net/http"
-	"strconv"
+	"strings"
+)
+
+const (
+	// idBatchSize is the maximum amount of mangas fetched by id in a single request
+	idBatchSize = 10
+
+	// searchBatchSize is the maximum amount of searches sent in a single request.
+	// Each search returns up to 30 mangas, so it is kept low to stay within the query complexity limit.
+	searchBatchSize = 3
 )
 
 type searchByNameResponse struct {
-	Data struct {
-		Page struct {
-			Media []*Manga `json:"media"`
-		} `json:"page"`
-	} `json:"data"`
+	Page struct {
+		Media []*Manga `json:"media"`
+	} `json:"page"`
 }
 
 type searchByIDResponse struct {
-	Data struct {
-		Media *Manga `json:"media"`
-	} `json:"data"`
+	Media *Manga `json:"media"`
 }
 
 // GetByID returns the manga with the given id.
@@ -33,56 +36,70 @@ func GetByID(id int) (*Manga, error) {
 		return manga.MustGet(), nil
 	}
 
-	// prepare body
 	log.Infof("Searching anilist for manga with id: %d", id)
-	body := map[string]interface{}{
-		"query": searchByIDQuery,
-		"variables": map[string]interface{}{
-			"id": id,
-		},
-	}
 
-	// parse body to json
-	jsonBody, err := json.Marshal(body)
+	var response searchByIDResponse
+	err := DefaultClient.Query(searchByIDQuery, map[string]any{"id": id}, "", &response)
 	if err != nil {
 		log.Error(err)
 		return nil, err
 	}
 
-	// send request
-	log.Info("Sending request to Anilist")
-	req, err := http.NewRequest(http.MethodPost, "https://graphql.anilist.co", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		log.Error(err)
-		return nil, err
+	manga := response.Media
+	if manga == nil {
+		return nil, nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := network.Client.Do(req)
+	log.Infof("Got response from Anilist, found manga with id %d", manga.ID)
+	_ = idCacher.Set(id, manga)
+	return manga, nil
+}
 
-	if err != nil {
-		log.Error(err)
-		return nil, err
+// GetByIDs returns the mangas with the given ids.
+// Mangas that are not cached are fetched in batches using aliased queries.
+// Ids that were not found on Anilist are absent from the result.
+func GetByIDs(ids []int) (map[int]*Manga, error) {
+	mangas := make(map[int]*Manga, len(ids))
+
+	var missing []int
+	for _, id := range lo.Uniq(ids) {
+		if manga, ok := idCacher.Get(id).Get(); ok {
+			mangas[id] = manga
+		} else {
+			missing = append(missing, id)
+		}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Error("Anilist returned status code " + strconv.Itoa(resp.StatusCode))
-		return nil, fmt.Errorf("invalid response code %d", resp.StatusCode)
-	}
+	for _, chunk := range lo.Chunk(missing, idBatchSize) {
+		log.Infof("Searching anilist for %d mangas by id", len(chunk))
 
-	// decode response
-	var response searchByIDResponse
+		var (
+			params    = make([]string, len(chunk))
+			fields    = make([]string, len(chunk))
+			variables = make(map[string]any, len(chunk))
+		)
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		log.Error(err)
-		return nil, err
+		for i, id := range chunk {
+			params[i] = fmt.Sprintf("$id%d: Int", i)
+			fields[i] = fmt.Sprintf("m%d: Media (id: $id%d, type: MANGA) { %s }", i, i, mangaSubquery)
+			variables[fmt.Sprintf("id%d", i)] = id
+		}
+
+		var response map[string]*Manga
+		if err := DefaultClient.Query(batchQuery(params, fields), variables, "", &response); err != nil && !isNotFound(err) {
+			log.Error(err)
+			return nil, err
+		}
+
+		for i, id := range chunk {
+			if manga := response[fmt.Sprintf("m%d", i)]; manga != nil {
+				mangas[id] = manga
+				_ = idCacher.Set(id, manga)
+			}
+		}
 	}
 
-	manga := response.Data.Media
-	log.Infof("Got response from Anilist, found manga with id %d", manga.ID)
-	_ = idCacher.Set(id, manga)
-	return manga, nil
+	return mangas, nil
 }
 
 // SearchByName returns a list of mangas that match the given name.
@@ -95,8 +112,15 @@ func SearchByName(name string) ([]*Manga, error) {
 	}
 
 	if ids, ok := searchCacher.Get(name).Get(); ok {
-		mangas := lo.FilterMap(ids, func(item, _ int) (*Manga, bool) {
-			return idCacher.Get(item).Get()
+		// mangas that have expired from the cache are fetched again by their ids
+		found, err := GetByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		mangas := lo.FilterMap(ids, func(id, _ int) (*Manga, bool) {
+			manga, ok := found[id]
+			return manga, ok
 		})
 
 		if len(mangas) == 0 {
@@ -107,61 +131,102 @@ func SearchByName(name string) ([]*Manga, error) {
 		return mangas, nil
 	}
 
-	// prepare body
 	log.Infof("Searching anilist for manga %s", name)
-	body := map[string]any{
-		"query": searchByNameQuery,
-		"variables": map[string]any{
-			"query": name,
-		},
-	}
 
-	// parse body to json
-	jsonBody, err := json.Marshal(body)
+	var response searchByNameResponse
+	err := DefaultClient.Query(searchByNameQuery, map[string]any{"query": name}, "", &response)
 	if err != nil {
 		log.Error(err)
-		return nil, err
-	}
 
-	// send request
-	log.Info("Sending request to Anilist")
-	req, err := http.NewRequest(http.MethodPost, "https://graphql.anilist.co", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		log.Error(err)
+		// rate limited searches may succeed later
+		if errs.KindOf(err) != errs.RateLimited {
+			_ = failCacher.Set(name, true)
+		}
+
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	mangas := response.Page.Media
+	log.Infof("Got response from Anilist, found %d results", len(mangas))
+	cacheSearch(name, mangas)
+	return mangas, nil
+}
 
-	resp, err := network.Client.Do(req)
+// Preload searches for the given names in batches and caches the results,
+// so that the following FindClosest calls for them do not hit Anilist one by one.
+// Names that are already bound or cached are skipped.
+func Preload(names []string) error {
+	var missing []string
+	for _, name := range lo.Uniq(lo.Map(names, func(name string, _ int) string {
+		return normalizedName(name)
+	})) {
+		if id, ok := relationCacher.Get(name).Get(); ok {
+			if id == -1 || idCacher.Get(id).IsPresent() {
+				continue
+			}
+		}
 
-	if err != nil {
-		log.Error(err)
-		_ = failCacher.Set(name, true)
-		return nil, err
-	}
+		if searchCacher.Get(name).IsPresent() || failCacher.Get(name).IsPresent() {
+			continue
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Error("Anilist returned status code " + strconv.Itoa(resp.StatusCode))
-		_ = failCacher.Set(name, true)
-		return nil, fmt.Errorf("invalid response code %d", resp.StatusCode)
+		missing = append(missing, name)
 	}
 
-	// decode response
-	var response searchByNameResponse
+	for _, chunk := range lo.Chunk(missing, searchBatchSize) {
+		log.Infof("Searching anilist for %d mangas", len(chunk))
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		log.Error(err)
-		return nil, err
+		var (
+			params    = make([]string, len(chunk))
+			fields    = make([]string, len(chunk))
+			variables = make(map[string]any, len(chunk))
+		)
+
+		for i, name := range chunk {
+			params[i] = fmt.Sprintf("$q%d: String", i)
+			fields[i] = fmt.Sprintf(
+				"q%d: Page (page: 1, perPage: 30) { media (search: $q%d, type: MANGA) { %s } }",
+				i, i, mangaSubquery,
+			)
+			variables[fmt.Sprintf("q%d", i)] = name
+		}
+
+		var response map[string]*struct {
+			Media []*Manga `json:"media"`
+		}
+
+		if err := DefaultClient.Query(batchQuery(params, fields), variables, "", &response); err != nil {
+			log.Error(err)
+			return err
+		}
+
+		for i, name := range chunk {
+			if page := response[fmt.Sprintf("q%d", i)]; page != nil {
+				cacheSearch(name, page.Media)
+			}
+		}
 	}
 
-	mangas := response.Data.Page.Media
-	log.Infof("Got response from Anilist, found %d results", len(mangas))
+	return nil
+}
+
+// cacheSearch saves search results for the given name
+func cacheSearch(name string, mangas []*Manga) {
 	ids := make([]int, len(mangas))
 	for i, manga := range mangas {
 		ids[i] = manga.ID
 		_ = idCacher.Set(manga.ID, manga)
 	}
 	_ = searchCacher.Set(name, ids)
-	return mangas, nil
+}
+
+// batchQuery joins aliased fields into a single query
+func batchQuery(params, fields []string) string {
+	return fmt.Sprintf("query (%s) {\n%s\n}", strings.Join(params, ", "), strings.Join(fields, "\n"))
+}
+
+// isNotFound returns true if the error only consists of "not found" errors
+func isNotFound(err error) bool {
+	var list Errors
+	return errors.As(err, &list) && list.NotFound()
 }
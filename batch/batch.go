@@ -0,0 +1,270 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/config"
+	"github.com/metafates/mangal/converter"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/inline"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/provider"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/mo"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"time"
+)
+
+// Job is a single unattended inline mode run
+type Job struct {
+	// Name of the job used in the reports. Defaults to the query or URL
+	Name string `yaml:"name" json:"name"`
+	// Query to search for
+	Query string `yaml:"query" json:"query,omitempty"`
+	// URL of the manga or chapter to use instead of searching
+	URL string `yaml:"url" json:"url,omitempty"`
+	// Sources to search in. Defaults to the sources from the config
+	Sources []string `yaml:"sources" json:"sources,omitempty"`
+	// Manga selector, see inline.ParseMangaPicker
+	Manga string `yaml:"manga" json:"manga,omitempty"`
+	// Chapters selector, see inline.ParseSelector
+	Chapters string `yaml:"chapters" json:"chapters,omitempty"`
+	// Format to download chapters in. Defaults to the format from the config
+	Format string `yaml:"format" json:"format,omitempty"`
+	// Output directory. Defaults to the downloads directory from the config
+	Output string `yaml:"output" json:"output,omitempty"`
+}
+
+// File is the batch file with the list of jobs
+type File struct {
+	// Defaults are used for the fields that are not set in the job
+	Defaults Job    `yaml:"defaults"`
+	Jobs     []*Job `yaml:"jobs"`
+}
+
+func (j *Job) String() string {
+	switch {
+	case j.Name != "":
+		return j.Name
+	case j.Query != "":
+		return j.Query
+	default:
+		return j.URL
+	}
+}
+
+// inherit sets fields that are empty to the defaults
+func (j *Job) inherit(defaults *Job) {
+	inherit := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+
+	inherit(&j.Manga, defaults.Manga)
+	inherit(&j.Chapters, defaults.Chapters)
+	inherit(&j.Format, defaults.Format)
+	inherit(&j.Output, defaults.Output)
+
+	if len(j.Sources) == 0 {
+		j.Sources = defaults.Sources
+	}
+}
+
+// validate checks the job for errors before anything is run
+func (j *Job) validate() error {
+	if (j.Query == "") == (j.URL == "") {
+		return errors.New("either query or url must be set")
+	}
+
+	if j.Query != "" {
+		if j.Manga == "" {
+			return errors.New("manga selector must be set when searching by query")
+		}
+
+		if _, err := inline.ParseMangaPicker(j.Query, j.Manga); err != nil {
+			return err
+		}
+	}
+
+	if j.Chapters != "" {
+		if _, err := inline.ParseSelector(j.Chapters); err != nil {
+			return err
+		}
+	}
+
+	if j.Format != "" {
+		if _, err := converter.Get(j.Format); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parse reads the batch file and returns its jobs with defaults applied.
+// Returns an error if any of the jobs is invalid.
+func Parse(path string) ([]*Job, error) {
+	contents, err := filesystem.Api().ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+	if err = yaml.Unmarshal(contents, &file); err != nil {
+		return nil, err
+	}
+
+	if len(file.Jobs) == 0 {
+		return nil, fmt.Errorf("no jobs found in %s", path)
+	}
+
+	for i, job := range file.Jobs {
+		if job == nil {
+			return nil, fmt.Errorf("job #%d is empty", i+1)
+		}
+
+		job.inherit(&file.Defaults)
+		if err = job.validate(); err != nil {
+			return nil, fmt.Errorf("job #%d %q: %w", i+1, job, err)
+		}
+	}
+
+	return file.Jobs, nil
+}
+
+// Result of the job
+type Result struct {
+	Job *Job
+	// Paths of the downloaded chapters
+	Paths    []string
+	Duration time.Duration
+	Err      error
+}
+
+// Runner runs jobs one by one.
+// Sources are shared between the jobs, so are their caches.
+type Runner struct {
+	sources map[string]source.Source
+}
+
+func NewRunner() *Runner {
+	return &Runner{
+		sources: make(map[string]source.Source),
+	}
+}
+
+// source returns the source with the given name, creating it on the first use
+func (r *Runner) source(name string) (source.Source, error) {
+	if src, ok := r.sources[name]; ok {
+		return src, nil
+	}
+
+	p, ok := provider.Get(name)
+	if !ok {
+		return nil, errs.Newf(errs.SourceNotFound, "source not found: %s", name)
+	}
+
+	src, err := p.CreateSource()
+	if err != nil {
+		return nil, err
+	}
+
+	r.sources[name] = src
+	return src, nil
+}
+
+// Options returns inline mode options for the job.
+// Output of the inline mode is written to the out.
+func (r *Runner) Options(job *Job, out *bytes.Buffer) (*inline.Options, error) {
+	options := &inline.Options{
+		Out:      out,
+		Download: true,
+		Query:    job.Query,
+		URL:      job.URL,
+	}
+
+	if job.Query != "" {
+		names := job.Sources
+		if len(names) == 0 {
+			names = viper.GetStringSlice(key.DownloaderDefaultSources)
+		}
+
+		for _, name := range names {
+			src, err := r.source(name)
+			if err != nil {
+				return nil, err
+			}
+
+			options.Sources = append(options.Sources, src)
+		}
+	}
+
+	if job.Manga != "" {
+		picker, err := inline.ParseMangaPicker(job.Query, job.Manga)
+		if err != nil {
+			return nil, err
+		}
+
+		options.MangaPicker = mo.Some(picker)
+	}
+
+	if job.Chapters != "" {
+		filter, err := inline.ParseChaptersFilter(job.Chapters)
+		if err != nil {
+			return nil, err
+		}
+
+		options.ChaptersFilter = mo.Some(filter)
+	}
+
+	return options, nil
+}
+
+// Run runs the job in the inline mode.
+// Format and output directory of the job are applied only for its duration.
+func (r *Runner) Run(job *Job) (result *Result) {
+	result = &Result{Job: job}
+	started := time.Now()
+	defer func() {
+		result.Duration = time.Since(started)
+	}()
+
+	for k, value := range map[string]string{
+		key.FormatsUse:     job.Format,
+		key.DownloaderPath: config.ExpandPath(job.Output),
+	} {
+		if value == "" {
+			continue
+		}
+
+		previous := viper.Get(k)
+		viper.Set(k, value)
+		defer viper.Set(k, previous)
+	}
+
+	var out bytes.Buffer
+	options, err := r.Options(job, &out)
+	if err != nil {
+		result.Err = err
+		return
+	}
+
+	log.Infof("running batch job %s", job)
+	result.Err = inline.Run(options)
+
+	// inline mode writes path of each downloaded chapter on a separate line
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		if path := scanner.Text(); path != "" {
+			result.Paths = append(result.Paths, path)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,76 @@
+package batch
+
+import (
+	"github.com/metafates/mangal/filesystem"
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"testing"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+}
+
+func writeJobs(contents string) string {
+	const path = "jobs.yaml"
+	if err := filesystem.Api().WriteFile(path, []byte(contents), os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	return path
+}
+
+func TestParse(t *testing.T) {
+	Convey("Given a batch file with defaults", t, func() {
+		path := writeJobs(`
+defaults:
+  sources: [Mangadex]
+  manga: first
+  format: pdf
+jobs:
+  - query: one piece
+    chapters: latest:3
+  - name: chainsaw
+    url: https://mangadex.org/title/a77742b1-befd-49a4-bff5-1ad4e6b0ef7b
+    format: cbz
+`)
+
+		Convey("When it is parsed", func() {
+			jobs, err := Parse(path)
+
+			Convey("Then jobs should inherit the defaults", func() {
+				So(err, ShouldBeNil)
+				So(jobs, ShouldHaveLength, 2)
+				So(jobs[0].Sources, ShouldResemble, []string{"Mangadex"})
+				So(jobs[0].Manga, ShouldEqual, "first")
+				So(jobs[0].Format, ShouldEqual, "pdf")
+				So(jobs[0].String(), ShouldEqual, "one piece")
+			})
+
+			Convey("Then job fields should override the defaults", func() {
+				So(jobs[1].Format, ShouldEqual, "cbz")
+				So(jobs[1].String(), ShouldEqual, "chainsaw")
+			})
+		})
+	})
+
+	Convey("Given a batch file with invalid jobs", t, func() {
+		for _, contents := range []string{
+			"jobs: []",
+			"jobs:\n  - manga: first",
+			"jobs:\n  - query: a\n    url: https://example.com",
+			"jobs:\n  - query: a",
+			"jobs:\n  - query: a\n    manga: worst",
+			"jobs:\n  - query: a\n    manga: first\n    chapters: v",
+			"jobs:\n  - query: a\n    manga: first\n    format: docx",
+		} {
+			Convey("When it is parsed: "+contents, func() {
+				_, err := Parse(writeJobs(contents))
+
+				Convey("Then error should be returned", func() {
+					So(err, ShouldNotBeNil)
+				})
+			})
+		}
+	})
+}
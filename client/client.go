@@ -0,0 +1,251 @@
+// Package client downloads chapters with an explicit configuration,
+// independent of the config file, so that several clients can be used in one program.
+//
+//	c, err := client.New(client.Options{DownloadDir: "/data/manga"})
+//	src, err := c.Source("Mangadex")
+//	mangas, err := src.Search("chainsaw man")
+//	chapters, err := src.ChaptersOf(mangas[0])
+//	path, err := c.Download(chapters[0])
+//
+// Sources use their own http clients to search and list chapters,
+// the options apply to downloading and saving pages.
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/converter"
+	"github.com/metafates/mangal/converter/cbz"
+	"github.com/metafates/mangal/converter/pdf"
+	"github.com/metafates/mangal/converter/plain"
+	"github.com/metafates/mangal/converter/zip"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/provider"
+	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultChapterNameTemplate is the chapter name template used if none is given
+const DefaultChapterNameTemplate = "[{padded-index}] {chapter}"
+
+// Options of the client. Zero values are replaced with defaults.
+type Options struct {
+	// DownloadDir is the directory to download chapters to. Required.
+	DownloadDir string
+	// Format of the downloaded chapters: pdf, cbz, zip or plain. Defaults to cbz.
+	Format string
+	// ChapterNameTemplate is the filename of the chapter without the extension.
+	// Available variables are the same as in the config. Defaults to DefaultChapterNameTemplate.
+	ChapterNameTemplate string
+	// SkipMangaDir saves chapters directly to the download directory instead of the manga subdirectory
+	SkipMangaDir bool
+	// CreateVolumeDir saves chapters to the volume subdirectory of the manga directory
+	CreateVolumeDir bool
+	// RedownloadExisting downloads chapters even if they are already saved
+	RedownloadExisting bool
+	// ComicInfoXML adds ComicInfo.xml to cbz archives
+	ComicInfoXML bool
+	// ComicInfoAddDate adds the release date of the chapter to ComicInfo.xml
+	ComicInfoAddDate bool
+	// ComicInfoAlternativeDate uses the download date instead of the release date in ComicInfo.xml
+	ComicInfoAlternativeDate bool
+	// SkipUnsupportedImages skips images that can not be added to pdf instead of failing
+	SkipUnsupportedImages bool
+	// HTTPClient downloads pages. Defaults to a client with a minute timeout.
+	HTTPClient *http.Client
+	// Fs is the filesystem to save chapters to. Defaults to the OS filesystem.
+	Fs afero.Fs
+}
+
+// Client downloads chapters according to its options
+type Client struct {
+	options Options
+}
+
+// New creates a new client with the options
+func New(options Options) (*Client, error) {
+	if options.DownloadDir == "" {
+		return nil, errors.New("download directory is required")
+	}
+
+	if options.Format == "" {
+		options.Format = constant.FormatCBZ
+	}
+
+	if !lo.Contains(converter.Available(), options.Format) {
+		return nil, fmt.Errorf("unknown format %q", options.Format)
+	}
+
+	if options.ChapterNameTemplate == "" {
+		options.ChapterNameTemplate = DefaultChapterNameTemplate
+	}
+
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: time.Minute}
+	}
+
+	if options.Fs == nil {
+		options.Fs = afero.NewOsFs()
+	}
+
+	return &Client{options: options}, nil
+}
+
+// Options returns the options of the client with defaults applied
+func (c *Client) Options() Options {
+	return c.options
+}
+
+// Source creates the source by its name, see provider.Builtins and provider.Customs
+func (c *Client) Source(name string) (source.Source, error) {
+	p, ok := provider.Get(name)
+	if !ok {
+		return nil, errs.Newf(errs.SourceNotFound, "source not found: %s", name)
+	}
+
+	return p.CreateSource()
+}
+
+// ChapterPath returns the path that the chapter is saved to
+func (c *Client) ChapterPath(chapter *source.Chapter) string {
+	path := c.options.DownloadDir
+
+	if !c.options.SkipMangaDir {
+		path = filepath.Join(path, chapter.Manga.Dirname())
+	}
+
+	if c.options.CreateVolumeDir && chapter.Volume != "" {
+		path = filepath.Join(path, util.SanitizeFilename(chapter.Volume))
+	}
+
+	filename := util.SanitizeFilename(chapter.FormatName(c.options.ChapterNameTemplate))
+	if c.options.Format != constant.FormatPlain {
+		filename += "." + c.options.Format
+	}
+
+	return filepath.Join(path, filename)
+}
+
+// IsDownloaded checks if the chapter is already saved
+func (c *Client) IsDownloaded(chapter *source.Chapter) bool {
+	exists, _ := afero.Exists(c.options.Fs, c.ChapterPath(chapter))
+	return exists
+}
+
+// Download gets pages of the chapter from its source, downloads and saves them.
+// Returns the path of the saved chapter.
+// If the chapter is already saved, it is not downloaded again unless RedownloadExisting is set.
+func (c *Client) Download(chapter *source.Chapter) (string, error) {
+	path := c.ChapterPath(chapter)
+
+	if c.IsDownloaded(chapter) {
+		if !c.options.RedownloadExisting {
+			return path, nil
+		}
+
+		if err := c.options.Fs.RemoveAll(path); err != nil {
+			return "", err
+		}
+	}
+
+	pages, err := chapter.Source().PagesOf(chapter)
+	if err != nil {
+		return "", err
+	}
+
+	chapter.Pages = pages
+	if err = c.DownloadPages(pages); err != nil {
+		return "", err
+	}
+
+	if err = c.options.Fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if err = c.save(chapter, path); err != nil {
+		_ = c.options.Fs.RemoveAll(path)
+		return "", errs.New(errs.Conversion, err)
+	}
+
+	return path, nil
+}
+
+// DownloadPages downloads contents of the pages in parallel with the http client of the client.
+// Returns the first error encountered.
+func (c *Client) DownloadPages(pages []*source.Page) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	wg.Add(len(pages))
+	for _, page := range pages {
+		go func(page *source.Page) {
+			defer wg.Done()
+
+			if err := page.DownloadWith(c.options.HTTPClient); err != nil {
+				once.Do(func() {
+					firstErr = err
+				})
+			}
+		}(page)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// save converts the downloaded pages to the format of the client
+func (c *Client) save(chapter *source.Chapter, path string) error {
+	if c.options.Format == constant.FormatPlain {
+		return plain.WriteTo(c.options.Fs, path, chapter.Pages)
+	}
+
+	file, err := c.options.Fs.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = c.write(file, chapter)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// comicInfo returns the ComicInfo of the chapter if it is enabled.
+// Chapter mapping rules are not applied, since they are a part of the config.
+func (c *Client) comicInfo(chapter *source.Chapter) *source.ComicInfo {
+	if !c.options.ComicInfoXML {
+		return nil
+	}
+
+	return chapter.ComicInfoWith(source.ComicInfoOptions{
+		AddDate:         c.options.ComicInfoAddDate,
+		AlternativeDate: c.options.ComicInfoAlternativeDate,
+	})
+}
+
+func (c *Client) write(w io.Writer, chapter *source.Chapter) error {
+	switch c.options.Format {
+	case constant.FormatCBZ:
+		return cbz.Write(w, chapter, c.comicInfo(chapter))
+	case constant.FormatZIP:
+		return zip.Write(w, chapter.Pages)
+	case constant.FormatPDF:
+		return pdf.Write(w, chapter.Pages, c.options.SkipUnsupportedImages)
+	default:
+		return fmt.Errorf("unknown format %q", c.options.Format)
+	}
+}
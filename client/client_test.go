@@ -0,0 +1,188 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+}
+
+type testSource struct {
+	server *httptest.Server
+}
+
+func (testSource) Name() string {
+	return "test"
+}
+
+func (testSource) ID() string {
+	return "test"
+}
+
+func (testSource) Search(string) ([]*source.Manga, error) {
+	panic("")
+}
+
+func (testSource) ChaptersOf(*source.Manga) ([]*source.Chapter, error) {
+	panic("")
+}
+
+func (s testSource) PagesOf(chapter *source.Chapter) ([]*source.Page, error) {
+	var pages []*source.Page
+	for i := 1; i <= 3; i++ {
+		pages = append(pages, &source.Page{
+			URL:       fmt.Sprintf("%s/%d.png", s.server.URL, i),
+			Index:     uint16(i),
+			Extension: ".png",
+			Chapter:   chapter,
+		})
+	}
+
+	return pages, nil
+}
+
+func testChapter(server *httptest.Server) *source.Chapter {
+	manga := &source.Manga{Name: "One Piece", Source: testSource{server: server}}
+	chapter := &source.Chapter{Name: "Romance Dawn", Index: 1, Volume: "Vol. 1", Manga: manga}
+	manga.Chapters = []*source.Chapter{chapter}
+	return chapter
+}
+
+func TestClient(t *testing.T) {
+	Convey("Given a server with pages", t, func() {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			_, _ = w.Write([]byte(r.URL.Path))
+		}))
+		defer server.Close()
+
+		Convey("When the options are invalid", func() {
+			_, withoutDir := New(Options{})
+			_, withFormat := New(Options{DownloadDir: "/manga", Format: "docx"})
+
+			Convey("Then an error should be returned", func() {
+				So(withoutDir, ShouldNotBeNil)
+				So(withFormat, ShouldNotBeNil)
+			})
+		})
+
+		Convey("And two clients with different options", func() {
+			cbzFs, plainFs := afero.NewMemMapFs(), afero.NewMemMapFs()
+
+			cbzClient, err := New(Options{DownloadDir: "/cbz", Fs: cbzFs, HTTPClient: server.Client()})
+			So(err, ShouldBeNil)
+
+			plainClient, err := New(Options{
+				DownloadDir:         "/plain",
+				Format:              constant.FormatPlain,
+				ChapterNameTemplate: "{volume} {chapter}",
+				SkipMangaDir:        true,
+				Fs:                  plainFs,
+			})
+			So(err, ShouldBeNil)
+
+			Convey("When downloading the chapter with both", func() {
+				cbzPath, err := cbzClient.Download(testChapter(server))
+				So(err, ShouldBeNil)
+
+				plainPath, err := plainClient.Download(testChapter(server))
+				So(err, ShouldBeNil)
+
+				Convey("Then the paths should follow the options of each client", func() {
+					So(cbzPath, ShouldEqual, filepath.Join("/cbz", "One_Piece", "[0001]_Romance_Dawn.cbz"))
+					So(plainPath, ShouldEqual, filepath.Join("/plain", "Vol._1_Romance_Dawn"))
+				})
+
+				Convey("Then the cbz archive should contain the pages", func() {
+					contents, err := afero.ReadFile(cbzFs, cbzPath)
+					So(err, ShouldBeNil)
+
+					reader, err := zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+					So(err, ShouldBeNil)
+					So(reader.File, ShouldHaveLength, 3)
+				})
+
+				Convey("Then the pages should be saved as images", func() {
+					contents, err := afero.ReadFile(plainFs, filepath.Join(plainPath, "000001.png"))
+					So(err, ShouldBeNil)
+					So(string(contents), ShouldEqual, "/1.png")
+				})
+
+				Convey("Then each client should only write to its filesystem", func() {
+					So(exists(plainFs, cbzPath), ShouldBeFalse)
+					So(exists(cbzFs, plainPath), ShouldBeFalse)
+					So(exists(filesystem.Api(), cbzPath), ShouldBeFalse)
+				})
+
+				Convey("Then downloading it again should be skipped", func() {
+					before := atomic.LoadInt32(&requests)
+					_, err := cbzClient.Download(testChapter(server))
+					So(err, ShouldBeNil)
+					So(atomic.LoadInt32(&requests), ShouldEqual, before)
+				})
+			})
+		})
+	})
+}
+
+func TestClient_ComicInfo(t *testing.T) {
+	Convey("Given a client with ComicInfo.xml without dates", t, func() {
+		viper.Set(key.MetadataComicInfoXMLAddDate, true)
+		defer viper.Set(key.MetadataComicInfoXMLAddDate, false)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.URL.Path))
+		}))
+		defer server.Close()
+
+		fs := afero.NewMemMapFs()
+		c, err := New(Options{DownloadDir: "/cbz", Fs: fs, HTTPClient: server.Client(), ComicInfoXML: true})
+		So(err, ShouldBeNil)
+
+		Convey("When the chapter is downloaded", func() {
+			chapter := testChapter(server)
+			chapter.Date.Year, chapter.Date.Month, chapter.Date.Day = 2022, 12, 31
+
+			path, err := c.Download(chapter)
+			So(err, ShouldBeNil)
+
+			Convey("Then ComicInfo.xml should follow the client options instead of the config", func() {
+				contents, err := afero.ReadFile(fs, path)
+				So(err, ShouldBeNil)
+
+				reader, err := zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+				So(err, ShouldBeNil)
+
+				file, err := reader.Open("ComicInfo.xml")
+				So(err, ShouldBeNil)
+				comicInfo, err := io.ReadAll(file)
+				So(err, ShouldBeNil)
+
+				So(string(comicInfo), ShouldContainSubstring, "<Title>Romance Dawn</Title>")
+				So(string(comicInfo), ShouldNotContainSubstring, "<Year>")
+			})
+		})
+	})
+}
+
+func exists(fs afero.Fs, path string) bool {
+	exists, _ := afero.Exists(fs, path)
+	return exists
+}
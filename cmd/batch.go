@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/metafates/mangal/batch"
+	"github.com/metafates/mangal/converter"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/icon"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/style"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"time"
+)
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().BoolP("json", "j", false, "print results as JSON")
+	batchCmd.Flags().Bool("fail-fast", false, "stop on the first failed job")
+	batchCmd.SetOut(os.Stdout)
+}
+
+var batchCmd = &cobra.Command{
+	Use:   "batch [file]",
+	Short: "Run jobs from the batch file",
+	Long: `Run inline mode jobs from the YAML batch file, one after another.
+Each job downloads chapters exactly as "mangal inline --download" would.
+
+Job fields:
+  name - name of the job in the report, defaults to the query or url
+  query - query to search for
+  url - url of the manga or chapter, used instead of the query
+  sources - sources to search in, defaults to the sources from the config
+  manga - manga selector, required with the query
+  chapters - chapter selector, all chapters if omitted
+  format - format to download chapters in
+  output - directory to download chapters to
+
+Fields in "defaults" are used for the jobs that do not set them.
+See "mangal inline --help" for the selectors syntax.`,
+	Example: `  # jobs.yaml
+  defaults:
+    sources: [Mangadex]
+    manga: best
+    chapters: new
+  jobs:
+    - query: one piece
+    - name: chainsaw man
+      url: https://mangadex.org/title/a77742b1-befd-49a4-bff5-1ad4e6b0ef7b
+      format: cbz
+      output: ~/manga/chainsaw
+
+  mangal batch jobs.yaml`,
+	Args: cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if _, err := converter.Get(viper.GetString(key.FormatsUse)); err != nil {
+			handleErr(err)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		flushOutbox()
+
+		jobs, err := batch.Parse(args[0])
+		handleErr(err)
+
+		var (
+			asJson   = lo.Must(cmd.Flags().GetBool("json"))
+			failFast = lo.Must(cmd.Flags().GetBool("fail-fast"))
+			runner   = batch.NewRunner()
+			results  []*batch.Result
+			failed   int
+		)
+
+		for _, job := range jobs {
+			result := runner.Run(job)
+			results = append(results, result)
+
+			if !asJson {
+				chapters := util.Quantify(len(result.Paths), "chapter", "chapters")
+				duration := style.Faint(result.Duration.Round(time.Millisecond).String())
+
+				if result.Err != nil {
+					cmd.Printf("%s %s: %s after %s %s\n", icon.Get(icon.Fail), job, result.Err, chapters, duration)
+				} else {
+					cmd.Printf("%s %s: %s %s\n", icon.Get(icon.Success), job, chapters, duration)
+				}
+			}
+
+			if result.Err != nil {
+				failed++
+				if failFast {
+					break
+				}
+			}
+		}
+
+		if asJson {
+			type jsonResult struct {
+				Job      *batch.Job `json:"job"`
+				Paths    []string   `json:"paths"`
+				Duration float64    `json:"duration"`
+				Error    string     `json:"error,omitempty"`
+				Kind     string     `json:"kind,omitempty"`
+			}
+
+			marshalled, err := json.Marshal(lo.Map(results, func(result *batch.Result, _ int) *jsonResult {
+				r := &jsonResult{
+					Job:      result.Job,
+					Paths:    lo.Ternary(result.Paths == nil, []string{}, result.Paths),
+					Duration: result.Duration.Seconds(),
+				}
+
+				if result.Err != nil {
+					r.Error = result.Err.Error()
+					r.Kind = errs.KindOf(result.Err).String()
+				}
+
+				return r
+			}))
+			handleErr(err)
+			cmd.Println(string(marshalled))
+		}
+
+		if failed > 0 {
+			handleErr(fmt.Errorf("%s of %d failed", util.Quantify(failed, "job", "jobs"), len(jobs)))
+		}
+	},
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/invopop/jsonschema"
 	"github.com/metafates/mangal/anilist"
 	"github.com/metafates/mangal/converter"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/filesystem"
 	"github.com/metafates/mangal/inline"
 	"github.com/metafates/mangal/key"
@@ -14,6 +15,7 @@ import (
 	"github.com/metafates/mangal/query"
 	"github.com/metafates/mangal/source"
 	"github.com/metafates/mangal/update"
+	"github.com/metafates/mangal/webreader"
 	"github.com/samber/lo"
 	"github.com/samber/mo"
 	"github.com/spf13/cobra"
@@ -29,10 +31,14 @@ func init() {
 	rootCmd.AddCommand(inlineCmd)
 
 	inlineCmd.Flags().StringP("query", "q", "", "query to search for")
+	inlineCmd.Flags().StringP("url", "u", "", "url of the manga or chapter to use instead of searching")
 	inlineCmd.Flags().StringP("manga", "m", "", "manga selector")
 	inlineCmd.Flags().StringP("chapters", "c", "", "chapter selector")
+	inlineCmd.Flags().Bool("explain", false, "print what each part of the chapter selector matched to stderr")
 	inlineCmd.Flags().BoolP("download", "d", false, "download chapters")
+	inlineCmd.Flags().Bool("dry-run", false, "print what would be downloaded, skipped or overwritten without downloading. Use with json flag for JSON output")
 	inlineCmd.Flags().BoolP("json", "j", false, "JSON output")
+	inlineCmd.Flags().BoolP("events", "e", false, "stream progress events as newline-delimited JSON")
 	inlineCmd.Flags().BoolP("populate-pages", "p", false, "Populate chapters pages")
 	inlineCmd.Flags().BoolP("fetch-metadata", "f", false, "Populate manga metadata")
 	inlineCmd.Flags().BoolP("include-anilist-manga", "a", false, "Include anilist manga in the output")
@@ -40,8 +46,9 @@ func init() {
 
 	inlineCmd.Flags().StringP("output", "o", "", "output file")
 
-	lo.Must0(inlineCmd.MarkFlagRequired("query"))
+	inlineCmd.MarkFlagsMutuallyExclusive("query", "url")
 	inlineCmd.MarkFlagsMutuallyExclusive("download", "json")
+	inlineCmd.MarkFlagsMutuallyExclusive("events", "json")
 	inlineCmd.MarkFlagsMutuallyExclusive("include-anilist-manga", "download")
 
 	inlineCmd.RegisterFlagCompletionFunc("query", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -57,6 +64,10 @@ var inlineCmd = &cobra.Command{
 Manga selectors:
   first - first manga in the list
   last - last manga in the list
+  exact - manga with the name equal to the query
+  best - manga with the best fuzzy match to the query and its Anilist titles and synonyms
+  anilist:[id] - manga bound to the Anilist id, e.g. anilist:30013
+  url:[url] - manga with the url
   [number] - select manga by index (starting from 0)
 
 Chapter selectors:
@@ -65,15 +76,45 @@ Chapter selectors:
   all - all chapters in the list
   [number] - select chapter by index (starting from 0)
   [from]-[to] - select chapters by range
+  #[number] - select chapter by its number, e.g. #10.5
+  #[from]-#[to] - select chapters by number range, e.g. #10-#20.5. Use #[from]- for open range
+  v[number] - select chapters by volume, e.g. v3 or v1-v3
   @[substring]@ - select chapters by name substring
+  /[regex]/ - select chapters by name regex, /[regex]/i for case-insensitive
+  new - chapters after the last one in the history
+  undownloaded - chapters that are not downloaded yet
+  latest:[count] - last chapters, e.g. latest:5
+
+Chapter selectors can be combined with commas, e.g. "v1,#20-#25".
+Prefix selector with ! to exclude chapters, e.g. "all,!@Extra@"
+
+With the json flag the candidate scores are included in the output, so the choice can be audited.
+Exit codes:
+  1 - unknown error
+  3 - source not found
+  4 - no manga found or matched the manga selector
+  5 - network error
+  6 - rate limited
+  7 - Lua source error
+  8 - conversion error
+  9 - some chapters failed to download
+With the json flag the error is also included in the output as an object with kind, message and exit code.
+
+When the url flag is used, search and manga selector are skipped.
+If the url points to a chapter, it is selected unless the chapter selector is given.
 
 When using the json flag manga selector could be omitted. That way, it will select all mangas`,
 
 	Example: "https://github.com/metafates/mangal/wiki/Inline-mode",
 	PreRun: func(cmd *cobra.Command, args []string) {
 		json, _ := cmd.Flags().GetBool("json")
+		url, _ := cmd.Flags().GetString("url")
 
-		if !json {
+		if url == "" {
+			lo.Must0(cmd.MarkFlagRequired("query"))
+		}
+
+		if (!json || lo.Must(cmd.Flags().GetBool("dry-run"))) && url == "" {
 			lo.Must0(cmd.MarkFlagRequired("manga"))
 		}
 
@@ -86,6 +127,8 @@ When using the json flag manga selector could be omitted. That way, it will sele
 		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		flushOutbox()
+
 		var (
 			sources []source.Source
 			err     error
@@ -93,12 +136,12 @@ When using the json flag manga selector could be omitted. That way, it will sele
 
 		for _, name := range viper.GetStringSlice(key.DownloaderDefaultSources) {
 			if name == "" {
-				handleErr(errors.New("source not set"))
+				handleErr(errs.Newf(errs.SourceNotFound, "source not set"))
 			}
 
 			p, ok := provider.Get(name)
 			if !ok {
-				handleErr(fmt.Errorf("source not found: %s", name))
+				handleErr(errs.Newf(errs.SourceNotFound, "source not found: %s", name))
 			}
 
 			src, err := p.CreateSource()
@@ -131,6 +174,20 @@ When using the json flag manga selector could be omitted. That way, it will sele
 		if chapterFlag != "" {
 			fn, err := inline.ParseChaptersFilter(chapterFlag)
 			handleErr(err)
+
+			if lo.Must(cmd.Flags().GetBool("explain")) {
+				selector := lo.Must(inline.ParseSelector(chapterFlag))
+				fn = func(chapters []*source.Chapter) ([]*source.Chapter, error) {
+					selected, explanation, err := selector.Explain(chapters)
+					if err != nil {
+						return nil, err
+					}
+
+					_, _ = fmt.Fprint(os.Stderr, explanation)
+					return selected, nil
+				}
+			}
+
 			chapterFilter = mo.Some(fn)
 		}
 
@@ -138,7 +195,10 @@ When using the json flag manga selector could be omitted. That way, it will sele
 			Sources:             sources,
 			Download:            lo.Must(cmd.Flags().GetBool("download")),
 			Json:                lo.Must(cmd.Flags().GetBool("json")),
+			DryRun:              lo.Must(cmd.Flags().GetBool("dry-run")),
+			Events:              lo.Must(cmd.Flags().GetBool("events")),
 			Query:               query,
+			URL:                 lo.Must(cmd.Flags().GetString("url")),
 			PopulatePages:       lo.Must(cmd.Flags().GetBool("populate-pages")),
 			IncludeAnilistManga: lo.Must(cmd.Flags().GetBool("include-anilist-manga")),
 			MangaPicker:         mangaPicker,
@@ -147,6 +207,12 @@ When using the json flag manga selector could be omitted. That way, it will sele
 		}
 
 		handleErr(inline.Run(options))
+
+		// keep serving the built-in reader until interrupted
+		if url, ok := webreader.Running(); ok {
+			cmd.PrintErrf("Reading at %s, press Ctrl+C to exit\n", url)
+			webreader.Wait()
+		}
 	},
 }
 
@@ -265,10 +331,96 @@ var inlineAnilistUpdateCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	inlineAnilistCmd.AddCommand(inlineAnilistMappingCmd)
+}
+
+var inlineAnilistMappingCmd = &cobra.Command{
+	Use:   "mapping",
+	Short: "Manage chapter mapping rules between the source and Anilist",
+	Long: `Manage chapter mapping rules between the source and Anilist.
+Mapping rules are applied to the progress sent to the trackers and to the ComicInfo.xml chapter number.`,
+}
+
+func init() {
+	inlineAnilistMappingCmd.AddCommand(inlineAnilistMappingGetCmd)
+
+	inlineAnilistMappingGetCmd.Flags().StringP("name", "n", "", "manga name")
+	lo.Must0(inlineAnilistMappingGetCmd.MarkFlagRequired("name"))
+}
+
+var inlineAnilistMappingGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get chapter mapping rules of the manga",
+	Run: func(cmd *cobra.Command, args []string) {
+		mapping := anilist.GetMapping(lo.Must(cmd.Flags().GetString("name")))
+		handleErr(json.NewEncoder(os.Stdout).Encode(mapping.OrElse(nil)))
+	},
+}
+
+func init() {
+	inlineAnilistMappingCmd.AddCommand(inlineAnilistMappingSetCmd)
+
+	inlineAnilistMappingSetCmd.Flags().StringP("name", "n", "", "manga name")
+	inlineAnilistMappingSetCmd.Flags().IntP("offset", "o", 0, "offset added to the source chapter index")
+	inlineAnilistMappingSetCmd.Flags().IntP("ignore-before", "i", 0, "do not track chapters with source index less than this")
+	inlineAnilistMappingSetCmd.Flags().StringToIntP("volume", "v", map[string]int{}, "map volume to the last Anilist chapter in it, e.g. --volume \"Vol. 1\"=8")
+	lo.Must0(inlineAnilistMappingSetCmd.MarkFlagRequired("name"))
+}
+
+var inlineAnilistMappingSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Set chapter mapping rules of the manga",
+	Long: `Set chapter mapping rules of the manga.
+Only the passed flags are changed, other rules are kept.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := lo.Must(cmd.Flags().GetString("name"))
+		mapping := anilist.GetMapping(name).OrElse(&anilist.Mapping{})
+
+		if cmd.Flags().Changed("offset") {
+			mapping.Offset = lo.Must(cmd.Flags().GetInt("offset"))
+		}
+
+		if cmd.Flags().Changed("ignore-before") {
+			mapping.IgnoreBefore = lo.Must(cmd.Flags().GetInt("ignore-before"))
+		}
+
+		if cmd.Flags().Changed("volume") {
+			if mapping.Volumes == nil {
+				mapping.Volumes = make(map[string]int)
+			}
+
+			for volume, chapter := range lo.Must(cmd.Flags().GetStringToInt("volume")) {
+				mapping.Volumes[volume] = chapter
+			}
+		}
+
+		handleErr(anilist.SetMapping(name, mapping))
+		handleErr(json.NewEncoder(os.Stdout).Encode(mapping))
+	},
+}
+
+func init() {
+	inlineAnilistMappingCmd.AddCommand(inlineAnilistMappingRemoveCmd)
+
+	inlineAnilistMappingRemoveCmd.Flags().StringP("name", "n", "", "manga name")
+	lo.Must0(inlineAnilistMappingRemoveCmd.MarkFlagRequired("name"))
+}
+
+var inlineAnilistMappingRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove chapter mapping rules of the manga",
+	Run: func(cmd *cobra.Command, args []string) {
+		handleErr(anilist.RemoveMapping(lo.Must(cmd.Flags().GetString("name"))))
+	},
+}
+
 func init() {
 	inlineCmd.AddCommand(inlineSchemaCmd)
 
 	inlineSchemaCmd.Flags().BoolP("anilist", "a", false, "generate anilist search output schema")
+	inlineSchemaCmd.Flags().BoolP("events", "e", false, "generate events stream line schema")
+	inlineSchemaCmd.Flags().BoolP("plan", "p", false, "generate dry-run plan schema")
 }
 
 var inlineSchemaCmd = &cobra.Command{
@@ -292,6 +444,10 @@ var inlineSchemaCmd = &cobra.Command{
 		switch {
 		case lo.Must(cmd.Flags().GetBool("anilist")):
 			schema = reflector.Reflect([]*anilist.Manga{})
+		case lo.Must(cmd.Flags().GetBool("events")):
+			schema = reflector.Reflect(&inline.Event{})
+		case lo.Must(cmd.Flags().GetBool("plan")):
+			schema = reflector.Reflect(&inline.Plan{})
 		default:
 			schema = reflector.Reflect(&inline.Output{})
 		}
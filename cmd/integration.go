@@ -3,20 +3,36 @@ package cmd
 import (
 	"fmt"
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/metafates/mangal/color"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/history"
 	"github.com/metafates/mangal/icon"
+	"github.com/metafates/mangal/integration"
 	"github.com/metafates/mangal/integration/anilist"
+	"github.com/metafates/mangal/integration/myanimelist"
+	"github.com/metafates/mangal/integration/oauth"
+	"github.com/metafates/mangal/integration/outbox"
+	"github.com/metafates/mangal/integration/tracker"
 	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/log"
 	"github.com/metafates/mangal/open"
+	"github.com/metafates/mangal/provider"
+	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/style"
+	"github.com/metafates/mangal/util"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"os"
+	"time"
 )
 
 func init() {
 	rootCmd.AddCommand(integrationCmd)
 	integrationCmd.AddCommand(integrationAnilistCmd)
 	integrationAnilistCmd.Flags().BoolP("disable", "d", false, "Disable Anilist integration")
+	integrationAnilistCmd.Flags().Bool("no-browser", false, "do not start local server and open browser, paste the code manually")
+	integrationAnilistCmd.Flags().Bool("relogin", false, "login again even if already logged in")
 }
 
 var integrationCmd = &cobra.Command{
@@ -36,6 +52,7 @@ See https://github.com/metafates/mangal/wiki/Anilist-Integration for more inform
 			viper.Set(key.AnilistCode, "")
 			viper.Set(key.AnilistSecret, "")
 			viper.Set(key.AnilistID, "")
+			handleErr(anilist.New().Logout())
 			log.Info("Anilist integration disabled")
 			handleErr(viper.WriteConfig())
 		}
@@ -103,42 +120,417 @@ See https://github.com/metafates/mangal/wiki/Anilist-Integration for more inform
 			handleErr(err)
 		}
 
-		if viper.GetString(key.AnilistCode) == "" {
-			authURL := anilist.New().AuthURL()
-			confirmOpenInBrowser := survey.Confirm{
-				Message: "Open browser to authenticate with Anilist?",
-				Default: false,
+		al := anilist.New()
+		if !al.LoggedIn() || lo.Must(cmd.Flags().GetBool("relogin")) {
+			var loggedIn bool
+			if !lo.Must(cmd.Flags().GetBool("no-browser")) && oauth.CanOpenBrowser() {
+				if err := loginAnilistLoopback(al); err != nil {
+					log.Warn(err)
+					fmt.Printf("%s Automatic login failed: %s\n", icon.Get(icon.Fail), err)
+				} else {
+					loggedIn = true
+				}
 			}
 
-			var openInBrowser bool
-			err := survey.AskOne(&confirmOpenInBrowser, &openInBrowser)
-			if err == nil && openInBrowser {
-				err = open.Start(authURL)
+			// fallback for headless machines
+			if !loggedIn {
+				loginAnilistPaste(al)
 			}
+		}
+
+		fmt.Printf("%s Anilist integration was set up\n", icon.Get(icon.Success))
+	},
+}
+
+// loginAnilistLoopback opens the browser and captures the redirect with a local server
+func loginAnilistLoopback(al *anilist.Anilist) error {
+	loopback, err := oauth.Listen(viper.GetInt(key.AnilistRedirectPort))
+	if err != nil {
+		return err
+	}
+
+	defer util.Ignore(loopback.Close)
+
+	authURL := al.AuthURL(loopback.RedirectURI(), loopback.State)
+	if err = open.Start(authURL); err != nil {
+		return err
+	}
 
-			if err != nil || !openInBrowser {
-				fmt.Println("Please open the following URL in your browser:")
-				fmt.Println(authURL)
+	fmt.Printf("Waiting for authorization in the browser. Make sure the redirect URL of your Anilist client is %s\n", loopback.RedirectURI())
+	fmt.Println("If the browser did not open, visit:")
+	fmt.Println(authURL)
+
+	code, err := loopback.Wait(5 * time.Minute)
+	if err != nil {
+		return err
+	}
+
+	return al.Login(code, loopback.RedirectURI())
+}
+
+// loginAnilistPaste asks user to copy the code from the Anilist pin page
+func loginAnilistPaste(al *anilist.Anilist) {
+	fmt.Println("Please open the following URL in your browser:")
+	fmt.Println(al.AuthURL(anilist.PinRedirectURI, ""))
+
+	input := survey.Input{
+		Message: "Please copy the code from the link and paste in here:",
+		Help:    "",
+	}
+
+	var response string
+	handleErr(survey.AskOne(&input, &response))
+
+	if response == "" {
+		os.Exit(0)
+	}
+
+	handleErr(al.Login(response, anilist.PinRedirectURI))
+}
+
+// requireAnilist exits if Anilist integration is disabled
+func requireAnilist() {
+	if !viper.GetBool(key.AnilistEnable) {
+		handleErr(fmt.Errorf("anilist integration is disabled, run `mangal integration anilist` to set it up"))
+	}
+}
+
+func init() {
+	integrationAnilistCmd.AddCommand(integrationAnilistSyncCmd)
+
+	integrationAnilistSyncCmd.Flags().StringP("prefer", "p", "", "resolve conflicts by preferring local or remote progress (local, remote)")
+	integrationAnilistSyncCmd.Flags().BoolP("dry-run", "n", false, "only show what would be done")
+	integrationAnilistSyncCmd.SetOut(os.Stdout)
+}
+
+var integrationAnilistSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync reading history with Anilist",
+	Long: `Sync reading history with Anilist in both directions.
+Progress changed only on one side since the last sync is propagated to the other one.
+If progress was changed on both sides, the conflict is reported and left untouched unless --prefer is set.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireAnilist()
+
+		prefer := history.SyncPreference(lo.Must(cmd.Flags().GetString("prefer")))
+		if !lo.Contains([]history.SyncPreference{history.PreferNone, history.PreferLocal, history.PreferRemote}, prefer) {
+			handleErr(fmt.Errorf("invalid preference %s, expected local or remote", prefer))
+		}
+
+		results, err := history.Sync(integration.Anilist, prefer, lo.Must(cmd.Flags().GetBool("dry-run")), savedChaptersOf())
+		handleErr(err)
+
+		var conflicts int
+		for _, result := range results {
+			switch result.Action {
+			case history.SyncConflict:
+				conflicts++
+				cmd.Println(style.Fg(color.Yellow)(result.String()))
+			case history.SyncFailed:
+				cmd.Println(style.Fg(color.Red)(result.String()))
+			default:
+				cmd.Println(result.String())
 			}
+		}
 
-			input := survey.Input{
-				Message: "Anilsit code is not set. Please copy it from the link and paste in here:",
-				Help:    "",
+		if conflicts > 0 {
+			cmd.Printf(
+				"\n%s %s, use --prefer to resolve\n",
+				icon.Get(icon.Fail),
+				util.Quantify(conflicts, "conflict", "conflicts"),
+			)
+			return
+		}
+
+		cmd.Printf("%s Synced with Anilist\n", icon.Get(icon.Success))
+	},
+}
+
+func init() {
+	integrationAnilistCmd.AddCommand(integrationAnilistGetCmd)
+	integrationAnilistGetCmd.SetOut(os.Stdout)
+}
+
+var integrationAnilistGetCmd = &cobra.Command{
+	Use:   "get <manga name>",
+	Short: "Show Anilist list entry of the manga",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireAnilist()
+
+		entry, err := integration.Anilist.GetEntry(&source.Manga{Name: args[0]})
+		handleErr(err)
+
+		e, ok := entry.Get()
+		if !ok {
+			cmd.Println("Manga is not in the list")
+			return
+		}
+
+		cmd.Printf("Status: %s\n", e.Status)
+		cmd.Printf("Progress: %d\n", e.Progress)
+		cmd.Printf("Score: %.1f\n", e.Score)
+		cmd.Printf("Started: %s\n", e.StartedAt)
+		cmd.Printf("Completed: %s\n", e.CompletedAt)
+	},
+}
+
+func init() {
+	integrationAnilistCmd.AddCommand(integrationAnilistSetCmd)
+
+	integrationAnilistSetCmd.Flags().StringP("status", "s", "", fmt.Sprintf("reading status %v", tracker.Statuses))
+	integrationAnilistSetCmd.Flags().Float64("score", -1, "score from 0 to 10")
+	integrationAnilistSetCmd.Flags().Int("progress", -1, "amount of chapters read")
+	integrationAnilistSetCmd.Flags().String("started", "", "start date in the YYYY-MM-DD format")
+	integrationAnilistSetCmd.Flags().String("completed", "", "finish date in the YYYY-MM-DD format")
+}
+
+var integrationAnilistSetCmd = &cobra.Command{
+	Use:   "set <manga name>",
+	Short: "Update Anilist list entry of the manga",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireAnilist()
+
+		manga := &source.Manga{Name: args[0]}
+
+		if s := lo.Must(cmd.Flags().GetString("status")); s != "" {
+			status, err := tracker.ParseStatus(s)
+			handleErr(err)
+			handleErr(integration.Anilist.SetStatus(manga, status))
+		}
+
+		if score := lo.Must(cmd.Flags().GetFloat64("score")); score >= 0 {
+			if score > 10 {
+				handleErr(fmt.Errorf("score must be from 0 to 10"))
 			}
 
-			var response string
-			err = survey.AskOne(&input, &response)
+			handleErr(integration.Anilist.SetScore(manga, score))
+		}
+
+		if progress := lo.Must(cmd.Flags().GetInt("progress")); progress >= 0 {
+			handleErr(integration.Anilist.SetProgress(manga, progress))
+		}
+
+		var startedAt, completedAt tracker.Date
+		if s := lo.Must(cmd.Flags().GetString("started")); s != "" {
+			date, err := tracker.ParseDate(s)
 			handleErr(err)
+			startedAt = date
+		}
+
+		if s := lo.Must(cmd.Flags().GetString("completed")); s != "" {
+			date, err := tracker.ParseDate(s)
+			handleErr(err)
+			completedAt = date
+		}
+
+		handleErr(integration.Anilist.SetDates(manga, startedAt, completedAt))
+
+		fmt.Printf("%s Anilist entry updated\n", icon.Get(icon.Success))
+	},
+}
+
+func init() {
+	integrationCmd.AddCommand(integrationMyAnimeListCmd)
+	integrationMyAnimeListCmd.Flags().BoolP("disable", "d", false, "Disable MyAnimeList integration")
+}
+
+var integrationMyAnimeListCmd = &cobra.Command{
+	Use:     "mal",
+	Aliases: []string{"myanimelist"},
+	Short:   "Integration with MyAnimeList",
+	Long: `Integration with MyAnimeList.
+Create an API client at https://myanimelist.net/apiconfig with "other" app type and use its client ID.
+Mangas are mapped to MyAnimeList by their Anilist bindings.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		mal := myanimelist.New()
+
+		if lo.Must(cmd.Flags().GetBool("disable")) {
+			viper.Set(key.MyAnimeListEnable, false)
+			viper.Set(key.MyAnimeListClientID, "")
+			handleErr(mal.Logout())
+			log.Info("MyAnimeList integration disabled")
+			handleErr(viper.WriteConfig())
+			return
+		}
+
+		if viper.GetString(key.MyAnimeListClientID) == "" {
+			input := survey.Input{
+				Message: "MyAnimeList client ID is not set. Please enter it:",
+			}
+			var response string
+			handleErr(survey.AskOne(&input, &response))
 
 			if response == "" {
 				return
 			}
 
-			viper.Set(key.AnilistCode, response)
-			err = viper.WriteConfig()
-			handleErr(err)
+			viper.Set(key.MyAnimeListClientID, response)
 		}
 
-		fmt.Printf("%s Anilist integration was set up\n", icon.Get(icon.Success))
+		verifier, err := myanimelist.NewVerifier()
+		handleErr(err)
+
+		state, err := oauth.RandomState()
+		handleErr(err)
+
+		authURL := mal.AuthURL(verifier, state)
+		confirmOpenInBrowser := survey.Confirm{
+			Message: "Open browser to authenticate with MyAnimeList?",
+			Default: false,
+		}
+
+		var openInBrowser bool
+		err = survey.AskOne(&confirmOpenInBrowser, &openInBrowser)
+		if err == nil && openInBrowser {
+			err = open.Start(authURL)
+		}
+
+		if err != nil || !openInBrowser {
+			fmt.Println("Please open the following URL in your browser:")
+			fmt.Println(authURL)
+		}
+
+		input := survey.Input{
+			Message: "Paste the URL you were redirected to:",
+		}
+
+		var response string
+		handleErr(survey.AskOne(&input, &response))
+
+		if response == "" {
+			return
+		}
+
+		// the full redirect url is required to check the state
+		code, err := oauth.CodeFromRedirect(response, state)
+		handleErr(err)
+
+		handleErr(mal.Login(code, verifier))
+
+		viper.Set(key.MyAnimeListEnable, true)
+		err = viper.WriteConfig()
+		if err != nil {
+			switch err.(type) {
+			case viper.ConfigFileNotFoundError:
+				handleErr(viper.SafeWriteConfig())
+			default:
+				handleErr(err)
+			}
+		}
+
+		fmt.Printf("%s MyAnimeList integration was set up\n", icon.Get(icon.Success))
 	},
 }
+
+func init() {
+	integrationCmd.AddCommand(integrationFlushCmd)
+	integrationFlushCmd.SetOut(os.Stdout)
+}
+
+var integrationFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Send pending tracker updates",
+	Long: `Send pending tracker updates.
+Updates that could not be sent, e.g. when offline, are kept in the outbox and retried on the next run.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		results, err := outbox.Flush()
+		handleErr(err)
+
+		var failed int
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+				cmd.Printf("%s %s: %s\n", icon.Get(icon.Fail), result.Update, result.Err)
+			} else {
+				cmd.Printf("%s %s\n", icon.Get(icon.Success), result.Update)
+			}
+		}
+
+		if len(results) == 0 {
+			cmd.Println("Nothing to send")
+			return
+		}
+
+		if failed > 0 {
+			handleErr(fmt.Errorf("%s failed", util.Quantify(failed, "update", "updates")))
+		}
+	},
+}
+
+func init() {
+	integrationCmd.AddCommand(integrationStatusCmd)
+	integrationStatusCmd.SetOut(os.Stdout)
+}
+
+var integrationStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show enabled integrations and pending tracker updates",
+	Run: func(cmd *cobra.Command, args []string) {
+		headerStyle := style.New().Foreground(color.HiBlue).Bold(true).Render
+
+		cmd.Println(headerStyle("Enabled:"))
+		enabled := integration.Enabled()
+		if len(enabled) == 0 {
+			cmd.Println("None")
+		}
+
+		for _, integrator := range enabled {
+			cmd.Println(integrator.Name())
+		}
+
+		pending, err := outbox.Pending()
+		handleErr(err)
+
+		cmd.Println()
+		cmd.Println(headerStyle("Pending updates:"))
+		if len(pending) == 0 {
+			cmd.Println("None")
+		}
+
+		for _, update := range pending {
+			cmd.Print(update.String())
+			if update.Attempts > 0 {
+				cmd.Print(style.Fg(color.Red)(fmt.Sprintf(
+					" (%s, last error: %s)",
+					util.Quantify(update.Attempts, "attempt", "attempts"),
+					update.LastError,
+				)))
+			}
+			cmd.Println()
+		}
+	},
+}
+
+// savedChaptersOf returns the function that fetches the chapters of the saved manga from its source.
+// Sources are created once and reused for the mangas from the same source.
+func savedChaptersOf() history.ChaptersOf {
+	sources := make(map[string]source.Source)
+
+	return func(saved *history.SavedChapter) ([]*source.Chapter, error) {
+		src, ok := sources[saved.SourceID]
+		if !ok {
+			p, found := lo.Find(append(provider.Builtins(), provider.Customs()...), func(p *provider.Provider) bool {
+				return p.ID == saved.SourceID
+			})
+			if !found {
+				return nil, errs.Newf(errs.SourceNotFound, "source %s not found", saved.SourceID)
+			}
+
+			var err error
+			if src, err = p.CreateSource(); err != nil {
+				return nil, err
+			}
+
+			sources[saved.SourceID] = src
+		}
+
+		return src.ChaptersOf(&source.Manga{
+			Name:   saved.MangaName,
+			URL:    saved.MangaURL,
+			ID:     saved.MangaID,
+			Source: src,
+		})
+	}
+}
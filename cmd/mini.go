@@ -29,6 +29,8 @@ Will try to mimic ani-cli.`,
 		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		flushOutbox()
+
 		options := mini.Options{
 			Download: lo.Must(cmd.Flags().GetBool("download")),
 			Continue: lo.Must(cmd.Flags().GetBool("continue")),
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/metafates/mangal/icon"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/opds"
+	"github.com/metafates/mangal/where"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+func init() {
+	rootCmd.AddCommand(opdsCmd)
+
+	opdsCmd.Flags().String("host", "", "host to listen on")
+	opdsCmd.Flags().IntP("port", "p", 0, "port to listen on")
+	opdsCmd.Flags().StringP("token", "t", "", "token required to access the catalog")
+
+	opdsCmd.SetOut(os.Stdout)
+}
+
+var opdsCmd = &cobra.Command{
+	Use:   "opds",
+	Short: "Serve downloaded chapters as OPDS catalog",
+	Long: `Serve the downloads directory as the OPDS 1.2 catalog for reader apps.
+
+Every manga directory is a navigation entry with its cover and the chapters
+are available for download as CBZ, ZIP, PDF and EPUB files.
+CBZ and ZIP chapters can be streamed page by page with the OPDS-PSE.
+
+The catalog is not indexed: every feed request walks the downloads directory,
+so large libraries may take a moment to load. Page lists of the archives are
+cached until the files are modified.
+
+If the token is set, apps must pass it as the basic auth password with any username.`,
+	Example: `  mangal opds --host 0.0.0.0 --token secret`,
+	Args:    cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// flags are bound here, because the keys are shared with other commands
+		lo.Must0(viper.BindPFlag(key.ServerHost, cmd.Flags().Lookup("host")))
+		lo.Must0(viper.BindPFlag(key.OPDSPort, cmd.Flags().Lookup("port")))
+		lo.Must0(viper.BindPFlag(key.ServerToken, cmd.Flags().Lookup("token")))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		address := net.JoinHostPort(viper.GetString(key.ServerHost), strconv.Itoa(viper.GetInt(key.OPDSPort)))
+		handler := opds.New(where.Downloads(), viper.GetString(key.ServerToken))
+
+		cmd.Printf("%s Serving %s at http://%s/opds\n", icon.Get(icon.Success), where.Downloads(), address)
+		handleErr(http.ListenAndServe(address, handler))
+	},
+}
@@ -6,10 +6,13 @@ import (
 	"github.com/metafates/mangal/color"
 	"github.com/metafates/mangal/constant"
 	"github.com/metafates/mangal/converter"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/icon"
+	"github.com/metafates/mangal/integration/outbox"
 	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/log"
 	"github.com/metafates/mangal/provider"
+	"github.com/metafates/mangal/provider/custom"
 	"github.com/metafates/mangal/style"
 	"github.com/metafates/mangal/tui"
 	"github.com/metafates/mangal/util"
@@ -20,6 +23,7 @@ import (
 	"github.com/spf13/viper"
 	"os"
 	"strings"
+	"time"
 )
 
 func init() {
@@ -87,6 +91,8 @@ var rootCmd = &cobra.Command{
 			return
 		}
 
+		flushOutbox()
+
 		options := tui.Options{
 			Continue: lo.Must(cmd.Flags().GetBool("continue")),
 		}
@@ -109,16 +115,39 @@ func Execute() {
 		})
 	}
 
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	waitForOutbox()
+	custom.CloseAll()
+
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
+// outboxTimeout is how long to wait for the tracker updates before exiting
+const outboxTimeout = 5 * time.Second
+
+// flushOutbox retries tracker updates that were not sent during the previous runs.
+// It is called by the commands that read or download chapters.
+func flushOutbox() {
+	outbox.FlushInBackground()
+}
+
+// waitForOutbox waits for the tracker updates that are being sent, so that they are not cut off by exiting
+func waitForOutbox() {
+	if !outbox.Wait(outboxTimeout) {
+		log.Warn("Timed out sending tracker updates, they will be retried on the next run")
+	}
+}
+
+// handleErr prints the error and exits with the exit code of its kind, see errs.Kind
 func handleErr(err error) {
 	if err != nil {
 		log.Error(err)
 		_, _ = fmt.Fprintf(os.Stderr, "%s %s\n", icon.Get(icon.Fail), strings.Trim(err.Error(), " \n"))
-		os.Exit(1)
+		waitForOutbox()
+		custom.CloseAll()
+		os.Exit(errs.KindOf(err).ExitCode())
 	}
 }
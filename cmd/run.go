@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/provider/custom"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
 )
 
 func init() {
 	rootCmd.AddCommand(runCmd)
 	runCmd.Flags().BoolP("lenient", "l", false, "do not warn about missing functions")
+	runCmd.Flags().Bool("no-sandbox", false, "run without the sandbox, ignoring permissions and limits")
 }
 
 var runCmd = &cobra.Command{
@@ -21,8 +25,13 @@ Or you can use mangal as a standalone lua interpreter.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		sourcePath := args[0]
 
+		if lo.Must(cmd.Flags().GetBool("no-sandbox")) {
+			viper.Set(key.LuaSandbox, false)
+		}
+
 		// LoadSource runs file when it's loaded
-		_, err := custom.LoadSource(sourcePath, !lo.Must(cmd.Flags().GetBool("lenient")))
+		src, err := custom.LoadSource(sourcePath, !lo.Must(cmd.Flags().GetBool("lenient")))
 		handleErr(err)
+		handleErr(src.(io.Closer).Close())
 	},
 }
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/icon"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/server"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("host", "", "host to listen on")
+	serveCmd.Flags().IntP("port", "p", 0, "port to listen on")
+	serveCmd.Flags().StringP("token", "t", "", "token required to access the API")
+
+	serveCmd.SetOut(os.Stdout)
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start the HTTP API server",
+	Long: `Start the HTTP server with the JSON API over sources, downloads and history.
+
+Endpoints:
+  GET  /api/providers - list available sources
+  GET  /api/search?source=&query= - search the source
+  GET  /api/chapters?source=&url=&id=&name= - list chapters of the manga
+  POST /api/downloads - enqueue a download, body: {"manga": {"source", "url", "id", "name"}, "chapters": "<selector>"}
+  GET  /api/downloads - list downloads
+  GET  /api/downloads/<id> - get the download status
+  GET  /api/history - list the reading history
+
+If the token is set, requests must pass it either in the
+"Authorization: Bearer <token>" header or in the "token" query parameter.
+POST requests must have "Content-Type: application/json" header.
+Requests to other hosts than localhost and the listened one are rejected,
+unless the server listens on all interfaces.
+See "mangal inline --help" for the chapters selector syntax.`,
+	Example: `  mangal serve --port 4280 --token secret
+  curl -H "Authorization: Bearer secret" "localhost:4280/api/search?source=Mangadex&query=chainsaw"
+  curl -H "Content-Type: application/json" -d '{"manga": {"source": "Mangadex", "name": "Chainsaw Man"}, "chapters": "last"}' localhost:4280/api/downloads`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// flags are bound here, because the keys are shared with other commands
+		lo.Must0(viper.BindPFlag(key.ServerHost, cmd.Flags().Lookup("host")))
+		lo.Must0(viper.BindPFlag(key.ServerPort, cmd.Flags().Lookup("port")))
+		lo.Must0(viper.BindPFlag(key.ServerToken, cmd.Flags().Lookup("token")))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		flushOutbox()
+
+		address := net.JoinHostPort(viper.GetString(key.ServerHost), strconv.Itoa(viper.GetInt(key.ServerPort)))
+		handler := server.New(viper.GetString(key.ServerHost), viper.GetString(key.ServerToken))
+
+		cmd.Printf("%s Listening on %s\n", icon.Get(icon.Success), fmt.Sprintf("http://%s", address))
+		handleErr(http.ListenAndServe(address, handler))
+	},
+}
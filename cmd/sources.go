@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/metafates/mangal/color"
 	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/tui"
 	"github.com/metafates/mangal/util"
@@ -13,9 +15,12 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/harness"
 	"github.com/metafates/mangal/icon"
+	"github.com/metafates/mangal/installer"
 	"github.com/metafates/mangal/provider"
 	"github.com/metafates/mangal/style"
 	"github.com/metafates/mangal/where"
@@ -65,6 +70,11 @@ var sourcesListCmd = &cobra.Command{
 		printCustom := func() {
 			h("Custom:")
 			for _, p := range provider.Customs() {
+				if p.Incompatible != nil && printHeader {
+					cmd.Printf("%s %s\n", p.Name, style.Fg(color.Red)("(incompatible: "+p.Incompatible.Error()+")"))
+					continue
+				}
+
 				cmd.Println(p.Name)
 			}
 		}
@@ -84,6 +94,76 @@ var sourcesListCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	sourcesCmd.AddCommand(sourcesInfoCmd)
+	sourcesInfoCmd.SetOut(os.Stdout)
+}
+
+var sourcesInfoCmd = &cobra.Command{
+	Use:   "info <name>",
+	Short: "Show information about the source",
+	Long: `Show information about the source.
+For custom sources it is read from the header of the scraper.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		names := lo.Map(append(provider.Builtins(), provider.Customs()...), func(p *provider.Provider, _ int) string {
+			return p.Name
+		})
+
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		p, ok := provider.Get(args[0])
+		if !ok {
+			handleErr(errs.Newf(errs.SourceNotFound, "source not found: %s", args[0]))
+		}
+
+		field := func(name string, value any) {
+			if value == "" || value == nil {
+				return
+			}
+
+			cmd.Printf("  %s  %s\n", style.Faint(fmt.Sprintf("%-12s", name)), style.Bold(fmt.Sprint(value)))
+		}
+
+		cmd.Printf("%s %s\n\n", style.Fg(color.Purple)("▇▇▇"), style.Fg(color.Purple)(p.Name))
+		field("ID", p.ID)
+
+		if !p.IsCustom {
+			field("Type", "builtin")
+			field("Headless", p.UsesHeadless)
+			return
+		}
+
+		h := p.Header
+		field("Type", "custom")
+		field("Path", filepath.Join(where.Sources(), p.Name+provider.CustomProviderExtension))
+		field("Name", h.Name)
+		field("Version", h.Version)
+		field("URL", h.URL)
+		field("Author", h.Author)
+		field("License", h.License)
+		field("Languages", strings.Join(h.Languages, ", "))
+		field("NSFW", h.NSFW)
+		field("Requires", strings.Join(h.Requires, ", "))
+		field("Mangal", h.MinVersion)
+		field("Hosts", strings.Join(lo.Uniq(h.Permissions.Hosts), ", "))
+		field("Filesystem", h.Permissions.Filesystem)
+		field("Headless", p.UsesHeadless)
+		field("Concurrent", h.Concurrent)
+
+		if p.Incompatible != nil {
+			cmd.Printf("\n%s %s\n", icon.Get(icon.Fail), style.Fg(color.Red)("incompatible: "+p.Incompatible.Error()))
+		} else {
+			cmd.Printf("\n%s compatible\n", icon.Get(icon.Success))
+		}
+	},
+}
+
 func init() {
 	sourcesCmd.AddCommand(sourcesRemoveCmd)
 
@@ -131,6 +211,225 @@ https://github.com/metafates/mangal-scrapers`,
 	},
 }
 
+func init() {
+	sourcesCmd.AddCommand(sourcesUpdateCmd)
+
+	sourcesUpdateCmd.Flags().BoolP("yes", "y", false, "apply updates without confirmation")
+	sourcesUpdateCmd.Flags().BoolP("diff", "d", false, "show changes of the scrapers")
+	sourcesUpdateCmd.Flags().Bool("check", false, "only list available updates")
+	sourcesUpdateCmd.MarkFlagsMutuallyExclusive("yes", "check")
+	sourcesUpdateCmd.SetOut(os.Stdout)
+}
+
+var sourcesUpdateCmd = &cobra.Command{
+	Use:   "update [names...]",
+	Short: "Update installed custom scrapers",
+	Long: `Update installed custom scrapers from the scrapers repository.
+New versions are verified against the SHA-256 checksums from the repository manifest before writing.
+Previous versions are kept and can be restored with "mangal sources rollback".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manifest, err := installer.FetchManifest()
+		handleErr(err)
+
+		updates, err := installer.Updates(manifest)
+		handleErr(err)
+
+		if len(args) > 0 {
+			updates = lo.Filter(updates, func(u *installer.Update, _ int) bool {
+				return lo.Contains(args, u.Name)
+			})
+		}
+
+		if len(updates) == 0 {
+			cmd.Println("All scrapers are up to date")
+			return
+		}
+
+		headerStyle := style.New().Foreground(color.HiBlue).Bold(true).Render
+		for _, update := range updates {
+			installed := update.Installed
+			if installed == "" {
+				installed = "unknown"
+			}
+
+			cmd.Printf("%s %s → %s\n", headerStyle(update.Name), style.Faint(installed), style.Bold(update.Entry.Version))
+			if update.Entry.Changelog != "" {
+				cmd.Println(strings.TrimSpace(update.Entry.Changelog))
+			}
+
+			if lo.Must(cmd.Flags().GetBool("diff")) {
+				handleErr(update.Fetch())
+				cmd.Println(colorDiff(update.Diff()))
+			}
+
+			cmd.Println()
+		}
+
+		if lo.Must(cmd.Flags().GetBool("check")) {
+			return
+		}
+
+		if !lo.Must(cmd.Flags().GetBool("yes")) {
+			confirm := survey.Confirm{
+				Message: fmt.Sprintf("Update %d scrapers?", len(updates)),
+				Default: true,
+			}
+
+			var response bool
+			handleErr(survey.AskOne(&confirm, &response))
+
+			if !response {
+				return
+			}
+		}
+
+		for _, update := range updates {
+			handleErr(update.Fetch())
+			handleErr(update.Apply())
+			cmd.Printf("%s updated %s to %s\n", icon.Get(icon.Success), style.Fg(color.Yellow)(update.Name), update.Entry.Version)
+		}
+	},
+}
+
+// colorDiff colors the removed and added lines of the diff
+func colorDiff(diff string) string {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			lines[i] = style.Fg(color.Green)(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = style.Fg(color.Red)(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = style.Faint(line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func init() {
+	sourcesCmd.AddCommand(sourcesRollbackCmd)
+	sourcesRollbackCmd.SetOut(os.Stdout)
+}
+
+var sourcesRollbackCmd = &cobra.Command{
+	Use:   "rollback <names...>",
+	Short: "Restore previous versions of custom scrapers",
+	Long: `Restore previous versions of custom scrapers replaced by "mangal sources update" or install.
+Rolling back again restores the replaced version.`,
+	Args: cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names := lo.FilterMap(provider.Customs(), func(p *provider.Provider, _ int) (string, bool) {
+			return p.Name, installer.HasBackup(p.Name) && !lo.Contains(args, p.Name)
+		})
+
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, name := range args {
+			handleErr(installer.Rollback(name))
+			cmd.Printf("%s rolled back %s\n", icon.Get(icon.Success), style.Fg(color.Yellow)(name))
+		}
+	},
+}
+
+func init() {
+	sourcesCmd.AddCommand(sourcesTestCmd)
+
+	sourcesTestCmd.Flags().StringP("query", "q", "", "query to search manga with")
+	sourcesTestCmd.Flags().Int("manga", 0, "index of the found manga to get chapters of")
+	sourcesTestCmd.Flags().Int("chapter", 0, "index of the chapter to get pages of")
+	sourcesTestCmd.Flags().Bool("record", false, "record http traffic to the fixture")
+	sourcesTestCmd.Flags().Bool("replay", false, "replay http traffic from the fixture without network")
+	sourcesTestCmd.Flags().StringP("fixture", "f", "", "path to the fixture file")
+
+	sourcesTestCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	sourcesTestCmd.SetOut(os.Stdout)
+}
+
+var sourcesTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Test custom scraper",
+	Long: `Test custom scraper by running its functions one after another and validating the returned tables.
+It searches manga with the query, gets chapters of the found manga and pages of its chapter.
+
+Use --record to save http traffic to the fixture and --replay to run the scraper against it offline.
+Replay uses the recorded query and indexes unless the query is given.
+Requests of the headless browser are not recorded.`,
+	Example: `  mangal sources test MySource --query "one piece" --record
+  mangal sources test MySource --replay`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		names := lo.Map(provider.Customs(), func(p *provider.Provider, _ int) string {
+			return p.Name
+		})
+
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		path := filepath.Join(where.Sources(), name+provider.CustomProviderExtension)
+		if exists, err := filesystem.Api().Exists(path); err != nil || !exists {
+			handleErr(errs.Newf(errs.SourceNotFound, "custom source not found: %s", name))
+		}
+
+		options := harness.Options{
+			Query:   lo.Must(cmd.Flags().GetString("query")),
+			Manga:   lo.Must(cmd.Flags().GetInt("manga")),
+			Chapter: lo.Must(cmd.Flags().GetInt("chapter")),
+			Fixture: lo.Must(cmd.Flags().GetString("fixture")),
+		}
+
+		if options.Fixture == "" {
+			options.Fixture = filepath.Join(where.SourceFixtures(), name+".json")
+		}
+
+		switch {
+		case lo.Must(cmd.Flags().GetBool("record")):
+			options.Mode = harness.Record
+		case lo.Must(cmd.Flags().GetBool("replay")):
+			options.Mode = harness.Replay
+		}
+
+		report, err := harness.Run(path, options)
+		handleErr(err)
+
+		for _, step := range report.Steps {
+			status := icon.Get(icon.Success)
+			if step.Failed() {
+				status = icon.Get(icon.Fail)
+			}
+
+			cmd.Printf(
+				"%s %s %s %s\n",
+				status,
+				style.Bold(step.Function),
+				style.Faint(step.Argument),
+				style.Faint(fmt.Sprintf("(%d items in %s)", step.Count, step.Duration.Round(time.Millisecond))),
+			)
+
+			if step.Err != nil {
+				cmd.Println("  " + style.Fg(color.Red)(step.Err.Error()))
+			}
+
+			for _, problem := range step.Problems {
+				cmd.Println("  " + style.Fg(color.Red)(problem))
+			}
+		}
+
+		if options.Mode == harness.Record {
+			cmd.Printf("\nRecorded to %s\n", options.Fixture)
+		}
+
+		handleErr(report.Err())
+	},
+}
+
 func init() {
 	sourcesCmd.AddCommand(sourcesGenCmd)
 
@@ -159,19 +458,25 @@ var sourcesGenCmd = &cobra.Command{
 		}
 
 		s := struct {
-			Name            string
-			URL             string
-			SearchMangaFn   string
-			MangaChaptersFn string
-			ChapterPagesFn  string
-			Author          string
+			Name             string
+			URL              string
+			SearchMangaFn    string
+			MangaChaptersFn  string
+			ChapterPagesFn   string
+			MangaFromURLFn   string
+			DescramblePageFn string
+			Author           string
+			MangalVersion    string
 		}{
-			Name:            lo.Must(cmd.Flags().GetString("name")),
-			URL:             lo.Must(cmd.Flags().GetString("url")),
-			SearchMangaFn:   constant.SearchMangaFn,
-			MangaChaptersFn: constant.MangaChaptersFn,
-			ChapterPagesFn:  constant.ChapterPagesFn,
-			Author:          author,
+			Name:             lo.Must(cmd.Flags().GetString("name")),
+			URL:              lo.Must(cmd.Flags().GetString("url")),
+			SearchMangaFn:    constant.SearchMangaFn,
+			MangaChaptersFn:  constant.MangaChaptersFn,
+			ChapterPagesFn:   constant.ChapterPagesFn,
+			MangaFromURLFn:   constant.MangaFromURLFn,
+			DescramblePageFn: constant.DescramblePageFn,
+			Author:           author,
+			MangalVersion:    constant.Version,
 		}
 
 		funcMap := template.FuncMap{
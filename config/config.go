@@ -74,8 +74,12 @@ func setDefaults() {
 
 // resolveAliases resolves the aliases for the paths
 func resolveAliases() {
+	viper.Set(key.DownloaderPath, ExpandPath(viper.GetString(key.DownloaderPath)))
+}
+
+// ExpandPath replaces leading ~ with the home directory and expands environment variables
+func ExpandPath(path string) string {
 	home := lo.Must(os.UserHomeDir())
-	path := viper.GetString(key.DownloaderPath)
 
 	if path == "~" {
 		path = home
@@ -83,7 +87,5 @@ func resolveAliases() {
 		path = filepath.Join(home, path[2:])
 	}
 
-	path = os.ExpandEnv(path)
-
-	viper.Set(key.DownloaderPath, path)
+	return os.ExpandEnv(path)
 }
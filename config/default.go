@@ -282,6 +282,24 @@ Available options are: emoji, kaomoji, plain, squares, nerd (nerd-font required)
 		false,
 		"Open chapter url in browser instead of downloading it",
 	},
+	{
+		key.ReaderBuiltin,
+		false,
+		`Read chapters in the built-in web reader instead of external programs.
+It serves pages on the local http server, so it works over SSH with port forwarding.
+Uses server.host and server.token options`,
+	},
+	{
+		key.ReaderBuiltinMode,
+		"paged",
+		`Default mode of the built-in reader.
+Available options are: paged, strip`,
+	},
+	{
+		key.ReaderBuiltinPort,
+		4281,
+		"Port of the built-in reader",
+	},
 	{
 		key.HistorySaveOnRead,
 		true,
@@ -328,6 +346,13 @@ Use "any" to show all languages`,
 		"main",
 		"Custom scrapers repository branch",
 	},
+	{
+		key.InstallerManifest,
+		"manifest.json",
+		`Path to the manifest in the custom scrapers repository.
+It lists versions, SHA-256 checksums and changelogs of the scrapers.
+Updates are verified against it before writing.`,
+	},
 	{
 		key.GenAuthor,
 		"",
@@ -374,6 +399,88 @@ panic, fatal, error, warn, info, debug, trace`,
 		true,
 		"Show link to Anilist on manga select",
 	},
+	{
+		key.AnilistRedirectPort,
+		18231,
+		`Port of the local server that receives Anilist login redirect.
+Redirect URL of the Anilist client must be set to http://127.0.0.1:<port>/callback`,
+	},
+	{
+		key.MyAnimeListEnable,
+		false,
+		"Enable MyAnimeList integration",
+	},
+	{
+		key.MyAnimeListClientID,
+		"",
+		"MyAnimeList client ID to use for authentication",
+	},
+	{
+		key.ServerHost,
+		"127.0.0.1",
+		`Host to listen on for the serve command.
+Use 0.0.0.0 to make the server available on the network`,
+	},
+	{
+		key.ServerPort,
+		4280,
+		"Port to listen on for the serve command",
+	},
+	{
+		key.ServerToken,
+		"",
+		`Token required to access the server API.
+Must be passed as "Authorization: Bearer <token>" header, basic auth password or token query parameter.
+Empty token disables authentication`,
+	},
+	{
+		key.OPDSPort,
+		4282,
+		"Port of the OPDS catalog server. Uses server.host and server.token options",
+	},
+	{
+		key.LuaSandbox,
+		false,
+		`Run custom scrapers in the sandbox.
+Scrapers can only use network, filesystem and headless browser
+if they declare it with "-- @allow" lines in the header.
+Time and instruction limits are applied only in the sandbox.
+Disabled by default, because scrapers written before the permissions
+do not declare them and would fail to load or to send requests to other hosts than their @url`,
+	},
+	{
+		key.LuaTimeout,
+		120,
+		"Seconds that a single call of the scraper function can take. 0 disables the limit",
+	},
+	{
+		key.LuaInstructionLimit,
+		500_000_000,
+		"Number of Lua instructions that a single call of the scraper function can execute. 0 disables the limit",
+	},
+	{
+		key.LuaMemoryLimit,
+		512,
+		`Megabytes that the heap can grow by during a single call of the scraper function.
+It is checked every thousand instructions against the heap of the whole program,
+so concurrent calls count towards each other's limit. 0 disables the limit`,
+	},
+	{
+		key.LuaStackLimit,
+		5120,
+		`Maximum number of values on the Lua stack of the sandboxed scraper.
+Calls that need more, e.g. unpacking large tables, fail.
+It does not limit the memory of strings and tables, see lua.memory_limit. 0 uses the default of the Lua VM`,
+	},
+	{
+		key.LuaPoolSize,
+		4,
+		`Maximum number of Lua states of each custom scraper.
+Each state can run one function at a time, so it limits the number of concurrent calls.
+Calls beyond it wait for a free state.
+Every state runs the top level code of the scraper and has its own globals.
+Scrapers with "-- @concurrent false" in the header run in a single state`,
+	},
 	{
 		key.TUIItemSpacing,
 		1,
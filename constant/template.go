@@ -4,19 +4,30 @@ const (
 	SearchMangaFn   = "SearchManga"
 	MangaChaptersFn = "MangaChapters"
 	ChapterPagesFn  = "ChapterPages"
+
+	// MangaFromURLFn is optional. Sources that define it can resolve manga from the URLs on their @url host
+	MangaFromURLFn = "MangaFromURL"
+
+	// DescramblePageFn is optional. Sources that define it can transform the downloaded page images
+	DescramblePageFn = "DescramblePage"
 )
 
-const SourceTemplate = `{{ $divider := repeat "-" (plus (max (len .URL) (len .Name) (len .Author) 3) 12) }}{{ $divider }}
+const SourceTemplate = `{{ $divider := repeat "-" (plus (max (len .URL) (len .Name) (len .Author) 3) 20) }}{{ $divider }}
 -- @name    {{ .Name }} 
+-- @version 0.1.0
 -- @url     {{ .URL }}
+-- @allow   network {{ .URL }}
 -- @author  {{ .Author }} 
 -- @license MIT
+-- @mangal  {{ .MangalVersion }}
+-- @concurrent true
 {{ $divider }}
 
 
----@alias manga { name: string, url: string, author: string|nil, genres: string|nil, summary: string|nil }
----@alias chapter { name: string, url: string, volume: string|nil, manga_summary: string|nil, manga_author: string|nil, manga_genres: string|nil }
----@alias page { url: string, index: number }
+---@alias manga { name: string, url: string, summary: string|nil, cover: string|nil, status: "ongoing"|"completed"|"hiatus"|"cancelled"|"not_yet_released"|nil, authors: string|string[]|nil, artists: string|string[]|nil, genres: string|string[]|nil, alt_titles: string|string[]|nil, cover_headers: table<string, string>|nil, cover_cookies: table<string, string>|nil }
+---@alias chapter { name: string, url: string, volume: string|nil, number: number|string|nil, language: string|nil, group: string|nil, date: string|number|nil, manga_summary: string|nil, manga_authors: string|string[]|nil, manga_genres: string|string[]|nil }
+---@alias tiles { columns: number, rows: number, order: number[] }
+---@alias page { url: string, index: number, extension: string|nil, headers: table<string, string>|nil, cookies: table<string, string>|nil, tiles: tiles|nil }
 
 
 ----- IMPORTS -----
@@ -26,6 +37,9 @@ const SourceTemplate = `{{ $divider := repeat "-" (plus (max (len .URL) (len .Na
 
 
 ----- VARIABLES -----
+-- The functions below run in several Lua states at once, see "-- @concurrent" in the header.
+-- Every state runs this file and has its own globals, so do not keep session state in them.
+-- Set "-- @concurrent false" to run in a single state where globals are kept between the calls.
 --- END VARIABLES ---
 
 
@@ -55,6 +69,25 @@ function {{ .ChapterPagesFn }}(chapterURL)
 	return {}
 end
 
+
+--- Gets the manga by its URL or by URL of its chapter. Optional.
+-- Only URLs on the same host as the @url above are passed.
+-- @param url string URL of the manga or chapter
+-- @return manga|nil Manga or nil if URL is unknown
+-- function {{ .MangaFromURLFn }}(url)
+-- 	return nil
+-- end
+
+
+--- Transforms the downloaded page image before it is saved. Optional.
+-- Tiles are numbered from 1 left to right, top to bottom.
+-- @param image string Contents of the image
+-- @param page { url: string, index: number, extension: string, chapter: string } Page and URL of its chapter
+-- @return string|tiles|nil New contents of the image, tiles to reassemble it or nil to keep it as is
+-- function {{ .DescramblePageFn }}(image, page)
+-- 	return nil
+-- end
+
 --- END MAIN ---
 
 
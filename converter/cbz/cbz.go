@@ -48,8 +48,23 @@ func SaveTo(chapter *source.Chapter, to string) error {
 
 	defer util.Ignore(cbzFile.Close)
 
-	zipWriter := zip.NewWriter(cbzFile)
-	defer util.Ignore(zipWriter.Close)
+	var comicInfo *source.ComicInfo
+	if viper.GetBool(key.MetadataComicInfoXML) {
+		comicInfo = chapter.ComicInfo()
+	}
+
+	return Write(cbzFile, chapter, comicInfo)
+}
+
+// Write writes pages of the chapter as cbz archive to w.
+// ComicInfo.xml is added if comicInfo is not nil.
+func Write(w io.Writer, chapter *source.Chapter, comicInfo *source.ComicInfo) (err error) {
+	zipWriter := zip.NewWriter(w)
+	defer func() {
+		if closeErr := zipWriter.Close(); err == nil {
+			err = closeErr
+		}
+	}()
 
 	for _, page := range chapter.Pages {
 		if err = addToZip(zipWriter, page.Contents, page.Filename()); err != nil {
@@ -57,16 +72,16 @@ func SaveTo(chapter *source.Chapter, to string) error {
 		}
 	}
 
-	if viper.GetBool(key.MetadataComicInfoXML) {
-		comicInfo := chapter.ComicInfo()
+	if comicInfo != nil {
 		marshalled, err := xml.MarshalIndent(comicInfo, "", "  ")
-		if err == nil {
-			buf := bytes.NewBuffer(marshalled)
-			err = addToZip(zipWriter, buf, "ComicInfo.xml")
+		if err != nil {
+			return err
 		}
+
+		return addToZip(zipWriter, bytes.NewBuffer(marshalled), "ComicInfo.xml")
 	}
 
-	return err
+	return nil
 }
 
 func addToZip(writer *zip.Writer, file io.Reader, name string) error {
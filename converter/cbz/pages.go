@@ -0,0 +1,78 @@
+package cbz
+
+import (
+	"archive/zip"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp"}
+
+// IsImage checks if the file is an image by its extension
+func IsImage(name string) bool {
+	return lo.Contains(imageExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// Pages returns names of the images in the archive sorted by name,
+// which is the order of the pages for the archives saved by mangal
+func Pages(path string) ([]string, error) {
+	var names []string
+	err := withArchive(path, func(reader *zip.Reader) error {
+		for _, file := range reader.File {
+			if IsImage(file.Name) {
+				names = append(names, file.Name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(names)
+	return names, nil
+}
+
+// ReadPage returns the contents of the file in the archive
+func ReadPage(path, name string) (contents []byte, err error) {
+	err = withArchive(path, func(reader *zip.Reader) error {
+		file, err := reader.Open(name)
+		if err != nil {
+			return err
+		}
+
+		defer util.Ignore(file.Close)
+
+		contents, err = io.ReadAll(file)
+		return err
+	})
+
+	return
+}
+
+func withArchive(path string, f func(reader *zip.Reader) error) error {
+	file, err := filesystem.Api().Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer util.Ignore(file.Close)
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	reader, err := zip.NewReader(file, stat.Size())
+	if err != nil {
+		return err
+	}
+
+	return f(reader)
+}
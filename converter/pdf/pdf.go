@@ -39,12 +39,13 @@ func save(chapter *source.Chapter, temp bool) (path string, err error) {
 
 	defer util.Ignore(file.Close)
 
-	err = pagesToPDF(file, chapter.Pages)
+	err = Write(file, chapter.Pages, viper.GetBool(key.FormatsSkipUnsupportedImages))
 	return
 }
 
-// pagesToPDF will convert images to PDF and write to w
-func pagesToPDF(w io.Writer, pages []*source.Page) error {
+// Write will convert images to PDF and write to w.
+// Unsupported images are skipped if skipUnsupported is true, otherwise an error is returned.
+func Write(w io.Writer, pages []*source.Page, skipUnsupported bool) error {
 	conf := pdfcpu.NewDefaultConfiguration()
 	conf.Cmd = pdfcpu.IMPORTIMAGES
 	imp := pdfcpu.DefaultImportConfig()
@@ -74,7 +75,7 @@ func pagesToPDF(w io.Writer, pages []*source.Page) error {
 		indRef, err := pdfcpu.NewPageForImage(ctx.XRefTable, r, pagesIndRef, imp)
 
 		if err != nil {
-			if viper.GetBool(key.FormatsSkipUnsupportedImages) {
+			if skipUnsupported {
 				continue
 			}
 
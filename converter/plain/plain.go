@@ -3,6 +3,7 @@ package plain
 import (
 	"github.com/metafates/mangal/filesystem"
 	"github.com/metafates/mangal/source"
+	"github.com/spf13/afero"
 	"io"
 	"os"
 	"path/filepath"
@@ -29,14 +30,20 @@ func save(chapter *source.Chapter, temp bool) (path string, err error) {
 		return
 	}
 
-	err = filesystem.Api().Mkdir(path, os.ModePerm)
+	err = WriteTo(filesystem.Api(), path, chapter.Pages)
+	return
+}
+
+// WriteTo saves pages as images to the new directory on the given filesystem
+func WriteTo(fs afero.Fs, dir string, pages []*source.Page) (err error) {
+	err = fs.Mkdir(dir, os.ModePerm)
 	if err != nil {
 		return
 	}
 
 	wg := sync.WaitGroup{}
-	wg.Add(len(chapter.Pages))
-	for _, page := range chapter.Pages {
+	wg.Add(len(pages))
+	for _, page := range pages {
 		func(page *source.Page) {
 			defer wg.Done()
 
@@ -44,7 +51,7 @@ func save(chapter *source.Chapter, temp bool) (path string, err error) {
 				return
 			}
 
-			err = savePage(page, path)
+			err = savePage(fs, page, dir)
 		}(page)
 	}
 
@@ -52,8 +59,8 @@ func save(chapter *source.Chapter, temp bool) (path string, err error) {
 	return
 }
 
-func savePage(page *source.Page, to string) error {
-	file, err := filesystem.Api().Create(filepath.Join(to, page.Filename()))
+func savePage(fs afero.Fs, page *source.Page, to string) error {
+	file, err := fs.Create(filepath.Join(to, page.Filename()))
 	if err != nil {
 		return err
 	}
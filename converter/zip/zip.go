@@ -36,16 +36,29 @@ func save(chapter *source.Chapter, temp bool) (path string, err error) {
 
 	defer util.Ignore(zipFile.Close)
 
-	zipWriter := zip.NewWriter(zipFile)
-	defer util.Ignore(zipWriter.Close)
+	if err = Write(zipFile, chapter.Pages); err != nil {
+		return "", err
+	}
+
+	return
+}
 
-	for _, page := range chapter.Pages {
+// Write writes pages as zip archive to w
+func Write(w io.Writer, pages []*source.Page) (err error) {
+	zipWriter := zip.NewWriter(w)
+	defer func() {
+		if closeErr := zipWriter.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, page := range pages {
 		if err = addToZip(zipWriter, page.Contents, page.Filename()); err != nil {
-			return "", err
+			return err
 		}
 	}
 
-	return
+	return nil
 }
 
 func addToZip(writer *zip.Writer, file io.Reader, name string) error {
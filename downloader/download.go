@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/metafates/mangal/color"
 	"github.com/metafates/mangal/converter"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/filesystem"
 	"github.com/metafates/mangal/history"
 	"github.com/metafates/mangal/key"
@@ -16,8 +17,24 @@ import (
 	"path/filepath"
 )
 
+// Hooks are called on the stages of the chapter download.
+// Any of them can be nil.
+type Hooks struct {
+	// OnPages is called when the list of pages is fetched
+	OnPages func(pages []*source.Page)
+	// OnPage is called after each page is downloaded
+	OnPage func(page *source.Page, err error)
+	// OnConverted is called when pages are converted and saved to the path
+	OnConverted func(format, path string)
+}
+
 // Download the chapter using given source.
 func Download(chapter *source.Chapter, progress func(string)) (string, error) {
+	return DownloadWith(chapter, progress, &Hooks{})
+}
+
+// DownloadWith downloads the chapter using given source and calls the hooks on each stage.
+func DownloadWith(chapter *source.Chapter, progress func(string), hooks *Hooks) (string, error) {
 	log.Info("downloading " + chapter.Name)
 
 	path, err := chapter.Path(false)
@@ -46,8 +63,11 @@ func Download(chapter *source.Chapter, progress func(string)) (string, error) {
 		return "", err
 	}
 	log.Info("found " + fmt.Sprintf("%d", len(pages)) + " pages")
+	if hooks.OnPages != nil {
+		hooks.OnPages(pages)
+	}
 
-	err = chapter.DownloadPages(false, progress)
+	err = chapter.DownloadPagesWith(false, progress, hooks.OnPage)
 	if err != nil {
 		log.Error(err)
 		return "", err
@@ -104,7 +124,11 @@ func Download(chapter *source.Chapter, progress func(string)) (string, error) {
 	path, err = conv.Save(chapter)
 	if err != nil {
 		log.Error(err)
-		return "", err
+		return "", errs.New(errs.Conversion, err)
+	}
+
+	if hooks.OnConverted != nil {
+		hooks.OnConverted(viper.GetString(key.FormatsUse), path)
 	}
 
 	if viper.GetBool(key.HistorySaveOnDownload) {
@@ -11,6 +11,7 @@ import (
 	"github.com/metafates/mangal/open"
 	"github.com/metafates/mangal/source"
 	"github.com/metafates/mangal/style"
+	"github.com/metafates/mangal/webreader"
 	"github.com/spf13/viper"
 )
 
@@ -24,6 +25,10 @@ func Read(chapter *source.Chapter, progress func(string)) error {
 		)
 	}
 
+	if viper.GetBool(key.ReaderBuiltin) {
+		return readBuiltin(chapter, progress)
+	}
+
 	if viper.GetBool(key.DownloaderReadDownloaded) && chapter.IsDownloaded() {
 		path, err := chapter.Path(false)
 		if err == nil {
@@ -76,6 +81,27 @@ func Read(chapter *source.Chapter, progress func(string)) error {
 	return nil
 }
 
+// readBuiltin opens the chapter in the built-in web reader.
+// Pages are served from the downloaded chapter if it exists, otherwise streamed from the source.
+func readBuiltin(chapter *source.Chapter, progress func(string)) error {
+	progress("Starting reader")
+	url, err := webreader.Open(chapter)
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("could not start the reader: %w", err)
+	}
+
+	log.Info("reading at " + url)
+	progress(fmt.Sprintf("Reading at %s", url))
+
+	// there may be no browser, e.g. over SSH, so the url is shown anyway
+	if err = open.StartWith(url, viper.GetString(key.ReaderBrowser)); err != nil {
+		log.Warn(err)
+	}
+
+	return nil
+}
+
 func openRead(path string, chapter *source.Chapter, progress func(string)) error {
 	if viper.GetBool(key.HistorySaveOnRead) {
 		go func() {
@@ -0,0 +1,118 @@
+// Package errs defines kinds of errors that can be told apart by scripts
+// through the exit codes and the error objects in the inline JSON output.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Kind is a category of errors
+type Kind int
+
+const (
+	// Unknown is an error of any other kind
+	Unknown Kind = iota
+	// SourceNotFound is returned when there is no source with the given name
+	SourceNotFound
+	// NoResults is returned when search or manga picker found nothing
+	NoResults
+	// Network is returned when a request failed or returned unexpected status
+	Network
+	// RateLimited is returned when a remote service refused the request because of too many requests
+	RateLimited
+	// Lua is returned when a custom Lua source failed or returned malformed data
+	Lua
+	// Conversion is returned when pages could not be converted to the output format
+	Conversion
+	// PartialDownload is returned when some chapters were not downloaded
+	PartialDownload
+)
+
+var names = map[Kind]string{
+	Unknown:         "unknown",
+	SourceNotFound:  "source_not_found",
+	NoResults:       "no_results",
+	Network:         "network",
+	RateLimited:     "rate_limited",
+	Lua:             "lua",
+	Conversion:      "conversion",
+	PartialDownload: "partial_download",
+}
+
+// Kinds returns all kinds of errors
+func Kinds() []Kind {
+	return []Kind{Unknown, SourceNotFound, NoResults, Network, RateLimited, Lua, Conversion, PartialDownload}
+}
+
+func (k Kind) String() string {
+	if name, ok := names[k]; ok {
+		return name
+	}
+
+	return names[Unknown]
+}
+
+// ExitCode of the program for the error of this kind.
+// Code 2 is skipped since it is commonly used for invalid usage.
+func (k Kind) ExitCode() int {
+	if k == Unknown {
+		return 1
+	}
+
+	return int(k) + 2
+}
+
+// Error is an error of the specific kind
+type Error struct {
+	kind Kind
+	err  error
+}
+
+// New wraps the error with the kind. Returns nil if err is nil.
+func New(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{kind: kind, err: err}
+}
+
+// Newf creates a new error of the kind with the formatted message
+func Newf(kind Kind, format string, args ...any) error {
+	return &Error{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+func (e *Error) Kind() Kind {
+	return e.kind
+}
+
+// KindOf returns the kind of the error.
+// The outermost error that has a kind wins.
+// Network errors from the standard library are recognized without being wrapped.
+func KindOf(err error) Kind {
+	if err == nil {
+		return Unknown
+	}
+
+	var kinded interface{ Kind() Kind }
+	if errors.As(err, &kinded) {
+		return kinded.Kind()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return Network
+	}
+
+	return Unknown
+}
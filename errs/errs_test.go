@@ -0,0 +1,53 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"net"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	Convey("Given errors of different kinds", t, func() {
+		Convey("When the error is wrapped with the kind", func() {
+			err := fmt.Errorf("searching: %w", New(NoResults, errors.New("nothing")))
+
+			Convey("Then the kind should be found through other wrappers", func() {
+				So(KindOf(err), ShouldEqual, NoResults)
+				So(err.Error(), ShouldEqual, "searching: nothing")
+			})
+		})
+
+		Convey("When the error is a network error from the standard library", func() {
+			err := fmt.Errorf("downloading: %w", &net.DNSError{Err: "no such host", Name: "example.com"})
+
+			Convey("Then it should be a network error", func() {
+				So(KindOf(err), ShouldEqual, Network)
+			})
+		})
+
+		Convey("When the error has no kind", func() {
+			Convey("Then it should be unknown", func() {
+				So(KindOf(errors.New("oops")), ShouldEqual, Unknown)
+				So(KindOf(nil), ShouldEqual, Unknown)
+				So(New(Lua, nil), ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	Convey("Exit codes should be distinct and never 0 or 2", t, func() {
+		seen := make(map[int]bool)
+		for _, kind := range Kinds() {
+			code := kind.ExitCode()
+			So(code, ShouldNotEqual, 0)
+			So(code, ShouldNotEqual, 2)
+			So(seen[code], ShouldBeFalse)
+			seen[code] = true
+		}
+
+		So(Unknown.ExitCode(), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,204 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/util"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// Interaction is the recorded request and its response
+type Interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// RequestBody is the body of the request, used to tell apart requests to the same url
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+	// Base64 is true if the body is not a valid UTF-8 text and is encoded
+	Base64 bool `json:"base64,omitempty"`
+}
+
+// Fixture is the recorded HTTP traffic of the test run
+type Fixture struct {
+	// Query, Manga and Chapter are the arguments of the recorded run, so that the replay is the same
+	Query        string         `json:"query"`
+	Manga        int            `json:"manga"`
+	Chapter      int            `json:"chapter"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// LoadFixture reads the fixture file
+func LoadFixture(path string) (*Fixture, error) {
+	contents, err := filesystem.Api().ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err = json.Unmarshal(contents, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+
+	return &fixture, nil
+}
+
+// Save writes the fixture file
+func (f *Fixture) Save(path string) error {
+	contents, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = filesystem.Api().MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	return filesystem.Api().WriteFile(path, contents, os.ModePerm)
+}
+
+func encodeBody(body []byte) (string, bool) {
+	if utf8.Valid(body) {
+		return string(body), false
+	}
+
+	return base64.StdEncoding.EncodeToString(body), true
+}
+
+func (i *Interaction) body() ([]byte, error) {
+	if i.Base64 {
+		return base64.StdEncoding.DecodeString(i.Body)
+	}
+
+	return []byte(i.Body), nil
+}
+
+// readRequestBody reads the body of the request and restores it, so that it can be sent
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body), nil
+}
+
+// recorder is the transport that records the traffic to the fixture
+type recorder struct {
+	mutex   *sync.Mutex
+	fixture *Fixture
+	base    http.RoundTripper
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer util.Ignore(res.Body.Close)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	encoded, isBase64 := encodeBody(body)
+	r.mutex.Lock()
+	r.fixture.Interactions = append(r.fixture.Interactions, &Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: requestBody,
+		Status:      res.StatusCode,
+		Header:      res.Header,
+		Body:        encoded,
+		Base64:      isBase64,
+	})
+	r.mutex.Unlock()
+
+	return res, nil
+}
+
+// replayer is the transport that responds with the recorded interactions without network.
+// Repeated requests get the recorded responses in order, the last one is repeated when they run out.
+type replayer struct {
+	mutex   *sync.Mutex
+	fixture *Fixture
+	// used counts the replayed interactions by their index
+	used map[int]int
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var matched []int
+	for i, interaction := range r.fixture.Interactions {
+		if interaction.Method == req.Method && interaction.URL == req.URL.String() && interaction.RequestBody == requestBody {
+			matched = append(matched, i)
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s, record the fixture again", req.Method, req.URL)
+	}
+
+	index := matched[len(matched)-1]
+	for _, i := range matched {
+		if r.used[i] == 0 {
+			index = i
+			break
+		}
+	}
+
+	r.used[index]++
+	interaction := r.fixture.Interactions[index]
+
+	body, err := interaction.body()
+	if err != nil {
+		return nil, err
+	}
+
+	header := interaction.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,291 @@
+package harness
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/provider/custom"
+	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/util"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Mode of the HTTP traffic in the test run
+type Mode int
+
+const (
+	// Live sends requests to the network
+	Live Mode = iota
+	// Record sends requests to the network and saves them to the fixture
+	Record
+	// Replay responds with the requests from the fixture without network
+	Replay
+)
+
+// Options of the test run
+type Options struct {
+	// Query to search manga with. When replaying, the recorded query is used if it is empty
+	Query string
+	// Manga is the index of the search result to get chapters of
+	Manga int
+	// Chapter is the index of the chapter to get pages of
+	Chapter int
+	Mode    Mode
+	// Fixture is the path to the fixture file for recording and replaying
+	Fixture string
+}
+
+// Step is the result of one of the scraper functions
+type Step struct {
+	// Function name, e.g. SearchManga
+	Function string
+	// Argument that the function was called with
+	Argument string
+	Duration time.Duration
+	// Count of the returned items
+	Count int
+	// Problems with the returned items
+	Problems []string
+	// Err is the error raised by the function
+	Err error
+}
+
+// Failed returns true if the function raised an error or returned invalid items
+func (s *Step) Failed() bool {
+	return s.Err != nil || len(s.Problems) > 0
+}
+
+// Report of the test run
+type Report struct {
+	Steps []*Step
+}
+
+// Failed returns true if any of the steps failed
+func (r *Report) Failed() bool {
+	for _, step := range r.Steps {
+		if step.Failed() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Err returns the error of the first failed step, nil if none failed.
+// The kind of the step error is kept, otherwise it is a Lua error.
+func (r *Report) Err() error {
+	for _, step := range r.Steps {
+		if !step.Failed() {
+			continue
+		}
+
+		kind := errs.Lua
+		if step.Err != nil && errs.KindOf(step.Err) != errs.Unknown {
+			kind = errs.KindOf(step.Err)
+		}
+
+		return errs.Newf(kind, "%s failed", step.Function)
+	}
+
+	return nil
+}
+
+// Run loads the scraper from the path and runs its functions one after another:
+// SearchManga with the query, MangaChapters of the found manga and ChapterPages of its chapter.
+// The returned items are validated field by field.
+// The run stops when a function raises an error or returns too few items for the next step.
+func Run(path string, options Options) (*Report, error) {
+	var (
+		fixture   = &Fixture{}
+		mutex     = &sync.Mutex{}
+		transport func(http.RoundTripper) http.RoundTripper
+	)
+
+	switch options.Mode {
+	case Record:
+		fixture.Query, fixture.Manga, fixture.Chapter = options.Query, options.Manga, options.Chapter
+		transport = func(base http.RoundTripper) http.RoundTripper {
+			return &recorder{mutex: mutex, fixture: fixture, base: base}
+		}
+	case Replay:
+		loaded, err := LoadFixture(options.Fixture)
+		if err != nil {
+			return nil, err
+		}
+
+		fixture = loaded
+		if options.Query == "" {
+			options.Query, options.Manga, options.Chapter = fixture.Query, fixture.Manga, fixture.Chapter
+		}
+
+		replay := &replayer{mutex: mutex, fixture: fixture, used: make(map[int]int)}
+		transport = func(http.RoundTripper) http.RoundTripper {
+			return replay
+		}
+	}
+
+	if options.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	src, err := custom.LoadSourceWith(path, custom.Options{
+		Validate:  true,
+		NoCache:   true,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer util.Ignore(src.(io.Closer).Close)
+
+	report := run(src, options)
+
+	if options.Mode == Record {
+		if err = fixture.Save(options.Fixture); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func run(src source.Source, options Options) *Report {
+	var report Report
+
+	step := func(function, argument string, call func() (int, []string, error)) bool {
+		s := &Step{Function: function, Argument: argument}
+		start := time.Now()
+		s.Count, s.Problems, s.Err = call()
+		s.Duration = time.Since(start)
+
+		report.Steps = append(report.Steps, s)
+		return s.Err == nil
+	}
+
+	var mangas []*source.Manga
+	ok := step(constant.SearchMangaFn, options.Query, func() (_ int, _ []string, err error) {
+		mangas, err = src.Search(options.Query)
+		problems := validateMangas(mangas)
+		if err == nil && len(mangas) <= options.Manga {
+			problems = append(problems, fmt.Sprintf("expected at least %d manga", options.Manga+1))
+		}
+
+		return len(mangas), problems, err
+	})
+	if !ok || len(mangas) <= options.Manga {
+		return &report
+	}
+
+	manga := mangas[options.Manga]
+	var chapters []*source.Chapter
+	ok = step(constant.MangaChaptersFn, manga.URL, func() (_ int, _ []string, err error) {
+		chapters, err = src.ChaptersOf(manga)
+		problems := validateChapters(chapters)
+		if err == nil && len(chapters) <= options.Chapter {
+			problems = append(problems, fmt.Sprintf("expected at least %d chapters", options.Chapter+1))
+		}
+
+		return len(chapters), problems, err
+	})
+	if !ok || len(chapters) <= options.Chapter {
+		return &report
+	}
+
+	chapter := chapters[options.Chapter]
+	step(constant.ChapterPagesFn, chapter.URL, func() (int, []string, error) {
+		pages, err := src.PagesOf(chapter)
+		return len(pages), validatePages(pages), err
+	})
+
+	return &report
+}
+
+// checkURL returns a problem if the value is not an absolute http url
+func checkURL(field, value string) (string, bool) {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Sprintf("%s %q is invalid: %s", field, value, err), false
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Sprintf("%s %q is not an absolute http url", field, value), false
+	}
+
+	return "", true
+}
+
+func validateMangas(mangas []*source.Manga) (problems []string) {
+	urls := make(map[string]int)
+	for i, manga := range mangas {
+		prefix := fmt.Sprintf("manga #%d", i+1)
+
+		if manga.Name == "" {
+			problems = append(problems, prefix+": name is empty")
+		}
+
+		if problem, ok := checkURL(prefix+": url", manga.URL); !ok {
+			problems = append(problems, problem)
+		} else if j, ok := urls[manga.URL]; ok {
+			problems = append(problems, fmt.Sprintf("%s: url is the same as of manga #%d", prefix, j+1))
+		} else {
+			urls[manga.URL] = i
+		}
+
+		if cover := manga.Metadata.Cover.ExtraLarge; cover != "" {
+			if problem, ok := checkURL(prefix+": cover", cover); !ok {
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	return problems
+}
+
+func validateChapters(chapters []*source.Chapter) (problems []string) {
+	urls := make(map[string]int)
+	for i, chapter := range chapters {
+		prefix := fmt.Sprintf("chapter #%d", i+1)
+
+		if chapter.Name == "" {
+			problems = append(problems, prefix+": name is empty")
+		}
+
+		if problem, ok := checkURL(prefix+": url", chapter.URL); !ok {
+			problems = append(problems, problem)
+		} else if j, ok := urls[chapter.URL]; ok {
+			problems = append(problems, fmt.Sprintf("%s: url is the same as of chapter #%d", prefix, j+1))
+		} else {
+			urls[chapter.URL] = i
+		}
+	}
+
+	return problems
+}
+
+func validatePages(pages []*source.Page) (problems []string) {
+	if len(pages) == 0 {
+		return []string{"no pages"}
+	}
+
+	indexes := make(map[uint16]int)
+	for i, page := range pages {
+		prefix := fmt.Sprintf("page #%d", i+1)
+
+		if problem, ok := checkURL(prefix+": url", page.URL); !ok {
+			problems = append(problems, problem)
+		}
+
+		if j, ok := indexes[page.Index]; ok {
+			problems = append(problems, fmt.Sprintf("%s: index %d is the same as of page #%d", prefix, page.Index, j+1))
+		} else {
+			indexes[page.Index] = i
+		}
+	}
+
+	return problems
+}
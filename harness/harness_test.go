@@ -0,0 +1,142 @@
+package harness
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+const testScraper = `-- @url %s
+
+local http = require("http")
+local client = http.client()
+
+local function get(url)
+	local response, err = client:do_request(http.request("GET", url))
+	if err then
+		error(err)
+	end
+
+	return response.body
+end
+
+function SearchManga(query)
+	local name = get("%s/search?q=" .. http.query_escape(query))
+	return { { name = name, url = "%s/manga/1" } }
+end
+
+function MangaChapters(mangaURL)
+	local name = get(mangaURL)
+	return { { name = name, url = mangaURL .. "/chapter/1" }, { name = "", url = "chapter/2" } }
+end
+
+function ChapterPages(chapterURL)
+	return { { url = get(chapterURL), index = 1 } }
+end
+`
+
+func TestRun(t *testing.T) {
+	Convey("Given the scraper of the website", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.LuaSandbox, true)
+		viper.Set(key.LuaTimeout, 0)
+		viper.Set(key.LuaInstructionLimit, 0)
+		viper.Set(key.LuaStackLimit, 0)
+		viper.Set(key.LuaMemoryLimit, 0)
+
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			switch r.URL.Path {
+			case "/search":
+				_, _ = w.Write([]byte("Result for " + r.URL.Query().Get("q")))
+			case "/manga/1":
+				_, _ = w.Write([]byte("Chapter 1"))
+			case "/manga/1/chapter/1":
+				_, _ = w.Write([]byte("https://example.com/1.png"))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+
+		path := "/sources/test.lua"
+		scraper := fmt.Sprintf(testScraper, server.URL, server.URL, server.URL)
+		So(filesystem.Api().WriteFile(path, []byte(scraper), os.ModePerm), ShouldBeNil)
+
+		fixture := "/fixtures/test.json"
+
+		Convey("When it is run with recording", func() {
+			report, err := Run(path, Options{Query: "one piece", Mode: Record, Fixture: fixture})
+
+			Convey("Then every function should be called", func() {
+				So(err, ShouldBeNil)
+				So(report.Steps, ShouldHaveLength, 3)
+				So(report.Steps[0].Failed(), ShouldBeFalse)
+				So(report.Steps[0].Count, ShouldEqual, 1)
+			})
+
+			Convey("Then invalid chapters should be reported field by field", func() {
+				So(report.Failed(), ShouldBeTrue)
+				So(errs.KindOf(report.Err()), ShouldEqual, errs.Lua)
+				So(report.Err().Error(), ShouldContainSubstring, "MangaChapters failed")
+				So(report.Steps[1].Problems, ShouldResemble, []string{
+					"chapter #2: name is empty",
+					`chapter #2: url "chapter/2" is not an absolute http url`,
+				})
+			})
+
+			Convey("Then the traffic should be saved to the fixture", func() {
+				recorded, err := LoadFixture(fixture)
+				So(err, ShouldBeNil)
+				So(recorded.Query, ShouldEqual, "one piece")
+				So(recorded.Interactions, ShouldHaveLength, requests)
+			})
+
+			Convey("And it is replayed without the website", func() {
+				server.Close()
+				recorded := requests
+
+				replayed, err := Run(path, Options{Mode: Replay, Fixture: fixture})
+
+				Convey("Then the report should be the same", func() {
+					So(err, ShouldBeNil)
+					So(requests, ShouldEqual, recorded)
+					So(replayed.Steps, ShouldHaveLength, 3)
+					for i, step := range replayed.Steps {
+						So(step.Argument, ShouldEqual, report.Steps[i].Argument)
+						So(step.Count, ShouldEqual, report.Steps[i].Count)
+						So(step.Problems, ShouldResemble, report.Steps[i].Problems)
+						So(step.Err, ShouldBeNil)
+					}
+				})
+			})
+
+			Convey("And it is replayed with another query", func() {
+				replayed, err := Run(path, Options{Query: "naruto", Mode: Replay, Fixture: fixture})
+
+				Convey("Then the missing response should fail the step", func() {
+					So(err, ShouldBeNil)
+					So(replayed.Steps, ShouldHaveLength, 1)
+					So(replayed.Steps[0].Err, ShouldNotBeNil)
+					So(replayed.Steps[0].Err.Error(), ShouldContainSubstring, "no recorded response")
+				})
+			})
+		})
+
+		Convey("When it is run without query", func() {
+			_, err := Run(path, Options{})
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -3,6 +3,7 @@ package history
 import (
 	"fmt"
 	"github.com/metafates/mangal/source"
+	"github.com/samber/mo"
 )
 
 type SavedChapter struct {
@@ -14,7 +15,10 @@ type SavedChapter struct {
 	URL                string `json:"url"`
 	ID                 string `json:"id"`
 	Index              int    `json:"index"`
+	Volume             string `json:"volume,omitempty"`
 	MangaID            string `json:"manga_id"`
+	// Synced is the progress at the moment of the last sync, per integration
+	Synced map[string]int `json:"synced,omitempty"`
 }
 
 func (c *SavedChapter) encode() string {
@@ -25,6 +29,23 @@ func (c *SavedChapter) String() string {
 	return fmt.Sprintf("%s : %d / %d", c.MangaName, c.Index, c.MangaChaptersTotal)
 }
 
+// synced returns the progress at the moment of the last sync with the integration
+func (c *SavedChapter) synced(integration string) mo.Option[int] {
+	if progress, ok := c.Synced[integration]; ok {
+		return mo.Some(progress)
+	}
+
+	return mo.None[int]()
+}
+
+func (c *SavedChapter) setSynced(integration string, progress int) {
+	if c.Synced == nil {
+		c.Synced = make(map[string]int)
+	}
+
+	c.Synced[integration] = progress
+}
+
 func newSavedChapter(chapter *source.Chapter) *SavedChapter {
 	return &SavedChapter{
 		SourceID:           chapter.Manga.Source.ID(),
@@ -36,5 +57,6 @@ func newSavedChapter(chapter *source.Chapter) *SavedChapter {
 		MangaID:            chapter.Manga.ID,
 		MangaChaptersTotal: len(chapter.Manga.Chapters),
 		Index:              int(chapter.Index),
+		Volume:             chapter.Volume,
 	}
-}
\ No newline at end of file
+}
@@ -4,11 +4,11 @@ import (
 	"github.com/metafates/gache"
 	"github.com/metafates/mangal/filesystem"
 	"github.com/metafates/mangal/integration"
-	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/integration/outbox"
 	"github.com/metafates/mangal/log"
 	"github.com/metafates/mangal/source"
 	"github.com/metafates/mangal/where"
-	"github.com/spf13/viper"
+	"github.com/samber/mo"
 )
 
 var cacher = gache.New[map[string]*SavedChapter](
@@ -35,14 +35,15 @@ func Get() (chapters map[string]*SavedChapter, err error) {
 
 // Save saves the chapter to the history file
 func Save(chapter *source.Chapter) error {
-	if viper.GetBool(key.AnilistEnable) {
-		go func() {
-			log.Info("Saving chapter to anilist")
-			err := integration.Anilist.MarkRead(chapter)
-			if err != nil {
-				log.Warn("Saving chapter to anilist failed: " + err.Error())
+	if enabled := integration.Enabled(); len(enabled) > 0 {
+		// save to the outbox first so that updates are not lost if sending fails
+		for _, integrator := range enabled {
+			if err := outbox.Add(integrator, chapter); err != nil {
+				log.Warnf("Saving chapter to %s outbox failed: %s", integrator.Name(), err)
 			}
-		}()
+		}
+
+		outbox.FlushInBackground()
 	}
 
 	saved, err := Get()
@@ -51,11 +52,30 @@ func Save(chapter *source.Chapter) error {
 	}
 
 	savedChapter := newSavedChapter(chapter)
+	if previous, ok := saved[savedChapter.encode()]; ok {
+		savedChapter.Synced = previous.Synced
+	}
+
 	saved[savedChapter.encode()] = savedChapter
 
 	return cacher.Set(saved)
 }
 
+// Last returns the last saved chapter of the manga
+func Last(manga *source.Manga) (mo.Option[*SavedChapter], error) {
+	saved, err := Get()
+	if err != nil {
+		return mo.None[*SavedChapter](), err
+	}
+
+	key := (&SavedChapter{MangaName: manga.Name, SourceID: manga.Source.ID()}).encode()
+	if chapter, ok := saved[key]; ok {
+		return mo.Some(chapter), nil
+	}
+
+	return mo.None[*SavedChapter](), nil
+}
+
 // Remove removes the chapter from the history file
 func Remove(chapter *SavedChapter) error {
 	saved, err := Get()
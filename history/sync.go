@@ -0,0 +1,238 @@
+package history
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/integration"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+	"golang.org/x/exp/slices"
+	"strings"
+)
+
+// SyncAction is what was done with the history entry during the sync
+type SyncAction string
+
+const (
+	// SyncUpToDate means that local and remote progress are the same
+	SyncUpToDate SyncAction = "up to date"
+	// SyncPushed means that local progress was sent to the remote list
+	SyncPushed SyncAction = "pushed"
+	// SyncPulled means that remote progress was saved to the local history
+	SyncPulled SyncAction = "pulled"
+	// SyncConflict means that both local and remote progress were changed since the last sync
+	SyncConflict SyncAction = "conflict"
+	// SyncFailed means that the entry could not be synced
+	SyncFailed SyncAction = "failed"
+	// SyncIgnored means that the chapter is ignored by the mapping rules
+	SyncIgnored SyncAction = "ignored"
+)
+
+// SyncPreference tells how to resolve conflicts
+type SyncPreference string
+
+const (
+	// PreferNone reports conflicts without resolving them
+	PreferNone SyncPreference = ""
+	// PreferLocal resolves conflicts by pushing local progress
+	PreferLocal SyncPreference = "local"
+	// PreferRemote resolves conflicts by pulling remote progress
+	PreferRemote SyncPreference = "remote"
+)
+
+// SyncResult is the result of syncing a single history entry
+type SyncResult struct {
+	Chapter *SavedChapter
+	Action  SyncAction
+	Local   int
+	Remote  int
+	Err     error
+}
+
+func (r *SyncResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s: %s (%s)", r.Chapter.MangaName, r.Action, r.Err)
+	}
+
+	return fmt.Sprintf("%s: %s (local %d, remote %d)", r.Chapter.MangaName, r.Action, r.Local, r.Remote)
+}
+
+// ChaptersOf returns the chapters of the saved manga from its source.
+// It is used to find the chapter that the pulled progress points to.
+type ChaptersOf func(chapter *SavedChapter) ([]*source.Chapter, error)
+
+// Sync reconciles local history with the remote list in both directions.
+// Progress that was changed only on one side since the last sync is propagated to the other one.
+// If both sides were changed, the entry is reported as conflict and resolved according to the preference.
+// When dryRun is set, nothing is changed.
+func Sync(integrator integration.Integrator, prefer SyncPreference, dryRun bool, chaptersOf ChaptersOf) ([]*SyncResult, error) {
+	saved, err := Get()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(saved))
+	for k := range saved {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var results = make([]*SyncResult, len(keys))
+	for i, k := range keys {
+		results[i] = syncChapter(integrator, saved[k], prefer, dryRun, chaptersOf)
+	}
+
+	if dryRun {
+		return results, nil
+	}
+
+	return results, cacher.Set(saved)
+}
+
+func syncChapter(integrator integration.Integrator, chapter *SavedChapter, prefer SyncPreference, dryRun bool, chaptersOf ChaptersOf) *SyncResult {
+	name := integrator.Name()
+	manga := &source.Manga{Name: chapter.MangaName, URL: chapter.MangaURL, ID: chapter.MangaID}
+	result := &SyncResult{Chapter: chapter}
+
+	local, ok := anilist.MapChapter(chapter.MangaName, chapter.Index, chapter.Volume)
+	if !ok {
+		result.Action = SyncIgnored
+		return result
+	}
+
+	result.Local = local
+
+	fail := func(err error) *SyncResult {
+		log.Warnf("Syncing %s with %s failed: %s", chapter.MangaName, name, err)
+		result.Action = SyncFailed
+		result.Err = err
+		return result
+	}
+
+	entry, err := integrator.GetEntry(manga)
+	if err != nil {
+		return fail(err)
+	}
+
+	if e, ok := entry.Get(); ok {
+		result.Remote = e.Progress
+	}
+
+	result.Action = resolve(result.Local, result.Remote, chapter.synced(name), prefer)
+
+	// remote progress that has no source chapter, e.g. 0 or before the offset, is not pulled
+	if result.Action == SyncPulled && !mappable(chapter.MangaName, result.Remote) {
+		result.Action = SyncIgnored
+		return result
+	}
+
+	if dryRun {
+		return result
+	}
+
+	switch result.Action {
+	case SyncPushed:
+		if err = integrator.SetProgress(manga, result.Local); err != nil {
+			return fail(err)
+		}
+
+		chapter.setSynced(name, result.Local)
+	case SyncPulled:
+		if err = pull(chapter, result.Remote, chaptersOf); err != nil {
+			return fail(err)
+		}
+
+		chapter.setSynced(name, result.Remote)
+	case SyncUpToDate:
+		chapter.setSynced(name, result.Local)
+	}
+
+	return result
+}
+
+// mappable checks that Anilist chapter number can be converted back to the source chapter
+func mappable(name string, number int) bool {
+	if _, ok := anilist.UnmapChapter(name, number); ok {
+		return true
+	}
+
+	_, ok := anilist.UnmapVolume(name, number)
+	return ok
+}
+
+// pull replaces the saved chapter with the chapter of the manga that Anilist chapter number maps to,
+// so that the history entry describes the chapter it resumes from.
+// Chapters of the mapped volumes are found by the volume name, others by the index.
+func pull(saved *SavedChapter, number int, chaptersOf ChaptersOf) error {
+	chapters, err := chaptersOf(saved)
+	if err != nil {
+		return err
+	}
+
+	var chapter *source.Chapter
+	if index, ok := anilist.UnmapChapter(saved.MangaName, number); ok {
+		chapter, _ = lo.Find(chapters, func(c *source.Chapter) bool {
+			mapped, ok := anilist.MapChapter(saved.MangaName, int(c.Index), c.Volume)
+			return int(c.Index) == index && ok && mapped == number
+		})
+	}
+
+	if chapter == nil {
+		if volume, ok := anilist.UnmapVolume(saved.MangaName, number); ok {
+			// the last chapter of the volume
+			for _, c := range chapters {
+				if strings.TrimSpace(c.Volume) == volume {
+					chapter = c
+				}
+			}
+		}
+	}
+
+	if chapter == nil {
+		return fmt.Errorf("chapter #%d of %s was not found in the source", number, saved.MangaName)
+	}
+
+	saved.Name = chapter.Name
+	saved.URL = chapter.URL
+	saved.ID = chapter.ID
+	saved.Index = int(chapter.Index)
+	saved.Volume = chapter.Volume
+	saved.MangaChaptersTotal = len(chapters)
+	return nil
+}
+
+// resolve decides what to do with local and remote progress.
+// base is the progress at the moment of the last sync, if any.
+func resolve(local, remote int, base mo.Option[int], prefer SyncPreference) SyncAction {
+	if local == remote {
+		return SyncUpToDate
+	}
+
+	b, ok := base.Get()
+	if !ok {
+		// never synced before, progress only moves forward
+		if local > remote {
+			return SyncPushed
+		}
+
+		return SyncPulled
+	}
+
+	switch {
+	case remote == b:
+		return SyncPushed
+	case local == b:
+		return SyncPulled
+	}
+
+	switch prefer {
+	case PreferLocal:
+		return SyncPushed
+	case PreferRemote:
+		return SyncPulled
+	default:
+		return SyncConflict
+	}
+}
@@ -0,0 +1,219 @@
+package history
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/integration/tracker"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/mo"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type testIntegrator struct {
+	progress map[string]int
+}
+
+func (t *testIntegrator) Name() string {
+	return "test"
+}
+
+func (t *testIntegrator) MarkRead(chapter *source.Chapter) error {
+	t.progress[chapter.Manga.Name] = int(chapter.Index)
+	return nil
+}
+
+func (t *testIntegrator) GetEntry(manga *source.Manga) (mo.Option[*tracker.Entry], error) {
+	progress, ok := t.progress[manga.Name]
+	if !ok {
+		return mo.None[*tracker.Entry](), nil
+	}
+
+	return mo.Some(&tracker.Entry{Progress: progress, Status: tracker.Current}), nil
+}
+
+func (t *testIntegrator) SetProgress(manga *source.Manga, progress int) error {
+	t.progress[manga.Name] = progress
+	return nil
+}
+
+func (t *testIntegrator) SetStatus(*source.Manga, tracker.Status) error {
+	return nil
+}
+
+func (t *testIntegrator) SetScore(*source.Manga, float64) error {
+	return nil
+}
+
+func (t *testIntegrator) SetDates(*source.Manga, tracker.Date, tracker.Date) error {
+	return nil
+}
+
+func TestSync(t *testing.T) {
+	Convey("Given local history and remote list", t, func() {
+		integrator := &testIntegrator{progress: map[string]int{
+			"remote ahead": 10,
+			"same":         3,
+			"conflict":     7,
+		}}
+
+		chapters := []*SavedChapter{
+			{MangaName: "local ahead", SourceID: "test", Index: 5},
+			{MangaName: "remote ahead", SourceID: "test", Index: 2},
+			{MangaName: "same", SourceID: "test", Index: 3},
+			{MangaName: "conflict", SourceID: "test", Index: 6, Synced: map[string]int{"test": 4}},
+		}
+
+		saved := make(map[string]*SavedChapter)
+		for _, chapter := range chapters {
+			saved[chapter.encode()] = chapter
+		}
+		So(cacher.Set(saved), ShouldBeNil)
+
+		chaptersOf := func(saved *SavedChapter) ([]*source.Chapter, error) {
+			manga := &source.Manga{Name: saved.MangaName}
+			for i := 1; i <= 10; i++ {
+				manga.Chapters = append(manga.Chapters, &source.Chapter{
+					Name:  fmt.Sprintf("Chapter %d", i),
+					URL:   fmt.Sprintf("https://example.com/%d", i),
+					Index: uint16(i),
+					Manga: manga,
+				})
+			}
+
+			return manga.Chapters, nil
+		}
+
+		actionOf := func(results []*SyncResult, name string) SyncAction {
+			for _, result := range results {
+				if result.Chapter.MangaName == name {
+					return result.Action
+				}
+			}
+
+			return ""
+		}
+
+		Convey("When syncing without preference", func() {
+			results, err := Sync(integrator, PreferNone, false, chaptersOf)
+			So(err, ShouldBeNil)
+
+			Convey("Then changes should be propagated in both directions", func() {
+				So(actionOf(results, "local ahead"), ShouldEqual, SyncPushed)
+				So(integrator.progress["local ahead"], ShouldEqual, 5)
+
+				So(actionOf(results, "remote ahead"), ShouldEqual, SyncPulled)
+				saved, err := Get()
+				So(err, ShouldBeNil)
+				So(saved["remote ahead (test)"].Index, ShouldEqual, 10)
+				So(saved["remote ahead (test)"].Name, ShouldEqual, "Chapter 10")
+				So(saved["remote ahead (test)"].URL, ShouldEqual, "https://example.com/10")
+				So(saved["remote ahead (test)"].MangaChaptersTotal, ShouldEqual, 10)
+
+				So(actionOf(results, "same"), ShouldEqual, SyncUpToDate)
+			})
+
+			Convey("And conflicts should be reported and left untouched", func() {
+				So(actionOf(results, "conflict"), ShouldEqual, SyncConflict)
+				So(integrator.progress["conflict"], ShouldEqual, 7)
+
+				saved, err := Get()
+				So(err, ShouldBeNil)
+				So(saved["conflict (test)"].Index, ShouldEqual, 6)
+			})
+		})
+
+		Convey("When syncing with local preference", func() {
+			results, err := Sync(integrator, PreferLocal, false, chaptersOf)
+			So(err, ShouldBeNil)
+
+			Convey("Then conflicts should be resolved by pushing", func() {
+				So(actionOf(results, "conflict"), ShouldEqual, SyncPushed)
+				So(integrator.progress["conflict"], ShouldEqual, 6)
+			})
+		})
+
+		Convey("When the pulled chapter is not in the source", func() {
+			integrator.progress["remote ahead"] = 20
+			results, err := Sync(integrator, PreferNone, false, chaptersOf)
+			So(err, ShouldBeNil)
+
+			Convey("Then the entry should fail and be left untouched", func() {
+				So(actionOf(results, "remote ahead"), ShouldEqual, SyncFailed)
+
+				saved, err := Get()
+				So(err, ShouldBeNil)
+				So(saved["remote ahead (test)"].Index, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the manga has the volume mapping", func() {
+			So(anilist.SetMapping("volumes", &anilist.Mapping{Volumes: map[string]int{"Vol. 1": 8, "Vol. 2": 16}}), ShouldBeNil)
+			defer func() { So(anilist.RemoveMapping("volumes"), ShouldBeNil) }()
+
+			chapter := &SavedChapter{MangaName: "volumes", SourceID: "test", Index: 1, Volume: "Vol. 1", Synced: map[string]int{"test": 8}}
+			saved[chapter.encode()] = chapter
+			So(cacher.Set(saved), ShouldBeNil)
+
+			volumesOf := func(saved *SavedChapter) ([]*source.Chapter, error) {
+				if saved.MangaName != "volumes" {
+					return chaptersOf(saved)
+				}
+
+				manga := &source.Manga{Name: saved.MangaName}
+				for i := 1; i <= 2; i++ {
+					manga.Chapters = append(manga.Chapters, &source.Chapter{
+						Name:   fmt.Sprintf("Volume %d", i),
+						Volume: fmt.Sprintf("Vol. %d", i),
+						Index:  uint16(i),
+						Manga:  manga,
+					})
+				}
+
+				return manga.Chapters, nil
+			}
+
+			Convey("And the remote progress is in the next volume", func() {
+				integrator.progress["volumes"] = 12
+				results, err := Sync(integrator, PreferNone, false, volumesOf)
+				So(err, ShouldBeNil)
+
+				Convey("Then the volume that contains it should be pulled", func() {
+					So(actionOf(results, "volumes"), ShouldEqual, SyncPulled)
+
+					saved, err := Get()
+					So(err, ShouldBeNil)
+					So(saved["volumes (test)"].Index, ShouldEqual, 2)
+					So(saved["volumes (test)"].Volume, ShouldEqual, "Vol. 2")
+				})
+			})
+
+			Convey("And the remote progress is reset", func() {
+				integrator.progress["volumes"] = 0
+				results, err := Sync(integrator, PreferNone, false, volumesOf)
+				So(err, ShouldBeNil)
+
+				Convey("Then it should not be pulled", func() {
+					So(actionOf(results, "volumes"), ShouldEqual, SyncIgnored)
+
+					saved, err := Get()
+					So(err, ShouldBeNil)
+					So(saved["volumes (test)"].Index, ShouldEqual, 1)
+				})
+			})
+		})
+
+		Convey("When syncing as dry run", func() {
+			_, err := Sync(integrator, PreferRemote, true, chaptersOf)
+			So(err, ShouldBeNil)
+
+			Convey("Then nothing should change", func() {
+				So(integrator.progress, ShouldNotContainKey, "local ahead")
+
+				saved, err := Get()
+				So(err, ShouldBeNil)
+				So(saved["remote ahead (test)"].Index, ShouldEqual, 2)
+			})
+		})
+	})
+}
@@ -0,0 +1,223 @@
+package inline
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/metafates/mangal/downloader"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/lo"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	// EventSearch is emitted for search results of each source
+	EventSearch EventType = "search"
+	// EventChapterStarted is emitted when chapter processing starts
+	EventChapterStarted EventType = "chapter_started"
+	// EventPages is emitted when the list of chapter pages is fetched
+	EventPages EventType = "pages"
+	// EventPage is emitted after each page is downloaded
+	EventPage EventType = "page"
+	// EventProgress is emitted for human-readable progress messages
+	EventProgress EventType = "progress"
+	// EventConverted is emitted when pages are converted to the output format
+	EventConverted EventType = "converted"
+	// EventSaved is emitted when chapter is saved
+	EventSaved EventType = "saved"
+	// EventError is emitted on error
+	EventError EventType = "error"
+)
+
+// Event is a single line of the events stream
+type Event struct {
+	Type EventType `json:"type" jsonschema:"description=Type of the event"`
+	Time time.Time `json:"time" jsonschema:"description=Time when the event was emitted"`
+	// Source name
+	Source string `json:"source,omitempty" jsonschema:"description=Source name"`
+	// Mangas found by the search
+	Mangas []string `json:"mangas,omitempty" jsonschema:"description=Names of the mangas found by the search"`
+	// Manga name
+	Manga string `json:"manga,omitempty" jsonschema:"description=Manga name"`
+	// Chapter name
+	Chapter string `json:"chapter,omitempty" jsonschema:"description=Chapter name"`
+	// ChapterIndex is the index of the chapter in the manga
+	ChapterIndex uint16 `json:"chapter_index,omitempty" jsonschema:"description=Index of the chapter in the manga"`
+	// Page is the index of the page in the chapter
+	Page *uint16 `json:"page,omitempty" jsonschema:"description=Index of the page in the chapter"`
+	// Pages is the amount of pages in the chapter
+	Pages int `json:"pages,omitempty" jsonschema:"description=Amount of pages in the chapter"`
+	// Downloaded is the amount of downloaded pages so far
+	Downloaded int `json:"downloaded,omitempty" jsonschema:"description=Amount of downloaded pages so far"`
+	// Format of the converted chapter
+	Format string `json:"format,omitempty" jsonschema:"description=Format of the converted chapter"`
+	// Path where the chapter was saved
+	Path string `json:"path,omitempty" jsonschema:"description=Path where the chapter was saved"`
+	// Message of the progress event
+	Message string `json:"message,omitempty" jsonschema:"description=Message of the progress event"`
+	// Error message
+	Error string `json:"error,omitempty" jsonschema:"description=Error message"`
+	// ErrorKind is the kind of the error, see Error.Kind
+	ErrorKind string `json:"error_kind,omitempty" jsonschema:"description=Kind of the error"`
+}
+
+// emitter writes events as newline-delimited JSON.
+// Nil emitter discards all events.
+type emitter struct {
+	mutex    sync.Mutex
+	encoder  *json.Encoder
+	reported error
+}
+
+func newEmitter(out io.Writer) *emitter {
+	return &emitter{encoder: json.NewEncoder(out)}
+}
+
+func (e *emitter) emit(event *Event) {
+	if e == nil {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	event.Time = time.Now()
+	if err := e.encoder.Encode(event); err != nil {
+		log.Warn(err)
+	}
+}
+
+// search emits search results of the source
+func (e *emitter) search(src source.Source, mangas []*source.Manga) {
+	e.emit(&Event{
+		Type:   EventSearch,
+		Source: src.Name(),
+		Mangas: lo.Map(mangas, func(manga *source.Manga, _ int) string {
+			return manga.Name
+		}),
+	})
+}
+
+// chapter creates event of the given type for the chapter
+func (e *emitter) chapter(t EventType, chapter *source.Chapter) *Event {
+	event := &Event{
+		Type:         t,
+		Manga:        chapter.Manga.Name,
+		Chapter:      chapter.Name,
+		ChapterIndex: chapter.Index,
+	}
+
+	if chapter.Source() != nil {
+		event.Source = chapter.Source().Name()
+	}
+
+	return event
+}
+
+// error emits the error. Each error is reported once
+func (e *emitter) error(err error, chapter *source.Chapter, page *source.Page) {
+	if e == nil || err == nil {
+		return
+	}
+
+	e.mutex.Lock()
+	if sameError(err, e.reported) {
+		e.mutex.Unlock()
+		return
+	}
+	e.reported = err
+	e.mutex.Unlock()
+
+	event := &Event{Type: EventError, Error: err.Error()}
+	if chapter != nil {
+		event = e.chapter(EventError, chapter)
+		event.Error = err.Error()
+	}
+
+	if page != nil {
+		event.Page = &page.Index
+	}
+
+	event.ErrorKind = errs.KindOf(err).String()
+
+	e.emit(event)
+}
+
+// sameError returns true if err is the reported error or wraps it without changing its kind,
+// so that e.g. partial download error that wraps the page error is still reported.
+// Errors that are not comparable, e.g. lists of errors, are the same if they have the same type and message.
+func sameError(err, reported error) bool {
+	if reported == nil {
+		return false
+	}
+
+	if errors.Is(err, reported) && errs.KindOf(err) == errs.KindOf(reported) {
+		return true
+	}
+
+	t := reflect.TypeOf(reported)
+	return !t.Comparable() && reflect.TypeOf(err) == t && err.Error() == reported.Error()
+}
+
+// progress returns progress function that emits messages for the chapter
+func (e *emitter) progress(chapter *source.Chapter) func(string) {
+	if e == nil {
+		return func(string) {}
+	}
+
+	return func(message string) {
+		event := e.chapter(EventProgress, chapter)
+		event.Message = message
+		e.emit(event)
+	}
+}
+
+// hooks returns download hooks that emit events for the chapter
+func (e *emitter) hooks(chapter *source.Chapter) *downloader.Hooks {
+	if e == nil {
+		return &downloader.Hooks{}
+	}
+
+	var (
+		total      int
+		downloaded int
+		mutex      sync.Mutex
+	)
+
+	return &downloader.Hooks{
+		OnPages: func(pages []*source.Page) {
+			total = len(pages)
+			event := e.chapter(EventPages, chapter)
+			event.Pages = total
+			e.emit(event)
+		},
+		OnPage: func(page *source.Page, err error) {
+			if err != nil {
+				e.error(err, chapter, page)
+				return
+			}
+
+			mutex.Lock()
+			downloaded++
+			event := e.chapter(EventPage, chapter)
+			event.Page = &page.Index
+			event.Pages = total
+			event.Downloaded = downloaded
+			mutex.Unlock()
+
+			e.emit(event)
+		},
+		OnConverted: func(format, path string) {
+			event := e.chapter(EventConverted, chapter)
+			event.Format = format
+			event.Path = path
+			e.emit(event)
+		},
+	}
+}
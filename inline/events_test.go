@@ -0,0 +1,56 @@
+package inline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/errs"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+// errorList is an error that can not be compared with ==
+type errorList []error
+
+func (e errorList) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func TestEmitter_Error(t *testing.T) {
+	Convey("Given an emitter", t, func() {
+		var out bytes.Buffer
+		e := newEmitter(&out)
+
+		Convey("When the uncomparable errors are reported", func() {
+			first := errorList{errors.New("first")}
+			So(func() {
+				e.error(first, nil, nil)
+				e.error(first, nil, nil)
+				e.error(errorList{errors.New("second")}, nil, nil)
+			}, ShouldNotPanic)
+
+			Convey("Then each error should be emitted once", func() {
+				So(strings.Count(out.String(), `"first"`), ShouldEqual, 1)
+				So(strings.Count(out.String(), `"second"`), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the error is wrapped", func() {
+			page := errs.New(errs.Network, errors.New("page failed"))
+			e.error(page, nil, nil)
+			e.error(fmt.Errorf("retry: %w", page), nil, nil)
+			e.error(errs.New(errs.PartialDownload, page), nil, nil)
+
+			Convey("Then it should be emitted again only with another kind", func() {
+				So(strings.Count(out.String(), `"network"`), ShouldEqual, 1)
+				So(strings.Count(out.String(), `"partial_download"`), ShouldEqual, 1)
+			})
+		})
+	})
+}
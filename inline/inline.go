@@ -1,10 +1,16 @@
 package inline
 
 import (
+	"github.com/metafates/mangal/anilist"
 	"github.com/metafates/mangal/downloader"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/provider"
 	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
 	"github.com/spf13/viper"
 	"os"
 )
@@ -14,34 +20,76 @@ func Run(options *Options) (err error) {
 		options.Out = os.Stdout
 	}
 
+	var events *emitter
+	if options.Events {
+		events = newEmitter(options.Out)
+		defer func() {
+			events.error(err, nil, nil)
+		}()
+	}
+
+	// wroteJson is set once the JSON output is written, so that errors are not written twice
+	var wroteJson bool
+	writeJson := func(mangas []*source.Manga, candidates []*Candidate, failure error) error {
+		wroteJson = true
+		marshalled, err := asJson(mangas, candidates, failure, options)
+		if err != nil {
+			return err
+		}
+
+		if _, err = options.Out.Write(marshalled); err != nil {
+			return err
+		}
+
+		return failure
+	}
+
+	if options.Json {
+		defer func() {
+			if err != nil && !wroteJson {
+				_ = writeJson([]*source.Manga{}, nil, err)
+			}
+		}()
+	}
+
 	var mangas []*source.Manga
-	for _, src := range options.Sources {
-		m, err := src.Search(options.Query)
+	if options.URL != "" {
+		manga, err := resolve(options)
 		if err != nil {
 			return err
 		}
 
-		mangas = append(mangas, m...)
+		events.search(manga.Source, []*source.Manga{manga})
+		mangas = append(mangas, manga)
+	} else {
+		for _, src := range options.Sources {
+			m, err := src.Search(options.Query)
+			if err != nil {
+				return err
+			}
+
+			events.search(src, m)
+			mangas = append(mangas, m...)
+		}
 	}
 
 	if options.MangaPicker.IsAbsent() && options.ChaptersFilter.IsAbsent() {
 		if viper.GetBool(key.MetadataFetchAnilist) {
+			preloadAnilist(mangas)
 			for _, manga := range mangas {
 				_ = manga.PopulateMetadata(func(string) {})
 			}
 		}
 
-		marshalled, err := asJson(mangas, options)
-		if err != nil {
-			return err
-		}
-
-		_, err = options.Out.Write(marshalled)
-		return err
+		return writeJson(mangas, nil, nil)
 	}
 
 	// manga picker can only be none if json is set
 	if options.MangaPicker.IsAbsent() {
+		if options.IncludeAnilistManga || viper.GetBool(key.MetadataFetchAnilist) {
+			preloadAnilist(mangas)
+		}
+
 		// preload all chapters
 		for _, manga := range mangas {
 			if err = prepareManga(manga, options); err != nil {
@@ -49,45 +97,29 @@ func Run(options *Options) (err error) {
 			}
 		}
 
-		marshalled, err := asJson(mangas, options)
-		if err != nil {
-			return err
-		}
-
-		_, err = options.Out.Write(marshalled)
-		return err
+		return writeJson(mangas, nil, nil)
 	}
 
 	var chapters []*source.Chapter
 
 	if len(mangas) == 0 {
+		err = errs.Newf(errs.NoResults, "no manga found for %q", options.Query)
 		if options.Json {
-			marshalled, err := asJson([]*source.Manga{}, options)
-			if err != nil {
-				return err
-			}
-
-			_, err = options.Out.Write(marshalled)
-			return err
+			return writeJson([]*source.Manga{}, nil, err)
 		}
 
-		return nil
+		return err
 	}
 
-	manga := options.MangaPicker.MustGet()(mangas)
+	manga, candidates := options.MangaPicker.MustGet()(mangas)
 
 	if manga == nil {
+		err = errs.Newf(errs.NoResults, "none of %s matched the manga selector", util.Quantify(len(mangas), "manga", "mangas"))
 		if options.Json {
-			marshalled, err := asJson([]*source.Manga{}, options)
-			if err != nil {
-				return err
-			}
-
-			_, err = options.Out.Write(marshalled)
-			return err
+			return writeJson([]*source.Manga{}, candidates, err)
 		}
 
-		return nil
+		return err
 	}
 
 	chapters, err = manga.Source.ChaptersOf(manga)
@@ -102,28 +134,38 @@ func Run(options *Options) (err error) {
 		}
 	}
 
+	if options.DryRun {
+		return newPlan(manga, chapters).write(options.Out, options.Json)
+	}
+
 	if options.Json {
 		if err = prepareManga(manga, options); err != nil {
 			return err
 		}
 
-		marshalled, err := asJson([]*source.Manga{manga}, options)
-		if err != nil {
-			return err
-		}
-
-		_, err = options.Out.Write(marshalled)
-		return err
+		return writeJson([]*source.Manga{manga}, candidates, nil)
 	}
 
+	var failed []error
 	for _, chapter := range chapters {
+		events.emit(events.chapter(EventChapterStarted, chapter))
+
 		if options.Download {
-			path, err := downloader.Download(chapter, func(string) {})
+			path, err := downloader.DownloadWith(chapter, events.progress(chapter), events.hooks(chapter))
 			if err != nil {
+				events.error(err, chapter, nil)
 				if viper.GetBool(key.DownloaderStopOnError) {
 					return err
 				}
 
+				failed = append(failed, err)
+				continue
+			}
+
+			if options.Events {
+				event := events.chapter(EventSaved, chapter)
+				event.Path = path
+				events.emit(event)
 				continue
 			}
 
@@ -132,12 +174,60 @@ func Run(options *Options) (err error) {
 				log.Warn(err)
 			}
 		} else {
-			err := downloader.Read(chapter, func(string) {})
+			err := downloader.Read(chapter, events.progress(chapter))
 			if err != nil {
+				events.error(err, chapter, nil)
 				return err
 			}
 		}
 	}
 
-	return nil
+	switch {
+	case len(failed) == 0:
+		return nil
+	case len(failed) == len(chapters):
+		return failed[0]
+	default:
+		return errs.Newf(
+			errs.PartialDownload,
+			"%s of %d failed to download, first error: %w",
+			util.Quantify(len(failed), "chapter", "chapters"),
+			len(chapters),
+			failed[0],
+		)
+	}
+}
+
+// resolve gets the manga from the URL. The manga is picked automatically,
+// and if the URL points to a chapter, it is selected unless chapters filter is given.
+func resolve(options *Options) (*source.Manga, error) {
+	manga, chapter, err := provider.Resolve(options.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.MangaPicker.IsAbsent() {
+		options.MangaPicker = mo.Some[MangaPicker](lo.Must(ParseMangaPicker(options.Query, "first")))
+	}
+
+	if chapter != nil && options.ChaptersFilter.IsAbsent() {
+		options.ChaptersFilter = mo.Some[ChaptersFilter](func(chapters []*source.Chapter) ([]*source.Chapter, error) {
+			return lo.Filter(chapters, func(c *source.Chapter, _ int) bool {
+				return source.SameURL(c.URL, chapter.URL)
+			}), nil
+		})
+	}
+
+	return manga, nil
+}
+
+// preloadAnilist searches Anilist for all mangas at once using batched queries
+func preloadAnilist(mangas []*source.Manga) {
+	names := lo.Map(mangas, func(manga *source.Manga, _ int) string {
+		return manga.Name
+	})
+
+	if err := anilist.Preload(names); err != nil {
+		log.Warn(err)
+	}
 }
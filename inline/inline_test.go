@@ -0,0 +1,50 @@
+package inline
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/lo"
+	"github.com/samber/mo"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type emptySource struct {
+	testSource
+}
+
+func (emptySource) Search(string) ([]*source.Manga, error) {
+	return nil, nil
+}
+
+func TestRunErrors(t *testing.T) {
+	Convey("Given a source that finds nothing", t, func() {
+		var out bytes.Buffer
+		options := &Options{
+			Out:         &out,
+			Sources:     []source.Source{emptySource{}},
+			Query:       "one piece",
+			Json:        true,
+			MangaPicker: mo.Some(lo.Must(ParseMangaPicker("one piece", "first"))),
+		}
+
+		Convey("When running in the json mode", func() {
+			err := Run(options)
+
+			Convey("Then no results error should be returned", func() {
+				So(errs.KindOf(err), ShouldEqual, errs.NoResults)
+			})
+
+			Convey("Then the error object should be written", func() {
+				var output Output
+				So(json.Unmarshal(out.Bytes(), &output), ShouldBeNil)
+				So(output.Result, ShouldBeEmpty)
+				So(output.Error, ShouldNotBeNil)
+				So(output.Error.Kind, ShouldEqual, "no_results")
+				So(output.Error.ExitCode, ShouldEqual, errs.NoResults.ExitCode())
+			})
+		})
+	})
+}
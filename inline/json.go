@@ -3,6 +3,7 @@ package inline
 import (
 	"encoding/json"
 	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/source"
 	"github.com/spf13/viper"
@@ -20,9 +21,36 @@ type Manga struct {
 type Output struct {
 	Query  string   `json:"query" jsonschema:"description=Query that was used to search for the manga."`
 	Result []*Manga `json:"result" jsonschema:"description=Result of the search."`
+	// Error is set when the run failed
+	Error *Error `json:"error,omitempty" jsonschema:"description=Error is set when the run failed."`
+	// Candidates are the search results scored by the manga picker
+	Candidates []*Candidate `json:"candidates,omitempty" jsonschema:"description=Search results scored by the manga picker."`
 }
 
-func asJson(manga []*source.Manga, options *Options) (marshalled []byte, err error) {
+// Error is a machine-readable error of the run
+type Error struct {
+	// Kind of the error
+	Kind string `json:"kind" jsonschema:"enum=unknown,enum=source_not_found,enum=no_results,enum=network,enum=rate_limited,enum=lua,enum=conversion,enum=partial_download"`
+	// Message of the error
+	Message string `json:"message" jsonschema:"description=Message of the error"`
+	// ExitCode the program exits with
+	ExitCode int `json:"exit_code" jsonschema:"description=Exit code the program exits with"`
+}
+
+func newError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	kind := errs.KindOf(err)
+	return &Error{
+		Kind:     kind.String(),
+		Message:  err.Error(),
+		ExitCode: kind.ExitCode(),
+	}
+}
+
+func asJson(manga []*source.Manga, candidates []*Candidate, failure error, options *Options) (marshalled []byte, err error) {
 	var m = make([]*Manga, len(manga))
 	for i, manga := range manga {
 		al := manga.Anilist.OrElse(nil)
@@ -38,8 +66,10 @@ func asJson(manga []*source.Manga, options *Options) (marshalled []byte, err err
 	}
 
 	return json.Marshal(&Output{
-		Result: m,
-		Query:  options.Query,
+		Result:     m,
+		Query:      options.Query,
+		Candidates: candidates,
+		Error:      newError(failure),
 	})
 }
 
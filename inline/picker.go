@@ -0,0 +1,198 @@
+package inline
+
+import (
+	"fmt"
+	levenshtein "github.com/ka-weihe/fast-levenshtein"
+	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Candidate is a manga considered by the manga picker
+type Candidate struct {
+	// Source name
+	Source string `json:"source" jsonschema:"description=Source name"`
+	// Name of the manga
+	Name string `json:"name" jsonschema:"description=Name of the manga"`
+	// URL of the manga
+	URL string `json:"url" jsonschema:"description=URL of the manga"`
+	// Score is the similarity to the query from 0 to 1.
+	// For the best picker Anilist titles and synonyms of the query are also considered
+	Score float64 `json:"score" jsonschema:"description=Similarity to the query from 0 to 1"`
+	// Selected is true for the picked manga
+	Selected bool `json:"selected" jsonschema:"description=True for the picked manga"`
+}
+
+// normalizeTitle lowercases the title and strips punctuation for comparison
+func normalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+// similarity returns how similar two titles are, from 0 to 1
+func similarity(a, b string) float64 {
+	a, b = normalizeTitle(a), normalizeTitle(b)
+	if a == b {
+		return 1
+	}
+
+	longest := util.Max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 0
+	}
+
+	return 1 - float64(levenshtein.Distance(a, b))/float64(longest)
+}
+
+// score calculates candidates for the mangas comparing their names to the titles
+func score(mangas []*source.Manga, titles []string) []*Candidate {
+	return lo.Map(mangas, func(manga *source.Manga, _ int) *Candidate {
+		candidate := &Candidate{
+			Name: manga.Name,
+			URL:  manga.URL,
+		}
+
+		if manga.Source != nil {
+			candidate.Source = manga.Source.Name()
+		}
+
+		for _, title := range titles {
+			candidate.Score = util.Max(candidate.Score, similarity(manga.Name, title))
+		}
+
+		return candidate
+	})
+}
+
+// anilistTitles returns the query and titles with synonyms of the closest Anilist manga
+func anilistTitles(query string) []string {
+	titles := []string{query}
+
+	manga, err := anilist.FindClosest(query)
+	if err != nil {
+		log.Warn(err)
+		return titles
+	}
+
+	titles = append(titles, manga.Title.English, manga.Title.Romaji, manga.Title.Native)
+	titles = append(titles, manga.Synonyms...)
+
+	return lo.Filter(titles, func(title string, _ int) bool {
+		return title != ""
+	})
+}
+
+var indexPickerRegex = regexp.MustCompile(`^\d+$`)
+
+// ParseMangaPicker parses the manga picker.
+//
+// Supported pickers:
+//
+//	first, last     - first or last manga in the list
+//	exact           - manga with the name equal to the query
+//	N               - manga by index, starting from 0
+//	best            - manga with the highest fuzzy score against the query and Anilist titles and synonyms
+//	anilist:<id>    - manga bound to the Anilist id
+//	url:<url>       - manga with the url
+func ParseMangaPicker(query, description string) (MangaPicker, error) {
+	const (
+		first   = "first"
+		last    = "last"
+		exact   = "exact"
+		best    = "best"
+		byURL   = "url:"
+		byAlist = "anilist:"
+	)
+
+	pattern := fmt.Sprintf(`^(%s|%s|%s|%s|\d+|%s\d+|%s.+)$`, first, last, exact, best, byAlist, byURL)
+	mangaPickerRegex := regexp.MustCompile(pattern)
+
+	if !mangaPickerRegex.MatchString(description) {
+		return nil, fmt.Errorf("invalid manga picker pattern: %s", description)
+	}
+
+	// numbers are parsed here, so that the overflows are reported as invalid patterns
+	var (
+		id    int
+		index uint64
+		err   error
+	)
+
+	switch {
+	case strings.HasPrefix(description, byAlist):
+		if id, err = strconv.Atoi(strings.TrimPrefix(description, byAlist)); err != nil {
+			return nil, fmt.Errorf("invalid manga picker pattern: %s: anilist id is out of range", description)
+		}
+	case indexPickerRegex.MatchString(description):
+		if index, err = strconv.ParseUint(description, 10, 16); err != nil {
+			return nil, fmt.Errorf("invalid manga picker pattern: %s: index is out of range", description)
+		}
+	}
+
+	// pick returns the manga at index and the candidates with the selection marked
+	pick := func(mangas []*source.Manga, candidates []*Candidate, index int) (*source.Manga, []*Candidate) {
+		if index < 0 || index >= len(mangas) {
+			return nil, candidates
+		}
+
+		candidates[index].Selected = true
+		return mangas[index], candidates
+	}
+
+	return func(mangas []*source.Manga) (*source.Manga, []*Candidate) {
+		if len(mangas) == 0 {
+			return nil, nil
+		}
+
+		switch {
+		case description == first:
+			return pick(mangas, score(mangas, []string{query}), 0)
+		case description == last:
+			return pick(mangas, score(mangas, []string{query}), len(mangas)-1)
+		case description == exact:
+			return pick(mangas, score(mangas, []string{query}), slices.IndexFunc(mangas, func(manga *source.Manga) bool {
+				return manga.Name == query
+			}))
+		case description == best:
+			candidates := score(mangas, anilistTitles(query))
+
+			// the first one wins on ties, so the source order is respected
+			var index int
+			for i, candidate := range candidates {
+				if candidate.Score > candidates[index].Score {
+					index = i
+				}
+			}
+
+			return pick(mangas, candidates, index)
+		case strings.HasPrefix(description, byURL):
+			url := strings.TrimPrefix(description, byURL)
+			return pick(mangas, score(mangas, []string{query}), slices.IndexFunc(mangas, func(manga *source.Manga) bool {
+				return source.SameURL(manga.URL, url)
+			}))
+		case strings.HasPrefix(description, byAlist):
+			if err := anilist.Preload(lo.Map(mangas, func(manga *source.Manga, _ int) string {
+				return manga.Name
+			})); err != nil {
+				log.Warn(err)
+			}
+
+			return pick(mangas, score(mangas, []string{query}), slices.IndexFunc(mangas, func(manga *source.Manga) bool {
+				closest, err := anilist.FindClosest(manga.Name)
+				return err == nil && closest.ID == id
+			}))
+		default:
+			return pick(mangas, score(mangas, []string{query}), int(util.Min(index, uint64(len(mangas)-1))))
+		}
+	}, nil
+}
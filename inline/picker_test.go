@@ -0,0 +1,83 @@
+package inline
+
+import (
+	"github.com/metafates/mangal/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func testMangas() []*source.Manga {
+	return []*source.Manga{
+		{Name: "One Piece: Party", URL: "https://example.com/manga/party", Source: testSource{}},
+		{Name: "One Piece", URL: "https://example.com/manga/one-piece", Source: testSource{}},
+		{Name: "One Punch-Man", URL: "https://example.com/manga/one-punch-man", Source: testSource{}},
+	}
+}
+
+func TestParseMangaPicker(t *testing.T) {
+	Convey("When parsing manga pickers", t, func() {
+		Convey("Invalid pickers should return an error", func() {
+			for _, description := range []string{"", "worst", "anilist:abc", "url:", "anilist:99999999999999999999", "99999999"} {
+				_, err := ParseMangaPicker("one piece", description)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("Index picker should select manga by index", func() {
+			picker, err := ParseMangaPicker("one piece", "1")
+			So(err, ShouldBeNil)
+
+			manga, candidates := picker(testMangas())
+			So(manga.Name, ShouldEqual, "One Piece")
+			So(candidates, ShouldHaveLength, 3)
+			So(candidates[1].Selected, ShouldBeTrue)
+			So(candidates[0].Selected, ShouldBeFalse)
+		})
+
+		Convey("Exact picker should be case-sensitive", func() {
+			picker, err := ParseMangaPicker("one piece", "exact")
+			So(err, ShouldBeNil)
+
+			manga, candidates := picker(testMangas())
+			So(manga, ShouldBeNil)
+			So(candidates, ShouldHaveLength, 3)
+		})
+
+		Convey("Url picker should ignore scheme and trailing slash", func() {
+			picker, err := ParseMangaPicker("one piece", "url:http://example.com/manga/one-punch-man/")
+			So(err, ShouldBeNil)
+
+			manga, candidates := picker(testMangas())
+			So(manga.Name, ShouldEqual, "One Punch-Man")
+			So(candidates[2].Selected, ShouldBeTrue)
+		})
+
+		Convey("Candidates should be scored against the query", func() {
+			picker, err := ParseMangaPicker("one piece", "first")
+			So(err, ShouldBeNil)
+
+			_, candidates := picker(testMangas())
+			So(candidates[1].Score, ShouldEqual, 1)
+			So(candidates[1].Score, ShouldBeGreaterThan, candidates[0].Score)
+			So(candidates[0].Score, ShouldBeGreaterThan, candidates[2].Score)
+			So(candidates[0].Source, ShouldEqual, "test source")
+		})
+
+		Convey("Empty results should pick nothing", func() {
+			picker, err := ParseMangaPicker("one piece", "last")
+			So(err, ShouldBeNil)
+
+			manga, candidates := picker(nil)
+			So(manga, ShouldBeNil)
+			So(candidates, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestSimilarity(t *testing.T) {
+	Convey("Similarity should ignore case and punctuation", t, func() {
+		So(similarity("One-Punch Man!", "one punch man"), ShouldEqual, 1)
+		So(similarity("", ""), ShouldEqual, 1)
+		So(similarity("abc", "xyz"), ShouldEqual, 0)
+	})
+}
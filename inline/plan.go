@@ -0,0 +1,118 @@
+package inline
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type PlanAction string
+
+const (
+	// PlanDownload means that chapter is not downloaded yet
+	PlanDownload PlanAction = "download"
+	// PlanSkip means that chapter is already downloaded and will be skipped
+	PlanSkip PlanAction = "skip"
+	// PlanOverwrite means that chapter is already downloaded and will be downloaded again
+	PlanOverwrite PlanAction = "overwrite"
+)
+
+// PlannedChapter is a chapter with the action that would be taken for it
+type PlannedChapter struct {
+	// Name of the chapter
+	Name string `json:"name" jsonschema:"description=Name of the chapter"`
+	// Index of the chapter in the manga
+	Index uint16 `json:"index" jsonschema:"description=Index of the chapter in the manga"`
+	// Volume of the chapter
+	Volume string `json:"volume" jsonschema:"description=Volume of the chapter"`
+	// URL of the chapter
+	URL string `json:"url" jsonschema:"description=URL of the chapter"`
+	// Path where the chapter would be saved
+	Path string `json:"path" jsonschema:"description=Path where the chapter would be saved"`
+	// Action that would be taken
+	Action PlanAction `json:"action" jsonschema:"enum=download,enum=skip,enum=overwrite"`
+}
+
+// Plan is what a download would do, computed without fetching any page
+type Plan struct {
+	// Source name
+	Source string `json:"source" jsonschema:"description=Source name"`
+	// Manga name
+	Manga string `json:"manga" jsonschema:"description=Manga name"`
+	// Format chapters would be saved in
+	Format string `json:"format" jsonschema:"description=Format chapters would be saved in"`
+	// Chapters with their actions
+	Chapters []*PlannedChapter `json:"chapters" jsonschema:"description=Chapters with their actions"`
+}
+
+// newPlan checks each chapter whether it is downloaded and where it would be saved
+func newPlan(manga *source.Manga, chapters []*source.Chapter) *Plan {
+	plan := &Plan{
+		Source: manga.Source.Name(),
+		Manga:  manga.Name,
+		Format: viper.GetString(key.FormatsUse),
+		Chapters: lo.Map(chapters, func(chapter *source.Chapter, _ int) *PlannedChapter {
+			return &PlannedChapter{
+				Name:   chapter.Name,
+				Index:  chapter.Index,
+				Volume: chapter.Volume,
+				URL:    chapter.URL,
+				Path:   chapter.PeekPath(),
+				Action: planAction(chapter),
+			}
+		}),
+	}
+
+	return plan
+}
+
+func planAction(chapter *source.Chapter) PlanAction {
+	switch {
+	case !chapter.IsDownloaded():
+		return PlanDownload
+	case viper.GetBool(key.DownloaderRedownloadExisting):
+		return PlanOverwrite
+	default:
+		return PlanSkip
+	}
+}
+
+// count returns how many chapters have the action
+func (p *Plan) count(action PlanAction) int {
+	return lo.CountBy(p.Chapters, func(chapter *PlannedChapter) bool {
+		return chapter.Action == action
+	})
+}
+
+// write writes the plan as JSON or as a table with a summary
+func (p *Plan) write(out io.Writer, asJson bool) error {
+	if asJson {
+		return json.NewEncoder(out).Encode(p)
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(writer, "ACTION\tCHAPTER\tPATH\n")
+	for _, chapter := range p.Chapters {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", strings.ToUpper(string(chapter.Action)), chapter.Name, chapter.Path)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(
+		out,
+		"\n%s: %d to download, %d to skip, %d to overwrite\n",
+		p.Manga,
+		p.count(PlanDownload),
+		p.count(PlanSkip),
+		p.count(PlanOverwrite),
+	)
+	return err
+}
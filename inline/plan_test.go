@@ -0,0 +1,67 @@
+package inline
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	Convey("Given chapters where one is already downloaded", t, func() {
+		viper.Set(key.FormatsUse, constant.FormatCBZ)
+		viper.Set(key.DownloaderChapterNameTemplate, "{chapter}")
+		chapters := testChapters()[:3]
+		downloaded := chapters[1].PeekPath()
+		So(filesystem.Api().MkdirAll(filepath.Dir(downloaded), os.ModePerm), ShouldBeNil)
+		So(filesystem.Api().WriteFile(downloaded, []byte{}, os.ModePerm), ShouldBeNil)
+
+		Reset(func() {
+			_ = filesystem.Api().Remove(downloaded)
+			viper.Set(key.DownloaderRedownloadExisting, false)
+		})
+
+		Convey("When planning without redownloading", func() {
+			plan := newPlan(chapters[0].Manga, chapters)
+
+			Convey("Then downloaded chapter should be skipped", func() {
+				So(plan.Chapters[0].Action, ShouldEqual, PlanDownload)
+				So(plan.Chapters[1].Action, ShouldEqual, PlanSkip)
+				So(plan.Chapters[1].Path, ShouldEqual, downloaded)
+				So(plan.Chapters[2].Action, ShouldEqual, PlanDownload)
+			})
+
+			Convey("Then table should include the summary", func() {
+				var out bytes.Buffer
+				So(plan.write(&out, false), ShouldBeNil)
+				So(out.String(), ShouldContainSubstring, "SKIP")
+				So(out.String(), ShouldContainSubstring, "2 to download, 1 to skip, 0 to overwrite")
+			})
+
+			Convey("Then JSON should be decodable", func() {
+				var out bytes.Buffer
+				So(plan.write(&out, true), ShouldBeNil)
+
+				var decoded Plan
+				So(json.Unmarshal(out.Bytes(), &decoded), ShouldBeNil)
+				So(decoded.Chapters, ShouldHaveLength, 3)
+				So(decoded.Format, ShouldEqual, constant.FormatCBZ)
+			})
+		})
+
+		Convey("When planning with redownloading", func() {
+			viper.Set(key.DownloaderRedownloadExisting, true)
+			plan := newPlan(chapters[0].Manga, chapters)
+
+			Convey("Then downloaded chapter should be overwritten", func() {
+				So(plan.Chapters[1].Action, ShouldEqual, PlanOverwrite)
+			})
+		})
+	})
+}
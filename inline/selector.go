@@ -0,0 +1,321 @@
+package inline
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/history"
+	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// selectorTerm is a single comma-separated part of the chapter selector
+type selectorTerm struct {
+	raw     string
+	negated bool
+	match   func(chapters []*source.Chapter) ([]bool, error)
+}
+
+// Selector is a parsed chapter selector.
+// It is a comma-separated union of terms, terms prefixed with "!" exclude chapters.
+type Selector struct {
+	terms []*selectorTerm
+}
+
+var (
+	indexRangeRegex  = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+	numberRangeRegex = regexp.MustCompile(`^#(\d+(?:\.\d+)?)(?:-#?(\d+(?:\.\d+)?)?)?$`)
+	openRangeRegex   = regexp.MustCompile(`^#(\d+(?:\.\d+)?)-$`)
+	volumeRegex      = regexp.MustCompile(`^(?i)v(\d+(?:\.\d+)?)(?:-v?(\d+(?:\.\d+)?))?$`)
+	latestRegex      = regexp.MustCompile(`^latest:(\d+)$`)
+	substringRegex   = regexp.MustCompile(`^@(.+)@$`)
+	patternRegex     = regexp.MustCompile(`^/(.+)/(i?)$`)
+)
+
+// splitSelector splits the selector by commas that are not inside a regex
+func splitSelector(description string) []string {
+	var (
+		parts   []string
+		current strings.Builder
+		inRegex bool
+	)
+
+	for i, r := range description {
+		switch {
+		case r == '/' && (i == 0 || description[i-1] != '\\'):
+			trimmed := strings.TrimSpace(current.String())
+			if !inRegex && (trimmed == "" || trimmed == "!") {
+				inRegex = true
+			} else if inRegex {
+				inRegex = false
+			}
+		case r == ',' && !inRegex:
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+
+		current.WriteRune(r)
+	}
+
+	return append(parts, strings.TrimSpace(current.String()))
+}
+
+// ParseSelector parses the chapter selector.
+//
+// Supported terms:
+//
+//	first, last, all
+//	N, N-M      - by position in the list, starting from 0
+//	#N, #N-#M   - by chapter number parsed from the name, e.g. #10-#20.5. #N- selects N and above
+//	vN, vN-vM   - by volume number
+//	@text@      - by name substring
+//	/regex/     - by name regex, /regex/i for case-insensitive
+//	new         - chapters after the last one in the history
+//	undownloaded - chapters that are not downloaded yet
+//	latest:N    - last N chapters
+//
+// Terms are separated by commas and united. Terms prefixed with "!" exclude matching chapters.
+func ParseSelector(description string) (*Selector, error) {
+	selector := &Selector{}
+
+	for _, part := range splitSelector(description) {
+		if part == "" {
+			return nil, fmt.Errorf("invalid chapter selector %q: empty term", description)
+		}
+
+		term, err := parseTerm(part)
+		if err != nil {
+			return nil, err
+		}
+
+		selector.terms = append(selector.terms, term)
+	}
+
+	return selector, nil
+}
+
+// byChapter creates matcher that checks each chapter independently
+func byChapter(f func(chapter *source.Chapter) bool) func([]*source.Chapter) ([]bool, error) {
+	return func(chapters []*source.Chapter) ([]bool, error) {
+		return lo.Map(chapters, func(chapter *source.Chapter, _ int) bool {
+			return f(chapter)
+		}), nil
+	}
+}
+
+// byPosition creates matcher that selects chapters within [from, to] positions.
+// Positions outside the chapters list match nothing
+func byPosition(from, to func(length int) int) func([]*source.Chapter) ([]bool, error) {
+	return func(chapters []*source.Chapter) ([]bool, error) {
+		matched := make([]bool, len(chapters))
+
+		start, end := from(len(chapters)), to(len(chapters))
+		if start > end {
+			start, end = end, start
+		}
+
+		for i := util.Max(start, 0); i <= util.Min(end, len(chapters)-1); i++ {
+			matched[i] = true
+		}
+
+		return matched, nil
+	}
+}
+
+func parseFloat(s string) float64 {
+	return lo.Must(strconv.ParseFloat(s, 64))
+}
+
+func parseTerm(raw string) (*selectorTerm, error) {
+	term := &selectorTerm{raw: raw}
+
+	description := raw
+	if strings.HasPrefix(description, "!") {
+		term.negated = true
+		description = strings.TrimSpace(description[1:])
+	}
+
+	switch {
+	case description == "all":
+		term.match = byChapter(func(*source.Chapter) bool { return true })
+	case description == "first":
+		term.match = byPosition(func(int) int { return 0 }, func(int) int { return 0 })
+	case description == "last":
+		last := func(length int) int { return length - 1 }
+		term.match = byPosition(last, last)
+	case description == "new":
+		term.match = matchNew
+	case description == "undownloaded":
+		term.match = byChapter(func(chapter *source.Chapter) bool {
+			return !chapter.IsDownloaded()
+		})
+	case latestRegex.MatchString(description):
+		n := int(parseFloat(latestRegex.FindStringSubmatch(description)[1]))
+		if n == 0 {
+			return nil, fmt.Errorf("invalid chapter selector term %q: count must be positive", raw)
+		}
+
+		term.match = byPosition(func(length int) int { return length - n }, func(length int) int { return length - 1 })
+	case indexRangeRegex.MatchString(description):
+		groups := indexRangeRegex.FindStringSubmatch(description)
+		from := int(parseFloat(groups[1]))
+		to := from
+		if groups[2] != "" {
+			to = int(parseFloat(groups[2]))
+		}
+
+		term.match = byPosition(func(int) int { return from }, func(int) int { return to })
+	case openRangeRegex.MatchString(description):
+		from := parseFloat(openRangeRegex.FindStringSubmatch(description)[1])
+		term.match = byChapter(func(chapter *source.Chapter) bool {
+			return chapter.ParsedNumber() >= from
+		})
+	case numberRangeRegex.MatchString(description):
+		groups := numberRangeRegex.FindStringSubmatch(description)
+		from := parseFloat(groups[1])
+		to := from
+		if groups[2] != "" {
+			to = parseFloat(groups[2])
+		}
+
+		if from > to {
+			from, to = to, from
+		}
+
+		term.match = byChapter(func(chapter *source.Chapter) bool {
+			number := chapter.ParsedNumber()
+			return from <= number && number <= to
+		})
+	case volumeRegex.MatchString(description):
+		groups := volumeRegex.FindStringSubmatch(description)
+		from := parseFloat(groups[1])
+		to := from
+		if groups[2] != "" {
+			to = parseFloat(groups[2])
+		}
+
+		term.match = byChapter(func(chapter *source.Chapter) bool {
+			volume, ok := chapter.VolumeNumber().Get()
+			return ok && from <= volume && volume <= to
+		})
+	case substringRegex.MatchString(description):
+		sub := substringRegex.FindStringSubmatch(description)[1]
+		term.match = byChapter(func(chapter *source.Chapter) bool {
+			return strings.Contains(chapter.Name, sub)
+		})
+	case patternRegex.MatchString(description):
+		groups := patternRegex.FindStringSubmatch(description)
+		pattern := groups[1]
+		if groups[2] == "i" {
+			pattern = "(?i)" + pattern
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chapter selector term %q: %s", raw, err)
+		}
+
+		term.match = byChapter(func(chapter *source.Chapter) bool {
+			return re.MatchString(chapter.Name)
+		})
+	default:
+		return nil, fmt.Errorf("invalid chapter selector term %q", raw)
+	}
+
+	return term, nil
+}
+
+// matchNew matches chapters after the last one saved in the history
+func matchNew(chapters []*source.Chapter) ([]bool, error) {
+	matched := make([]bool, len(chapters))
+	if len(chapters) == 0 {
+		return matched, nil
+	}
+
+	last, err := history.Last(chapters[0].Manga)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, ok := last.Get()
+	for i, chapter := range chapters {
+		matched[i] = !ok || int(chapter.Index) > saved.Index
+	}
+
+	return matched, nil
+}
+
+// Select returns chapters matched by the selector, in the original order
+func (s *Selector) Select(chapters []*source.Chapter) ([]*source.Chapter, error) {
+	selected, _, err := s.evaluate(chapters)
+	return selected, err
+}
+
+// Explain returns selected chapters and the description of what each term matched
+func (s *Selector) Explain(chapters []*source.Chapter) ([]*source.Chapter, string, error) {
+	selected, matches, err := s.evaluate(chapters)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var sb strings.Builder
+	for i, term := range s.terms {
+		names := lo.FilterMap(chapters, func(chapter *source.Chapter, j int) (string, bool) {
+			return chapter.Name, matches[i][j]
+		})
+
+		verb := "matched"
+		if term.negated {
+			verb = "excluded"
+		}
+
+		sb.WriteString(fmt.Sprintf("%q %s %s", term.raw, verb, util.Quantify(len(names), "chapter", "chapters")))
+		if len(names) > 0 {
+			sb.WriteString(": " + strings.Join(names, ", "))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("Selected %s\n", util.Quantify(len(selected), "chapter", "chapters")))
+	return selected, sb.String(), nil
+}
+
+func (s *Selector) evaluate(chapters []*source.Chapter) (selected []*source.Chapter, matches [][]bool, err error) {
+	var (
+		included    = make([]bool, len(chapters))
+		excluded    = make([]bool, len(chapters))
+		hasPositive bool
+	)
+
+	for _, term := range s.terms {
+		matched, err := term.match(chapters)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		matches = append(matches, matched)
+
+		target := included
+		if term.negated {
+			target = excluded
+		} else {
+			hasPositive = true
+		}
+
+		for i, ok := range matched {
+			target[i] = target[i] || ok
+		}
+	}
+
+	for i, chapter := range chapters {
+		// selector with only negated terms excludes from all chapters
+		if (included[i] || !hasPositive) && !excluded[i] {
+			selected = append(selected, chapter)
+		}
+	}
+
+	return
+}
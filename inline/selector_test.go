@@ -0,0 +1,131 @@
+package inline
+
+import (
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/lo"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+}
+
+type testSource struct{}
+
+func (testSource) Name() string {
+	return "test source"
+}
+
+func (testSource) Search(string) ([]*source.Manga, error) {
+	panic("")
+}
+
+func (testSource) ChaptersOf(*source.Manga) ([]*source.Chapter, error) {
+	panic("")
+}
+
+func (testSource) PagesOf(*source.Chapter) ([]*source.Page, error) {
+	panic("")
+}
+
+func (testSource) ID() string {
+	return "test source"
+}
+
+func testChapters() []*source.Chapter {
+	manga := &source.Manga{Name: "test", Source: testSource{}}
+	names := []string{
+		"Prologue",
+		"Vol.1 Chapter 1",
+		"Vol.1 Chapter 2",
+		"Vol.2 Chapter 2.5 Extra",
+		"Vol.2 Chapter 3",
+		"Vol.3 Chapter 10",
+		"Vol.3 Chapter 20.5",
+	}
+	volumes := []string{"", "Vol. 1", "Vol. 1", "Vol. 2", "Vol. 2", "Vol. 3", "Vol. 3"}
+
+	chapters := make([]*source.Chapter, len(names))
+	for i, name := range names {
+		chapters[i] = &source.Chapter{Name: name, Index: uint16(i + 1), Volume: volumes[i], Manga: manga}
+	}
+
+	manga.Chapters = chapters
+	return chapters
+}
+
+func selectNames(description string) []string {
+	selector, err := ParseSelector(description)
+	So(err, ShouldBeNil)
+
+	selected, err := selector.Select(testChapters())
+	So(err, ShouldBeNil)
+
+	return lo.Map(selected, func(chapter *source.Chapter, _ int) string {
+		return chapter.Name
+	})
+}
+
+func TestParseSelector(t *testing.T) {
+	Convey("Given a list of chapters", t, func() {
+		Convey("When selecting by position", func() {
+			So(selectNames("first"), ShouldResemble, []string{"Prologue"})
+			So(selectNames("last"), ShouldResemble, []string{"Vol.3 Chapter 20.5"})
+			So(selectNames("1-2"), ShouldResemble, []string{"Vol.1 Chapter 1", "Vol.1 Chapter 2"})
+			So(selectNames("latest:2"), ShouldResemble, []string{"Vol.3 Chapter 10", "Vol.3 Chapter 20.5"})
+			So(selectNames("latest:100"), ShouldHaveLength, 7)
+		})
+
+		Convey("When selecting positions past the end", func() {
+			So(selectNames("100"), ShouldBeEmpty)
+			So(selectNames("50-60"), ShouldBeEmpty)
+			So(selectNames("5-100"), ShouldResemble, []string{"Vol.3 Chapter 10", "Vol.3 Chapter 20.5"})
+		})
+
+		Convey("When selecting by chapter number", func() {
+			So(selectNames("#10-#20.5"), ShouldResemble, []string{"Vol.3 Chapter 10", "Vol.3 Chapter 20.5"})
+			So(selectNames("#2.5"), ShouldResemble, []string{"Vol.2 Chapter 2.5 Extra"})
+			So(selectNames("#3-"), ShouldResemble, []string{"Vol.2 Chapter 3", "Vol.3 Chapter 10", "Vol.3 Chapter 20.5"})
+		})
+
+		Convey("When selecting by volume", func() {
+			So(selectNames("v2"), ShouldResemble, []string{"Vol.2 Chapter 2.5 Extra", "Vol.2 Chapter 3"})
+		})
+
+		Convey("When selecting by regex", func() {
+			So(selectNames(`/chapter \d$/i`), ShouldResemble, []string{"Vol.1 Chapter 1", "Vol.1 Chapter 2", "Vol.2 Chapter 3"})
+			So(selectNames(`/^(Prologue|.*,.*)$/`), ShouldResemble, []string{"Prologue"})
+		})
+
+		Convey("When combining and negating terms", func() {
+			So(selectNames("v1,#10"), ShouldResemble, []string{"Vol.1 Chapter 1", "Vol.1 Chapter 2", "Vol.3 Chapter 10"})
+			So(selectNames("v2,!@Extra@"), ShouldResemble, []string{"Vol.2 Chapter 3"})
+			So(selectNames("!first,!v3"), ShouldHaveLength, 4)
+		})
+
+		Convey("When selecting new chapters without history", func() {
+			So(selectNames("new"), ShouldHaveLength, 7)
+		})
+
+		Convey("When selector is invalid", func() {
+			for _, description := range []string{"", "v", "#a", "latest:0", "1,,2", "/[/"} {
+				_, err := ParseSelector(description)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("When explaining", func() {
+			selector, err := ParseSelector("v1,!#2")
+			So(err, ShouldBeNil)
+
+			selected, explanation, err := selector.Explain(testChapters())
+			So(err, ShouldBeNil)
+			So(selected, ShouldHaveLength, 1)
+			So(explanation, ShouldContainSubstring, `"v1" matched 2 chapters`)
+			So(explanation, ShouldContainSubstring, `"!#2" excluded 1 chapter: Vol.1 Chapter 2`)
+			So(explanation, ShouldContainSubstring, "Selected 1 chapter")
+		})
+	})
+}
@@ -0,0 +1,116 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around the changes
+const diffContext = 3
+
+type diffLine struct {
+	// op is one of ' ', '-', '+'
+	op   byte
+	text string
+}
+
+// diffLines compares the lines using the longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+
+	return lines
+}
+
+// Diff returns the changed lines between the old and new text with a few lines of context.
+// Skipped unchanged lines are replaced with "@@ line N @@" markers, where N is the line in the new text.
+func Diff(old, new string) string {
+	lines := diffLines(strings.Split(old, "\n"), strings.Split(new, "\n"))
+
+	// show marks the lines near the changes
+	show := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.op == ' ' {
+			continue
+		}
+
+		for j := i - diffContext; j <= i+diffContext; j++ {
+			if j >= 0 && j < len(lines) {
+				show[j] = true
+			}
+		}
+	}
+
+	var (
+		b       strings.Builder
+		newLine int
+		skipped = true
+	)
+
+	for i, line := range lines {
+		if line.op != '-' {
+			newLine++
+		}
+
+		if !show[i] {
+			skipped = true
+			continue
+		}
+
+		if skipped {
+			at := newLine
+			if line.op == '-' {
+				at++
+			}
+
+			_, _ = fmt.Fprintf(&b, "@@ line %d @@\n", at)
+			skipped = false
+		}
+
+		b.WriteByte(line.op)
+		b.WriteString(line.text)
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
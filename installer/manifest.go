@@ -0,0 +1,114 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrNoManifest is returned when the scrapers repository does not publish the manifest
+var ErrNoManifest = errors.New("scrapers repository has no manifest")
+
+// errNotFound is returned when the requested file does not exist
+var errNotFound = errors.New("not found")
+
+// rawURL is the host of the raw files of GitHub repositories
+var rawURL = "https://raw.githubusercontent.com"
+
+// ManifestEntry describes the published version of the scraper
+type ManifestEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// SHA256 is the hex encoded checksum of the scraper file
+	SHA256    string `json:"sha256"`
+	Changelog string `json:"changelog"`
+}
+
+// Manifest of the scrapers repository, e.g.
+//
+//	{
+//	  "scrapers": [
+//	    { "name": "Example", "version": "1.1.0", "sha256": "...", "changelog": "Fix search" }
+//	  ]
+//	}
+type Manifest struct {
+	Scrapers []*ManifestEntry `json:"scrapers"`
+}
+
+// Get returns the entry of the scraper with the given name
+func (m *Manifest) Get(name string) (*ManifestEntry, bool) {
+	return lo.Find(m.Scrapers, func(e *ManifestEntry) bool {
+		return e.Name == name
+	})
+}
+
+// Verify checks that the contents match the checksum of the entry
+func (e *ManifestEntry) Verify(contents []byte) error {
+	sum := sha256.Sum256(contents)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, e.SHA256) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", e.Name, e.SHA256, actual)
+	}
+
+	return nil
+}
+
+// raw returns the url of the raw file in the scrapers repository
+func raw(path string) string {
+	return fmt.Sprintf(
+		"%s/%s/%s/%s/%s",
+		rawURL,
+		viper.GetString(key.InstallerUser),
+		viper.GetString(key.InstallerRepo),
+		viper.GetString(key.InstallerBranch),
+		strings.TrimPrefix(path, "/"),
+	)
+}
+
+// get downloads the file from the url
+func get(url string) ([]byte, error) {
+	res, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer util.Ignore(res.Body.Close)
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("failed to get %s: %w", url, errNotFound)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: %s", url, res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// FetchManifest downloads the manifest from the scrapers repository.
+// ErrNoManifest is returned if the repository does not have one.
+func FetchManifest() (*Manifest, error) {
+	contents, err := get(raw(viper.GetString(key.InstallerManifest)))
+	if errors.Is(err, errNotFound) {
+		return nil, ErrNoManifest
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err = json.Unmarshal(contents, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
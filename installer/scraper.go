@@ -3,12 +3,11 @@ package installer
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/metafates/mangal/filesystem"
 	"github.com/metafates/mangal/where"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 )
 
@@ -84,5 +83,19 @@ func (s *Scraper) Install() error {
 		return err
 	}
 
-	return filesystem.Api().WriteFile(s.Path(), []byte(s.Contents), os.ModePerm)
+	// scrapers that are not published in the manifest are installed as is
+	manifest, err := FetchManifest()
+	switch {
+	case errors.Is(err, ErrNoManifest):
+	case err != nil:
+		return err
+	default:
+		if entry, ok := manifest.Get(s.Name); ok {
+			if err = entry.Verify([]byte(s.Contents)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return replace(s.Name, []byte(s.Contents))
 }
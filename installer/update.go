@@ -0,0 +1,150 @@
+package installer
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/provider/custom"
+	"github.com/metafates/mangal/util"
+	"github.com/metafates/mangal/version"
+	"github.com/metafates/mangal/where"
+	"os"
+	"path/filepath"
+)
+
+// Update of the installed scraper to the version from the manifest
+type Update struct {
+	Name string
+	// Installed is the version from the header of the installed scraper, empty if it has none
+	Installed string
+	Entry     *ManifestEntry
+
+	current, contents []byte
+}
+
+func installedPath(name string) string {
+	return filepath.Join(where.Sources(), name+".lua")
+}
+
+func backupPath(name string) string {
+	return filepath.Join(where.SourcesBackup(), name+".lua")
+}
+
+// Updates returns the installed scrapers that have newer versions in the manifest.
+// Scrapers without versions are updated if their contents differ from the published ones.
+func Updates(manifest *Manifest) ([]*Update, error) {
+	files, err := filesystem.Api().ReadDir(where.Sources())
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []*Update
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".lua" {
+			continue
+		}
+
+		name := util.FileStem(file.Name())
+		entry, ok := manifest.Get(name)
+		if !ok {
+			continue
+		}
+
+		current, err := filesystem.Api().ReadFile(installedPath(name))
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Verify(current) == nil {
+			continue
+		}
+
+		header, err := custom.ParseHeader(installedPath(name))
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Version != "" {
+			// local versions that are newer or the same are kept
+			if cmp, err := version.Compare(header.Version, entry.Version); err == nil && cmp >= 0 {
+				continue
+			}
+		}
+
+		updates = append(updates, &Update{
+			Name:      name,
+			Installed: header.Version,
+			Entry:     entry,
+			current:   current,
+		})
+	}
+
+	return updates, nil
+}
+
+// Fetch downloads the new version of the scraper and verifies its checksum
+func (u *Update) Fetch() error {
+	if u.contents != nil {
+		return nil
+	}
+
+	contents, err := get(raw(fmt.Sprintf("scrapers/%s.lua", u.Name)))
+	if err != nil {
+		return err
+	}
+
+	if err = u.Entry.Verify(contents); err != nil {
+		return err
+	}
+
+	u.contents = contents
+	return nil
+}
+
+// Diff returns changes between the installed and the fetched versions
+func (u *Update) Diff() string {
+	return Diff(string(u.current), string(u.contents))
+}
+
+// Apply writes the fetched version and keeps the installed one for Rollback
+func (u *Update) Apply() error {
+	if u.contents == nil {
+		return fmt.Errorf("update of %s is not fetched", u.Name)
+	}
+
+	return replace(u.Name, u.contents)
+}
+
+// replace writes the scraper and moves the installed one, if any, to the backup
+func replace(name string, contents []byte) error {
+	current, err := filesystem.Api().ReadFile(installedPath(name))
+	if err == nil {
+		if err = filesystem.Api().WriteFile(backupPath(name), current, os.ModePerm); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return filesystem.Api().WriteFile(installedPath(name), contents, os.ModePerm)
+}
+
+// HasBackup checks if the previous version of the scraper is kept
+func HasBackup(name string) bool {
+	exists, err := filesystem.Api().Exists(backupPath(name))
+	return err == nil && exists
+}
+
+// Rollback restores the previous version of the scraper.
+// The replaced version becomes the backup, so the rollback can be undone by another one.
+func Rollback(name string) error {
+	previous, err := filesystem.Api().ReadFile(backupPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no previous version of %s", name)
+		}
+
+		return err
+	}
+
+	return replace(name, previous)
+}
@@ -0,0 +1,189 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+const (
+	installedScraper = "-- @name Example\n-- @version 1.0.0\n\nfunction SearchManga(query)\n\treturn {}\nend\n"
+	publishedScraper = "-- @name Example\n-- @version 1.1.0\n\nfunction SearchManga(query)\n\treturn { { name = query, url = query } }\nend\n"
+)
+
+func checksum(contents string) string {
+	sum := sha256.Sum256([]byte(contents))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestUpdate(t *testing.T) {
+	Convey("Given the repository with the manifest", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.InstallerUser, "user")
+		viper.Set(key.InstallerRepo, "repo")
+		viper.Set(key.InstallerBranch, "main")
+		viper.Set(key.InstallerManifest, "manifest.json")
+
+		published := publishedScraper
+		manifest := Manifest{Scrapers: []*ManifestEntry{{
+			Name:      "Example",
+			Version:   "1.1.0",
+			SHA256:    checksum(publishedScraper),
+			Changelog: "Return results",
+		}}}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/user/repo/main/manifest.json":
+				_ = json.NewEncoder(w).Encode(manifest)
+			case "/user/repo/main/scrapers/Example.lua":
+				_, _ = w.Write([]byte(published))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+		rawURL = server.URL
+
+		So(filesystem.Api().WriteFile(installedPath("Example"), []byte(installedScraper), os.ModePerm), ShouldBeNil)
+		So(filesystem.Api().WriteFile(installedPath("Other"), []byte("-- @version 1.0.0\n"), os.ModePerm), ShouldBeNil)
+
+		fetched, err := FetchManifest()
+		So(err, ShouldBeNil)
+
+		Convey("When updates are checked", func() {
+			updates, err := Updates(fetched)
+
+			Convey("Then only the outdated published scraper should be listed", func() {
+				So(err, ShouldBeNil)
+				So(updates, ShouldHaveLength, 1)
+				So(updates[0].Name, ShouldEqual, "Example")
+				So(updates[0].Installed, ShouldEqual, "1.0.0")
+				So(updates[0].Entry.Changelog, ShouldEqual, "Return results")
+			})
+
+			Convey("And the update is fetched and applied", func() {
+				So(updates[0].Fetch(), ShouldBeNil)
+				So(updates[0].Diff(), ShouldContainSubstring, "+-- @version 1.1.0")
+				So(updates[0].Apply(), ShouldBeNil)
+
+				Convey("Then the new version should be installed", func() {
+					contents, err := filesystem.Api().ReadFile(installedPath("Example"))
+					So(err, ShouldBeNil)
+					So(string(contents), ShouldEqual, publishedScraper)
+
+					updates, err := Updates(fetched)
+					So(err, ShouldBeNil)
+					So(updates, ShouldBeEmpty)
+				})
+
+				Convey("Then the rollback should restore the previous version", func() {
+					So(HasBackup("Example"), ShouldBeTrue)
+					So(Rollback("Example"), ShouldBeNil)
+
+					contents, err := filesystem.Api().ReadFile(installedPath("Example"))
+					So(err, ShouldBeNil)
+					So(string(contents), ShouldEqual, installedScraper)
+
+					Convey("And another rollback should undo it", func() {
+						So(Rollback("Example"), ShouldBeNil)
+
+						contents, err := filesystem.Api().ReadFile(installedPath("Example"))
+						So(err, ShouldBeNil)
+						So(string(contents), ShouldEqual, publishedScraper)
+					})
+				})
+			})
+		})
+
+		Convey("When the published file does not match the checksum", func() {
+			published = publishedScraper + "-- tampered\n"
+			updates, err := Updates(fetched)
+			So(err, ShouldBeNil)
+			So(updates, ShouldHaveLength, 1)
+
+			Convey("Then it should not be fetched nor written", func() {
+				So(updates[0].Fetch(), ShouldNotBeNil)
+				So(updates[0].Apply(), ShouldNotBeNil)
+
+				contents, err := filesystem.Api().ReadFile(installedPath("Example"))
+				So(err, ShouldBeNil)
+				So(string(contents), ShouldEqual, installedScraper)
+			})
+		})
+
+		Convey("When there is no previous version", func() {
+			Convey("Then the rollback should fail", func() {
+				So(HasBackup("Other"), ShouldBeFalse)
+				So(Rollback("Other"), ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestDiff(t *testing.T) {
+	Convey("Given two texts with a changed line", t, func() {
+		old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10"
+		new := "1\n2\n3\n4\n5\nsix\n7\n8\n9\n10"
+
+		Convey("When they are compared", func() {
+			diff := Diff(old, new)
+
+			Convey("Then only the change with its context should be shown", func() {
+				So(diff, ShouldEqual, "@@ line 3 @@\n 3\n 4\n 5\n-6\n+six\n 7\n 8\n 9\n")
+			})
+		})
+	})
+}
+
+func TestInstall(t *testing.T) {
+	Convey("Given the repository", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.InstallerUser, "user")
+		viper.Set(key.InstallerRepo, "repo")
+		viper.Set(key.InstallerBranch, "main")
+		viper.Set(key.InstallerManifest, "manifest.json")
+
+		status := http.StatusNotFound
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+		rawURL = server.URL
+
+		scraper := &Scraper{Name: "Example", Contents: publishedScraper}
+
+		Convey("When the repository has no manifest", func() {
+			err := scraper.Install()
+
+			Convey("Then the scraper should be installed as is", func() {
+				So(err, ShouldBeNil)
+
+				contents, err := filesystem.Api().ReadFile(installedPath("Example"))
+				So(err, ShouldBeNil)
+				So(string(contents), ShouldEqual, publishedScraper)
+			})
+		})
+
+		Convey("When the manifest can not be fetched", func() {
+			status = http.StatusInternalServerError
+			err := scraper.Install()
+
+			Convey("Then the scraper should not be installed", func() {
+				So(err, ShouldNotBeNil)
+
+				exists, err := filesystem.Api().Exists(installedPath("Example"))
+				So(err, ShouldBeNil)
+				So(exists, ShouldBeFalse)
+			})
+		})
+	})
+}
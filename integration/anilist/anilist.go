@@ -1,17 +1,30 @@
 package anilist
 
 import (
+	"github.com/metafates/gache"
+	"github.com/metafates/mangal/filesystem"
 	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/where"
 	"github.com/spf13/viper"
+	"net/url"
 )
 
+// PinRedirectURI is the redirect URI that shows the code to copy manually
+const PinRedirectURI = "https://anilist.co/api/v2/oauth/pin"
+
 type Anilist struct {
-	token string
+	token  string
+	tokens *gache.Cache[*Token]
 }
 
 // New cereates a new Anilist integration instance
 func New() *Anilist {
-	return &Anilist{}
+	return &Anilist{
+		tokens: gache.New[*Token](&gache.Options{
+			Path:       where.AnilistToken(),
+			FileSystem: &filesystem.GacheFs{},
+		}),
+	}
 }
 
 func (a *Anilist) id() string {
@@ -26,7 +39,16 @@ func (a *Anilist) code() string {
 	return viper.GetString(key.AnilistCode)
 }
 
-// AuthURL returns the URL to authenticate with Anilist
-func (a *Anilist) AuthURL() string {
-	return "https://anilist.co/api/v2/oauth/authorize?client_id=" + a.id() + "&response_type=code&redirect_uri=https://anilist.co/api/v2/oauth/pin"
+// AuthURL returns the URL to authenticate with Anilist.
+// Use PinRedirectURI to let user copy the code manually
+func (a *Anilist) AuthURL(redirectURI, state string) string {
+	params := url.Values{}
+	params.Set("client_id", a.id())
+	params.Set("response_type", "code")
+	params.Set("redirect_uri", redirectURI)
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	return "https://anilist.co/api/v2/oauth/authorize?" + params.Encode()
 }
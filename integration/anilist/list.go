@@ -0,0 +1,165 @@
+package anilist
+
+import (
+	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/integration/tracker"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/mo"
+	"math"
+)
+
+var getEntryQuery = `
+query ($id: Int) {
+	Media (id: $id, type: MANGA) {
+		mediaListEntry {
+			progress
+			status
+			score (format: POINT_10_DECIMAL)
+			startedAt { year month day }
+			completedAt { year month day }
+		}
+	}
+}
+`
+
+// saveEntryQuery updates only the fields that were passed in the variables.
+// Score is written as scoreRaw, which is out of 100 regardless of the user scoring format,
+// unlike score that would be read in the format of the user.
+var saveEntryQuery = `
+mutation ($id: Int, $progress: Int, $status: MediaListStatus, $scoreRaw: Int, $startedAt: FuzzyDateInput, $completedAt: FuzzyDateInput) {
+	SaveMediaListEntry (mediaId: $id, progress: $progress, status: $status, scoreRaw: $scoreRaw, startedAt: $startedAt, completedAt: $completedAt) {
+		id
+	}
+}
+`
+
+// date is the Anilist FuzzyDate, fields are null when unknown
+type date struct {
+	Year  *int `json:"year"`
+	Month *int `json:"month"`
+	Day   *int `json:"day"`
+}
+
+func (d date) toDate() tracker.Date {
+	value := func(i *int) int {
+		if i == nil {
+			return 0
+		}
+
+		return *i
+	}
+
+	return tracker.Date{Year: value(d.Year), Month: value(d.Month), Day: value(d.Day)}
+}
+
+func (a *Anilist) Name() string {
+	return "Anilist"
+}
+
+// closest returns the Anilist manga bound to the given manga
+func (a *Anilist) closest(manga *source.Manga) (*anilist.Manga, error) {
+	if m, ok := manga.Anilist.Get(); ok {
+		return m, nil
+	}
+
+	return anilist.FindClosest(manga.Name)
+}
+
+func (a *Anilist) GetEntry(manga *source.Manga) (mo.Option[*tracker.Entry], error) {
+	if err := a.ensureLogin(); err != nil {
+		return mo.None[*tracker.Entry](), err
+	}
+
+	m, err := a.closest(manga)
+	if err != nil {
+		log.Error(err)
+		return mo.None[*tracker.Entry](), err
+	}
+
+	var response struct {
+		Media struct {
+			MediaListEntry *struct {
+				Progress    int            `json:"progress"`
+				Status      tracker.Status `json:"status"`
+				Score       float64        `json:"score"`
+				StartedAt   date           `json:"startedAt"`
+				CompletedAt date           `json:"completedAt"`
+			} `json:"mediaListEntry"`
+		} `json:"Media"`
+	}
+
+	err = anilist.DefaultClient.Query(getEntryQuery, map[string]any{"id": m.ID}, a.token, &response)
+	if err != nil {
+		log.Error(err)
+		return mo.None[*tracker.Entry](), err
+	}
+
+	entry := response.Media.MediaListEntry
+	if entry == nil {
+		return mo.None[*tracker.Entry](), nil
+	}
+
+	return mo.Some(&tracker.Entry{
+		MediaID:     m.ID,
+		Progress:    entry.Progress,
+		Status:      entry.Status,
+		Score:       entry.Score,
+		StartedAt:   entry.StartedAt.toDate(),
+		CompletedAt: entry.CompletedAt.toDate(),
+	}), nil
+}
+
+func (a *Anilist) SetProgress(manga *source.Manga, progress int) error {
+	return a.save(manga, map[string]any{"progress": progress})
+}
+
+func (a *Anilist) SetStatus(manga *source.Manga, status tracker.Status) error {
+	return a.save(manga, map[string]any{"status": status})
+}
+
+func (a *Anilist) SetScore(manga *source.Manga, score float64) error {
+	// score is out of 10, the same as it is read with POINT_10_DECIMAL
+	return a.save(manga, map[string]any{"scoreRaw": int(math.Round(score * 10))})
+}
+
+func (a *Anilist) SetDates(manga *source.Manga, startedAt, completedAt tracker.Date) error {
+	variables := make(map[string]any)
+
+	if !startedAt.IsZero() {
+		variables["startedAt"] = startedAt
+	}
+
+	if !completedAt.IsZero() {
+		variables["completedAt"] = completedAt
+	}
+
+	if len(variables) == 0 {
+		return nil
+	}
+
+	return a.save(manga, variables)
+}
+
+// save updates the list entry of the manga with the given variables
+func (a *Anilist) save(manga *source.Manga, variables map[string]any) error {
+	if err := a.ensureLogin(); err != nil {
+		return err
+	}
+
+	m, err := a.closest(manga)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	variables["id"] = m.ID
+
+	var response struct {
+		SaveMediaListEntry struct {
+			ID int `json:"id"`
+		} `json:"SaveMediaListEntry"`
+	}
+
+	return anilist.DefaultClient.Query(saveEntryQuery, variables, a.token, &response)
+}
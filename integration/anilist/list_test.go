@@ -0,0 +1,46 @@
+package anilist
+
+import (
+	"encoding/json"
+	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/mo"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnilist_SetScore(t *testing.T) {
+	Convey("Given a logged in Anilist", t, func() {
+		var request struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &request)
+			_, _ = w.Write([]byte(`{"data": {"SaveMediaListEntry": {"id": 1}}}`))
+		}))
+		defer server.Close()
+
+		defaultClient := anilist.DefaultClient
+		defer func() { anilist.DefaultClient = defaultClient }()
+		anilist.DefaultClient = anilist.NewClient(server.Client(), server.URL)
+
+		a := &Anilist{token: "token"}
+		manga := &source.Manga{Anilist: mo.Some(&anilist.Manga{ID: 30013})}
+
+		Convey("When the score is set", func() {
+			err := a.SetScore(manga, 8.5)
+
+			Convey("Then it should be sent as the raw score out of 100", func() {
+				So(err, ShouldBeNil)
+				So(request.Query, ShouldContainSubstring, "scoreRaw: $scoreRaw")
+				So(request.Variables, ShouldResemble, map[string]any{"id": float64(30013), "scoreRaw": float64(85)})
+			})
+		})
+	})
+}
@@ -6,13 +6,69 @@ import (
 	"fmt"
 	"github.com/metafates/mangal/log"
 	"github.com/metafates/mangal/network"
+	"github.com/metafates/mangal/util"
 	"github.com/samber/lo"
 	"net/http"
 	"strconv"
+	"time"
 )
 
-// login to Anilist
-func (a *Anilist) login() error {
+// Token is the Anilist access token.
+// Anilist tokens are long-lived and can not be refreshed.
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// LoggedIn returns true if there is a valid saved token
+func (a *Anilist) LoggedIn() bool {
+	return a.savedToken() != ""
+}
+
+// Logout removes the saved token
+func (a *Anilist) Logout() error {
+	a.token = ""
+	return a.tokens.Set(nil)
+}
+
+func (a *Anilist) savedToken() string {
+	token, _, err := a.tokens.Get()
+	if err != nil || token == nil || time.Now().After(token.ExpiresAt) {
+		return ""
+	}
+
+	return token.AccessToken
+}
+
+// ensureLogin logs in to Anilist if not logged in yet.
+// Saved token is used if present, otherwise the code from the config is exchanged for it.
+func (a *Anilist) ensureLogin() error {
+	if a.token != "" {
+		return nil
+	}
+
+	if token := a.savedToken(); token != "" {
+		a.token = token
+		return nil
+	}
+
+	if a.code() == "" {
+		e := fmt.Errorf("not logged in to Anilist, run `mangal integration anilist`")
+		log.Error(e)
+		return e
+	}
+
+	err := a.Login(a.code(), PinRedirectURI)
+	if err != nil {
+		log.Error(err)
+	}
+
+	return err
+}
+
+// Login exchanges the authorization code for the token and saves it.
+// Redirect URI must be the same that was used to get the code.
+func (a *Anilist) Login(code, redirectURI string) error {
 	log.Info("Logging in to Anilist")
 
 	if a.id() == "" {
@@ -25,7 +81,7 @@ func (a *Anilist) login() error {
 		log.Error(e)
 		return e
 	}
-	if a.code() == "" {
+	if code == "" {
 		e := fmt.Errorf("no code set")
 		log.Error(e)
 		return e
@@ -36,8 +92,8 @@ func (a *Anilist) login() error {
 		"client_id":     a.id(),
 		"client_secret": a.secret(),
 		"grant_type":    "authorization_code",
-		"redirect_uri":  "https://anilist.co/api/v2/oauth/pin",
-		"code":          a.code(),
+		"redirect_uri":  redirectURI,
+		"code":          code,
 	}
 
 	// encode body
@@ -64,6 +120,8 @@ func (a *Anilist) login() error {
 		return err
 	}
 
+	defer util.Ignore(resp.Body.Close)
+
 	// check response code
 	if resp.StatusCode != http.StatusOK {
 		log.Info("Request failed with status code: " + strconv.Itoa(resp.StatusCode))
@@ -74,6 +132,7 @@ func (a *Anilist) login() error {
 	log.Info("Decoding response from Anilist")
 	var response struct {
 		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
 	}
 
 	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
@@ -85,5 +144,8 @@ func (a *Anilist) login() error {
 	log.Info("Logged in Anilist")
 	a.token = response.AccessToken
 
-	return nil
+	return a.tokens.Set(&Token{
+		AccessToken: response.AccessToken,
+		ExpiresAt:   time.Now().Add(time.Duration(response.ExpiresIn) * time.Second),
+	})
 }
@@ -2,15 +2,49 @@ package integration
 
 import (
 	"github.com/metafates/mangal/integration/anilist"
+	"github.com/metafates/mangal/integration/myanimelist"
+	"github.com/metafates/mangal/integration/tracker"
+	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/source"
+	"github.com/samber/mo"
+	"github.com/spf13/viper"
 )
 
 // Integrator is the interface that wraps the basic integration methods.
 type Integrator interface {
+	// Name of the integration
+	Name() string
 	// MarkRead marks a chapter as read
 	MarkRead(chapter *source.Chapter) error
+	// GetEntry returns the list entry of the manga.
+	// Returns none if the manga is not in the list
+	GetEntry(manga *source.Manga) (mo.Option[*tracker.Entry], error)
+	// SetProgress sets the amount of chapters read without changing the status
+	SetProgress(manga *source.Manga, progress int) error
+	// SetStatus sets the reading status of the manga
+	SetStatus(manga *source.Manga, status tracker.Status) error
+	// SetScore sets the score of the manga, from 0 to 10
+	SetScore(manga *source.Manga, score float64) error
+	// SetDates sets start and finish dates. Zero dates are left unchanged
+	SetDates(manga *source.Manga, startedAt, completedAt tracker.Date) error
 }
 
 var (
-	Anilist Integrator = anilist.New()
+	Anilist     Integrator = anilist.New()
+	MyAnimeList Integrator = myanimelist.New()
 )
+
+// Enabled returns integrations enabled in the config
+func Enabled() []Integrator {
+	var enabled []Integrator
+
+	if viper.GetBool(key.AnilistEnable) {
+		enabled = append(enabled, Anilist)
+	}
+
+	if viper.GetBool(key.MyAnimeListEnable) {
+		enabled = append(enabled, MyAnimeList)
+	}
+
+	return enabled
+}
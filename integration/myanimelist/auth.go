@@ -0,0 +1,163 @@
+package myanimelist
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/util"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotLoggedIn is returned when there is no saved token
+var ErrNotLoggedIn = errors.New("not logged in to MyAnimeList, run `mangal integration mal`")
+
+// verifierAlphabet is the set of characters allowed in the PKCE code verifier
+const verifierAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~"
+
+// Token is the OAuth2 token pair issued by MyAnimeList
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// expired returns true if the access token is expired or about to expire
+func (t *Token) expired() bool {
+	return time.Now().Add(time.Minute).After(t.ExpiresAt)
+}
+
+// NewVerifier generates a random PKCE code verifier
+func NewVerifier() (string, error) {
+	const length = 128
+
+	var sb strings.Builder
+	sb.Grow(length)
+
+	max := big.NewInt(int64(len(verifierAlphabet)))
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteByte(verifierAlphabet[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+// AuthURL returns the URL to authenticate with MyAnimeList.
+// MyAnimeList supports only the plain challenge method, so the verifier is used as challenge.
+func (m *MyAnimeList) AuthURL(verifier, state string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", m.clientID())
+	params.Set("code_challenge", verifier)
+	params.Set("code_challenge_method", "plain")
+	params.Set("state", state)
+
+	return m.authURL + "/v1/oauth2/authorize?" + params.Encode()
+}
+
+// Login exchanges authorization code for the token and saves it
+func (m *MyAnimeList) Login(code, verifier string) error {
+	log.Info("Logging in to MyAnimeList")
+
+	params := url.Values{}
+	params.Set("grant_type", "authorization_code")
+	params.Set("code", code)
+	params.Set("code_verifier", verifier)
+
+	return m.requestToken(params)
+}
+
+// Logout removes the saved token
+func (m *MyAnimeList) Logout() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.tokens.Set(nil)
+}
+
+// token returns a valid access token, refreshing it if needed
+func (m *MyAnimeList) token() (string, error) {
+	token, _, err := m.tokens.Get()
+	if err != nil {
+		return "", err
+	}
+
+	if token == nil || token.AccessToken == "" {
+		return "", ErrNotLoggedIn
+	}
+
+	if token.expired() {
+		if err = m.refresh(token); err != nil {
+			return "", err
+		}
+
+		return m.token()
+	}
+
+	return token.AccessToken, nil
+}
+
+// refresh gets a new access token using the refresh token
+func (m *MyAnimeList) refresh(token *Token) error {
+	log.Info("Refreshing MyAnimeList token")
+
+	if token.RefreshToken == "" {
+		return ErrNotLoggedIn
+	}
+
+	params := url.Values{}
+	params.Set("grant_type", "refresh_token")
+	params.Set("refresh_token", token.RefreshToken)
+
+	return m.requestToken(params)
+}
+
+// requestToken sends request to the token endpoint and saves the received token
+func (m *MyAnimeList) requestToken(params url.Values) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	params.Set("client_id", m.clientID())
+
+	resp, err := m.client.PostForm(m.authURL+"/v1/oauth2/token", params)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	defer util.Ignore(resp.Body.Close)
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("invalid response code %d", resp.StatusCode)
+		log.Error(err)
+		return err
+	}
+
+	var response struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log.Info("Logged in MyAnimeList")
+	return m.tokens.Set(&Token{
+		AccessToken:  response.AccessToken,
+		RefreshToken: response.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(response.ExpiresIn) * time.Second),
+	})
+}
@@ -0,0 +1,247 @@
+package myanimelist
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/integration/tracker"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/mo"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// statuses maps tracker statuses to MyAnimeList ones
+var statuses = map[tracker.Status]string{
+	tracker.Current:   "reading",
+	tracker.Repeating: "reading",
+	tracker.Completed: "completed",
+	tracker.Paused:    "on_hold",
+	tracker.Dropped:   "dropped",
+	tracker.Planning:  "plan_to_read",
+}
+
+// fromStatuses maps MyAnimeList statuses back to tracker ones
+var fromStatuses = map[string]tracker.Status{
+	"reading":      tracker.Current,
+	"completed":    tracker.Completed,
+	"on_hold":      tracker.Paused,
+	"dropped":      tracker.Dropped,
+	"plan_to_read": tracker.Planning,
+}
+
+// listStatus is the my_list_status object of the MyAnimeList API
+type listStatus struct {
+	Status          string `json:"status"`
+	IsRereading     bool   `json:"is_rereading"`
+	NumChaptersRead int    `json:"num_chapters_read"`
+	Score           int    `json:"score"`
+	StartDate       string `json:"start_date"`
+	FinishDate      string `json:"finish_date"`
+}
+
+func (s *listStatus) toEntry(id int) *tracker.Entry {
+	status := fromStatuses[s.Status]
+	if s.IsRereading {
+		status = tracker.Repeating
+	}
+
+	// dates can be partial, e.g. "2020" or "2020-05"
+	parseDate := func(s string) (date tracker.Date) {
+		parts := strings.Split(s, "-")
+		fields := []*int{&date.Year, &date.Month, &date.Day}
+		for i, part := range parts {
+			if i < len(fields) {
+				*fields[i], _ = strconv.Atoi(part)
+			}
+		}
+
+		return
+	}
+
+	return &tracker.Entry{
+		MediaID:     id,
+		Progress:    s.NumChaptersRead,
+		Status:      status,
+		Score:       float64(s.Score),
+		StartedAt:   parseDate(s.StartDate),
+		CompletedAt: parseDate(s.FinishDate),
+	}
+}
+
+// malID returns MyAnimeList id of the manga using its Anilist binding
+func (m *MyAnimeList) malID(manga *source.Manga) (int, error) {
+	al, ok := manga.Anilist.Get()
+	if !ok {
+		var err error
+		al, err = anilist.FindClosest(manga.Name)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if al.IDMal == 0 {
+		return 0, fmt.Errorf("manga %s has no MyAnimeList id on Anilist", al.Name())
+	}
+
+	return al.IDMal, nil
+}
+
+func (m *MyAnimeList) MarkRead(chapter *source.Chapter) error {
+	progress, ok := chapter.TrackerIndex()
+	if !ok {
+		log.Infof("Chapter %s of %s is ignored by the mapping rules", chapter.Name, chapter.Manga.Name)
+		return nil
+	}
+
+	log.Infof("Marking chapter %d of %s as read on MyAnimeList", progress, chapter.Manga.Name)
+	return m.update(chapter.Manga, url.Values{
+		"num_chapters_read": {strconv.Itoa(progress)},
+		"status":            {statuses[tracker.Current]},
+	})
+}
+
+func (m *MyAnimeList) GetEntry(manga *source.Manga) (mo.Option[*tracker.Entry], error) {
+	id, err := m.malID(manga)
+	if err != nil {
+		log.Error(err)
+		return mo.None[*tracker.Entry](), err
+	}
+
+	var response struct {
+		MyListStatus *listStatus `json:"my_list_status"`
+	}
+
+	path := fmt.Sprintf("/v2/manga/%d?fields=my_list_status", id)
+	if err = m.request(http.MethodGet, path, nil, &response); err != nil {
+		return mo.None[*tracker.Entry](), err
+	}
+
+	if response.MyListStatus == nil {
+		return mo.None[*tracker.Entry](), nil
+	}
+
+	return mo.Some(response.MyListStatus.toEntry(id)), nil
+}
+
+func (m *MyAnimeList) SetProgress(manga *source.Manga, progress int) error {
+	return m.update(manga, url.Values{"num_chapters_read": {strconv.Itoa(progress)}})
+}
+
+func (m *MyAnimeList) SetStatus(manga *source.Manga, status tracker.Status) error {
+	return m.update(manga, url.Values{
+		"status":       {statuses[status]},
+		"is_rereading": {strconv.FormatBool(status == tracker.Repeating)},
+	})
+}
+
+func (m *MyAnimeList) SetScore(manga *source.Manga, score float64) error {
+	// MyAnimeList supports only integer scores
+	return m.update(manga, url.Values{"score": {strconv.Itoa(int(math.Round(score)))}})
+}
+
+func (m *MyAnimeList) SetDates(manga *source.Manga, startedAt, completedAt tracker.Date) error {
+	values := url.Values{}
+
+	if !startedAt.IsZero() {
+		values.Set("start_date", startedAt.String())
+	}
+
+	if !completedAt.IsZero() {
+		values.Set("finish_date", completedAt.String())
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	return m.update(manga, values)
+}
+
+// update updates the list status of the manga with the given values
+func (m *MyAnimeList) update(manga *source.Manga, values url.Values) error {
+	id, err := m.malID(manga)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return m.request(http.MethodPatch, fmt.Sprintf("/v2/manga/%d/my_list_status", id), values, nil)
+}
+
+// request sends authorized request to the API.
+// If the token was rejected, it is refreshed and the request is sent again.
+func (m *MyAnimeList) request(method, path string, values url.Values, dst any) error {
+	for try := 0; ; try++ {
+		token, err := m.token()
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		var body io.Reader
+		if values != nil {
+			body = strings.NewReader(values.Encode())
+		}
+
+		req, err := http.NewRequest(method, m.apiURL+path, body)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		if values != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+
+		resp, err := m.client.Do(req)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && try == 0 {
+			util.Ignore(resp.Body.Close)
+			if err = m.forceRefresh(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		defer util.Ignore(resp.Body.Close)
+
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("invalid response code %d", resp.StatusCode)
+			log.Error(err)
+			return err
+		}
+
+		if dst == nil {
+			return nil
+		}
+
+		return json.NewDecoder(resp.Body).Decode(dst)
+	}
+}
+
+// forceRefresh refreshes the token even if it is not expired yet
+func (m *MyAnimeList) forceRefresh() error {
+	token, _, err := m.tokens.Get()
+	if err != nil {
+		return err
+	}
+
+	if token == nil {
+		return ErrNotLoggedIn
+	}
+
+	return m.refresh(token)
+}
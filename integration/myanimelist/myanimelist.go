@@ -0,0 +1,48 @@
+package myanimelist
+
+import (
+	"github.com/metafates/gache"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/network"
+	"github.com/metafates/mangal/where"
+	"github.com/spf13/viper"
+	"net/http"
+	"sync"
+)
+
+const (
+	// AuthURL is the base url of the MyAnimeList OAuth2 server
+	AuthURL = "https://myanimelist.net"
+	// APIURL is the base url of the MyAnimeList API
+	APIURL = "https://api.myanimelist.net"
+)
+
+type MyAnimeList struct {
+	authURL string
+	apiURL  string
+	client  *http.Client
+	tokens  *gache.Cache[*Token]
+	mutex   sync.Mutex
+}
+
+// New creates a new MyAnimeList integration instance
+func New() *MyAnimeList {
+	return &MyAnimeList{
+		authURL: AuthURL,
+		apiURL:  APIURL,
+		client:  network.Client,
+		tokens: gache.New[*Token](&gache.Options{
+			Path:       where.MyAnimeListToken(),
+			FileSystem: &filesystem.GacheFs{},
+		}),
+	}
+}
+
+func (m *MyAnimeList) Name() string {
+	return "MyAnimeList"
+}
+
+func (m *MyAnimeList) clientID() string {
+	return viper.GetString(key.MyAnimeListClientID)
+}
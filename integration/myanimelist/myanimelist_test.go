@@ -0,0 +1,146 @@
+package myanimelist
+
+import (
+	"encoding/json"
+	"github.com/metafates/gache"
+	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/integration/tracker"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/mo"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+}
+
+// standIn is a minimal MyAnimeList API stand-in
+type standIn struct {
+	mutex    sync.Mutex
+	verifier string
+	issued   int
+	status   map[int]url.Values
+}
+
+func (s *standIn) token() string {
+	return "token" + string(rune('0'+s.issued))
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r.URL.Path == "/v1/oauth2/token" {
+		_ = r.ParseForm()
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			if r.PostForm.Get("code_verifier") != s.verifier || r.PostForm.Get("code") != "code" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "refresh" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		s.issued++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  s.token(),
+			"refresh_token": "refresh",
+			"expires_in":    3600,
+		})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.token() {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPatch && r.URL.Path == "/v2/manga/42/my_list_status":
+		_ = r.ParseForm()
+		for k, v := range r.PostForm {
+			s.status[42][k] = v
+		}
+		_, _ = w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/manga/42":
+		_, _ = w.Write([]byte(`{"my_list_status": {"status": "reading", "num_chapters_read": 5, "score": 8, "start_date": "2022-10"}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestMyAnimeList(t *testing.T) {
+	Convey("Given a MyAnimeList stand-in", t, func() {
+		verifier, err := NewVerifier()
+		So(err, ShouldBeNil)
+		So(verifier, ShouldHaveLength, 128)
+
+		api := &standIn{verifier: verifier, status: map[int]url.Values{42: {}}}
+		server := httptest.NewServer(api)
+		defer server.Close()
+
+		mal := &MyAnimeList{
+			authURL: server.URL,
+			apiURL:  server.URL,
+			client:  server.Client(),
+			tokens:  gache.New[*Token](&gache.Options{Path: "myanimelist.json", FileSystem: &filesystem.GacheFs{}}),
+		}
+
+		manga := &source.Manga{Name: "test", Anilist: mo.Some(&anilist.Manga{IDMal: 42})}
+		chapter := &source.Chapter{Index: 7, Manga: manga}
+
+		Convey("When not logged in", func() {
+			So(mal.Logout(), ShouldBeNil)
+
+			Convey("Then marking should fail", func() {
+				So(mal.MarkRead(chapter), ShouldEqual, ErrNotLoggedIn)
+			})
+		})
+
+		Convey("When logged in with PKCE", func() {
+			So(mal.AuthURL(verifier, "state"), ShouldContainSubstring, "code_challenge_method=plain")
+			So(mal.Login("code", verifier), ShouldBeNil)
+
+			Convey("Then chapter should be marked as read", func() {
+				So(mal.MarkRead(chapter), ShouldBeNil)
+				So(api.status[42].Get("num_chapters_read"), ShouldEqual, "7")
+				So(api.status[42].Get("status"), ShouldEqual, "reading")
+			})
+
+			Convey("Then entry should be read", func() {
+				entry, err := mal.GetEntry(manga)
+				So(err, ShouldBeNil)
+				So(entry.IsPresent(), ShouldBeTrue)
+				So(entry.MustGet().Progress, ShouldEqual, 5)
+				So(entry.MustGet().Status, ShouldEqual, tracker.Current)
+				So(entry.MustGet().StartedAt, ShouldResemble, tracker.Date{Year: 2022, Month: 10})
+			})
+
+			Convey("And the token was revoked", func() {
+				api.issued++
+
+				Convey("Then it should be refreshed", func() {
+					So(mal.SetStatus(manga, tracker.Paused), ShouldBeNil)
+					So(api.status[42].Get("status"), ShouldEqual, "on_hold")
+				})
+			})
+		})
+
+		Convey("When manga has no MyAnimeList id", func() {
+			err := mal.SetProgress(&source.Manga{Anilist: mo.Some(&anilist.Manga{})}, 1)
+			Convey("Then error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,162 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CallbackPath is the path that the authorization server redirects to
+const CallbackPath = "/callback"
+
+// ErrTimeout is returned when the redirect was not received in time
+var ErrTimeout = errors.New("timed out waiting for the authorization redirect")
+
+type result struct {
+	code string
+	err  error
+}
+
+// Loopback is a local HTTP server that captures the authorization code from the OAuth redirect
+type Loopback struct {
+	State    string
+	listener net.Listener
+	server   *http.Server
+	result   chan result
+}
+
+// Listen starts the loopback server on the given port of 127.0.0.1.
+// Port 0 picks a random free port.
+func Listen(port int) (*Loopback, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	state, err := RandomState()
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	l := &Loopback{
+		State:    state,
+		listener: listener,
+		result:   make(chan result, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(CallbackPath, l.handle)
+	l.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := l.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error(err)
+		}
+	}()
+
+	return l, nil
+}
+
+// RedirectURI is the address of the callback.
+// It must be registered as the redirect URL of the OAuth client.
+// The IP is used instead of localhost, because localhost may resolve to ::1 that is not listened on.
+func (l *Loopback) RedirectURI() string {
+	return fmt.Sprintf("http://127.0.0.1:%d%s", l.listener.Addr().(*net.TCPAddr).Port, CallbackPath)
+}
+
+func (l *Loopback) handle(w http.ResponseWriter, r *http.Request) {
+	var res result
+	res.code, res.err = codeFrom(r.URL.Query(), l.State)
+
+	if res.err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "%s. You can close this window.", res.err)
+	} else {
+		_, _ = fmt.Fprintf(w, "Authorized %s. You can close this window.", constant.Mangal)
+	}
+
+	select {
+	case l.result <- res:
+	default:
+	}
+}
+
+// Wait blocks until the authorization code is received or timeout exceeds
+func (l *Loopback) Wait(timeout time.Duration) (string, error) {
+	select {
+	case res := <-l.result:
+		return res.code, res.err
+	case <-time.After(timeout):
+		return "", ErrTimeout
+	}
+}
+
+// Close shuts down the server
+func (l *Loopback) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	return l.server.Shutdown(ctx)
+}
+
+// CanOpenBrowser returns false on machines that are likely headless,
+// e.g. connected via SSH or without a display server
+func CanOpenBrowser() bool {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return false
+	}
+
+	if runtime.GOOS == constant.Linux {
+		return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+	}
+
+	return true
+}
+
+// RandomState returns a random value for the OAuth state parameter
+func RandomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// CodeFromRedirect returns the authorization code from the pasted redirect URL,
+// after checking that its state parameter is the one that was sent
+func CodeFromRedirect(redirect, state string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(redirect))
+	if err != nil {
+		return "", err
+	}
+
+	return codeFrom(u.Query(), state)
+}
+
+// codeFrom returns the authorization code from the redirect query if its state matches
+func codeFrom(query url.Values, state string) (string, error) {
+	switch {
+	case query.Get("error") != "":
+		return "", fmt.Errorf("authorization failed: %s", query.Get("error"))
+	case query.Get("state") != state:
+		return "", errors.New("authorization failed: state mismatch")
+	case query.Get("code") == "":
+		return "", errors.New("authorization failed: no code received")
+	}
+
+	return query.Get("code"), nil
+}
@@ -0,0 +1,85 @@
+package oauth
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestLoopback(t *testing.T) {
+	Convey("Given a loopback server", t, func() {
+		l, err := Listen(0)
+		So(err, ShouldBeNil)
+		defer l.Close()
+
+		callback := func(params url.Values) int {
+			resp, err := http.Get(l.RedirectURI() + "?" + params.Encode())
+			So(err, ShouldBeNil)
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		Convey("Then the redirect URI should point to the listened address", func() {
+			So(l.RedirectURI(), ShouldStartWith, "http://127.0.0.1:")
+			So(l.RedirectURI(), ShouldEndWith, CallbackPath)
+		})
+
+		Convey("When redirected with the code", func() {
+			status := callback(url.Values{"code": {"secret"}, "state": {l.State}})
+
+			Convey("Then the code should be captured", func() {
+				So(status, ShouldEqual, http.StatusOK)
+				code, err := l.Wait(time.Second)
+				So(err, ShouldBeNil)
+				So(code, ShouldEqual, "secret")
+			})
+		})
+
+		Convey("When redirected with wrong state", func() {
+			status := callback(url.Values{"code": {"secret"}, "state": {"forged"}})
+
+			Convey("Then error should be returned", func() {
+				So(status, ShouldEqual, http.StatusBadRequest)
+				_, err := l.Wait(time.Second)
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When not redirected", func() {
+			_, err := l.Wait(time.Millisecond * 10)
+
+			Convey("Then it should time out", func() {
+				So(err, ShouldEqual, ErrTimeout)
+			})
+		})
+	})
+}
+
+func TestCodeFromRedirect(t *testing.T) {
+	Convey("Given a random state", t, func() {
+		state, err := RandomState()
+		So(err, ShouldBeNil)
+
+		Convey("When the redirect URL has the same state", func() {
+			code, err := CodeFromRedirect("http://localhost/oauth?code=secret&state="+state, state)
+
+			Convey("Then the code should be returned", func() {
+				So(err, ShouldBeNil)
+				So(code, ShouldEqual, "secret")
+			})
+		})
+
+		Convey("When the redirect URL has another state or none", func() {
+			for _, redirect := range []string{
+				"http://localhost/oauth?code=secret&state=forged",
+				"http://localhost/oauth?code=secret",
+				"secret",
+			} {
+				_, err := CodeFromRedirect(redirect, state)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
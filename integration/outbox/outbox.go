@@ -0,0 +1,258 @@
+package outbox
+
+import (
+	"fmt"
+	"github.com/metafates/gache"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/integration"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/where"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Update is a pending tracker update
+type Update struct {
+	// Integration is the name of the integration to send the update to
+	Integration string `json:"integration"`
+	MangaName   string `json:"manga_name"`
+	ChapterName string `json:"chapter_name"`
+	Volume      string `json:"volume,omitempty"`
+	// Progress is the highest chapter index read
+	Progress  int       `json:"progress"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (u *Update) key() string {
+	return fmt.Sprintf("%s (%s)", strings.ToLower(u.MangaName), u.Integration)
+}
+
+func (u *Update) String() string {
+	return fmt.Sprintf("%s : %d → %s", u.MangaName, u.Progress, u.Integration)
+}
+
+// chapter restores the chapter to mark as read
+func (u *Update) chapter() *source.Chapter {
+	manga := &source.Manga{Name: u.MangaName}
+	chapter := &source.Chapter{Name: u.ChapterName, Index: uint16(u.Progress), Volume: u.Volume, Manga: manga}
+	manga.Chapters = []*source.Chapter{chapter}
+	return chapter
+}
+
+var cacher = gache.New[map[string]*Update](
+	&gache.Options{
+		Path:       where.Outbox(),
+		FileSystem: &filesystem.GacheFs{},
+	},
+)
+
+var (
+	// mutex guards read-modify-write of the outbox file
+	mutex sync.Mutex
+	// flushing makes sure that only one flush is running at a time
+	flushing sync.Mutex
+)
+
+func get() (map[string]*Update, error) {
+	cached, expired, err := cacher.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if expired || cached == nil {
+		return make(map[string]*Update), nil
+	}
+
+	return cached, nil
+}
+
+// Pending returns all pending updates sorted by manga name
+func Pending() ([]*Update, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	updates, err := get()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := lo.Values(updates)
+	slices.SortFunc(pending, func(a, b *Update) bool {
+		return a.key() < b.key()
+	})
+
+	return pending, nil
+}
+
+// Add adds an update for the chapter to the outbox.
+// Updates are coalesced per manga, only the highest progress is kept.
+func Add(integrator integration.Integrator, chapter *source.Chapter) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	updates, err := get()
+	if err != nil {
+		return err
+	}
+
+	update := &Update{
+		Integration: integrator.Name(),
+		MangaName:   chapter.Manga.Name,
+		ChapterName: chapter.Name,
+		Volume:      chapter.Volume,
+		Progress:    int(chapter.Index),
+		UpdatedAt:   time.Now(),
+	}
+
+	if existing, ok := updates[update.key()]; ok && existing.Progress >= update.Progress {
+		return nil
+	}
+
+	updates[update.key()] = update
+	return cacher.Set(updates)
+}
+
+// Result is the result of sending a single update
+type Result struct {
+	Update *Update
+	Err    error
+}
+
+var (
+	// background is closed when the background flush is done, nil if it is not running
+	background chan struct{}
+	// dirty is set when the flush is requested while the background one is running,
+	// so that it runs once more instead of starting another one
+	dirty           bool
+	backgroundMutex sync.Mutex
+)
+
+// FlushInBackground flushes the outbox without blocking the caller.
+// Requests made while the flush is running are merged into a single flush after it.
+// Wait should be called before exiting, so that the requests are not cut off.
+func FlushInBackground() {
+	backgroundMutex.Lock()
+	defer backgroundMutex.Unlock()
+
+	if background != nil {
+		dirty = true
+		return
+	}
+
+	done := make(chan struct{})
+	background = done
+
+	go func() {
+		defer close(done)
+
+		for {
+			if _, err := Flush(); err != nil {
+				log.Warn(err)
+			}
+
+			backgroundMutex.Lock()
+			again := dirty
+			dirty = false
+			if !again {
+				background = nil
+			}
+			backgroundMutex.Unlock()
+
+			if !again {
+				return
+			}
+		}
+	}()
+}
+
+// Wait waits for the background flush to finish, but no longer than the timeout.
+// It returns false if it did not finish in time. Unsent updates stay in the outbox.
+func Wait(timeout time.Duration) bool {
+	backgroundMutex.Lock()
+	done := background
+	backgroundMutex.Unlock()
+
+	if done == nil {
+		return true
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Flush sends all pending updates to the enabled integrations.
+// Sent updates are removed from the outbox, failed ones are kept for the next try.
+// Updates for disabled integrations are left untouched.
+func Flush() ([]*Result, error) {
+	flushing.Lock()
+	defer flushing.Unlock()
+
+	pending, err := Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	integrators := lo.SliceToMap(integration.Enabled(), func(i integration.Integrator) (string, integration.Integrator) {
+		return i.Name(), i
+	})
+
+	var results []*Result
+	for _, update := range pending {
+		integrator, ok := integrators[update.Integration]
+		if !ok {
+			continue
+		}
+
+		log.Infof("Sending %s", update)
+		results = append(results, &Result{
+			Update: update,
+			Err:    integrator.MarkRead(update.chapter()),
+		})
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	return results, done(results)
+}
+
+// done removes sent updates and records failures.
+// Updates that were superseded while sending are kept.
+func done(results []*Result) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	updates, err := get()
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		current, ok := updates[result.Update.key()]
+		if !ok || current.Progress != result.Update.Progress {
+			continue
+		}
+
+		if result.Err == nil {
+			delete(updates, result.Update.key())
+			continue
+		}
+
+		log.Warnf("Sending %s failed: %s", result.Update, result.Err)
+		current.Attempts++
+		current.LastError = result.Err.Error()
+	}
+
+	return cacher.Set(updates)
+}
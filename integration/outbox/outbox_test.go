@@ -0,0 +1,175 @@
+package outbox
+
+import (
+	"errors"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/integration"
+	"github.com/metafates/mangal/integration/tracker"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/mo"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"testing"
+	"time"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+}
+
+type testIntegrator struct {
+	err  error
+	sent []int
+}
+
+func (t *testIntegrator) Name() string {
+	return "test"
+}
+
+func (t *testIntegrator) MarkRead(chapter *source.Chapter) error {
+	if t.err != nil {
+		return t.err
+	}
+
+	t.sent = append(t.sent, int(chapter.Index))
+	return nil
+}
+
+func (t *testIntegrator) GetEntry(*source.Manga) (mo.Option[*tracker.Entry], error) {
+	return mo.None[*tracker.Entry](), nil
+}
+
+func (t *testIntegrator) SetProgress(*source.Manga, int) error {
+	return nil
+}
+
+func (t *testIntegrator) SetStatus(*source.Manga, tracker.Status) error {
+	return nil
+}
+
+func (t *testIntegrator) SetScore(*source.Manga, float64) error {
+	return nil
+}
+
+func (t *testIntegrator) SetDates(*source.Manga, tracker.Date, tracker.Date) error {
+	return nil
+}
+
+func chapterOf(manga string, index uint16) *source.Chapter {
+	return &source.Chapter{Index: index, Manga: &source.Manga{Name: manga}}
+}
+
+func TestOutbox(t *testing.T) {
+	Convey("Given an enabled integration that is offline", t, func() {
+		integrator := &testIntegrator{err: errors.New("offline")}
+
+		anilist := integration.Anilist
+		integration.Anilist = integrator
+		viper.Set(key.AnilistEnable, true)
+		defer func() {
+			integration.Anilist = anilist
+			viper.Set(key.AnilistEnable, false)
+		}()
+
+		So(cacher.Set(nil), ShouldBeNil)
+
+		Convey("When several chapters of the same manga are added", func() {
+			So(Add(integrator, chapterOf("manga", 3)), ShouldBeNil)
+			So(Add(integrator, chapterOf("manga", 5)), ShouldBeNil)
+			So(Add(integrator, chapterOf("Manga", 4)), ShouldBeNil)
+			So(Add(integrator, chapterOf("another", 1)), ShouldBeNil)
+
+			Convey("Then they should be coalesced to the highest progress", func() {
+				pending, err := Pending()
+				So(err, ShouldBeNil)
+				So(pending, ShouldHaveLength, 2)
+				So(pending[1].MangaName, ShouldEqual, "manga")
+				So(pending[1].Progress, ShouldEqual, 5)
+			})
+
+			Convey("And flushed while offline", func() {
+				results, err := Flush()
+				So(err, ShouldBeNil)
+				So(results, ShouldHaveLength, 2)
+
+				Convey("Then updates should be kept with the error", func() {
+					pending, err := Pending()
+					So(err, ShouldBeNil)
+					So(pending, ShouldHaveLength, 2)
+					So(pending[0].Attempts, ShouldEqual, 1)
+					So(pending[0].LastError, ShouldEqual, "offline")
+				})
+
+				Convey("And flushed again when online", func() {
+					integrator.err = nil
+					results, err := Flush()
+					So(err, ShouldBeNil)
+					So(results, ShouldHaveLength, 2)
+
+					Convey("Then only the highest progress should be sent", func() {
+						So(integrator.sent, ShouldResemble, []int{1, 5})
+
+						pending, err := Pending()
+						So(err, ShouldBeNil)
+						So(pending, ShouldBeEmpty)
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestWait(t *testing.T) {
+	Convey("Given a flush in the background", t, func() {
+		filesystem.SetMemMapFs()
+
+		Convey("When it is blocked by another flush", func() {
+			flushing.Lock()
+			FlushInBackground()
+
+			Convey("Then waiting should time out", func() {
+				So(Wait(time.Millisecond*20), ShouldBeFalse)
+
+				flushing.Unlock()
+				So(Wait(time.Second), ShouldBeTrue)
+			})
+		})
+
+		Convey("When it is requested many times while running", func() {
+			flushing.Lock()
+			FlushInBackground()
+
+			backgroundMutex.Lock()
+			running := background
+			backgroundMutex.Unlock()
+
+			for i := 0; i < 100; i++ {
+				FlushInBackground()
+			}
+
+			Convey("Then the requests should be merged into the running flush", func() {
+				backgroundMutex.Lock()
+				So(background, ShouldEqual, running)
+				So(dirty, ShouldBeTrue)
+				backgroundMutex.Unlock()
+
+				flushing.Unlock()
+				So(Wait(time.Second), ShouldBeTrue)
+
+				backgroundMutex.Lock()
+				So(background, ShouldBeNil)
+				So(dirty, ShouldBeFalse)
+				backgroundMutex.Unlock()
+			})
+		})
+
+		Convey("When it has nothing to send", func() {
+			FlushInBackground()
+
+			Convey("Then waiting should finish in time", func() {
+				So(Wait(time.Second), ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,85 @@
+package tracker
+
+import (
+	"fmt"
+	"github.com/samber/lo"
+	"strings"
+	"time"
+)
+
+// Status is the status of the manga in the user list
+type Status string
+
+const (
+	Current   Status = "CURRENT"
+	Planning  Status = "PLANNING"
+	Completed Status = "COMPLETED"
+	Dropped   Status = "DROPPED"
+	Paused    Status = "PAUSED"
+	Repeating Status = "REPEATING"
+)
+
+// Statuses is the list of all available statuses
+var Statuses = []Status{Current, Planning, Completed, Dropped, Paused, Repeating}
+
+// ParseStatus parses status from string, case-insensitive
+func ParseStatus(s string) (Status, error) {
+	status := Status(strings.ToUpper(strings.TrimSpace(s)))
+	if !lo.Contains(Statuses, status) {
+		return "", fmt.Errorf("unknown status %s, available: %v", s, Statuses)
+	}
+
+	return status, nil
+}
+
+// Date is a date that can be partially set, e.g. only the year.
+// Zero value means that the date is not set.
+type Date struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
+// DateOf converts time to date
+func DateOf(t time.Time) Date {
+	return Date{Year: t.Year(), Month: int(t.Month()), Day: t.Day()}
+}
+
+// ParseDate parses date in the YYYY-MM-DD format
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return Date{}, err
+	}
+
+	return DateOf(t), nil
+}
+
+// IsZero returns true if the date is not set
+func (d Date) IsZero() bool {
+	return d.Year == 0 && d.Month == 0 && d.Day == 0
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return "-"
+	}
+
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// Entry is the manga entry in the user list
+type Entry struct {
+	// MediaID is the id of the manga on the tracker
+	MediaID int `json:"media_id"`
+	// Progress is the amount of chapters read
+	Progress int `json:"progress"`
+	// Status is the reading status
+	Status Status `json:"status"`
+	// Score is the score given by the user, from 0 to 10
+	Score float64 `json:"score"`
+	// StartedAt is the date when user started reading the manga
+	StartedAt Date `json:"started_at"`
+	// CompletedAt is the date when user finished reading the manga
+	CompletedAt Date `json:"completed_at"`
+}
@@ -3,7 +3,7 @@ package key
 // DefinedFieldsCount is the number of fields defined in this package.
 // You have to manually update this number when you add a new field
 // to check later if every field has a defined default value
-const DefinedFieldsCount = 53
+const DefinedFieldsCount = 70
 
 const (
 	DownloaderPath                = "downloader.path"
@@ -40,6 +40,9 @@ const (
 	ReaderBrowser       = "reader.browser"
 	ReaderFolder        = "reader.folder"
 	ReaderReadInBrowser = "reader.read_in_browser"
+	ReaderBuiltin       = "reader.builtin"
+	ReaderBuiltinMode   = "reader.builtin_mode"
+	ReaderBuiltinPort   = "reader.builtin_port"
 )
 
 const (
@@ -71,6 +74,31 @@ const (
 	AnilistSecret            = "anilist.secret"
 	AnilistCode              = "anilist.code"
 	AnilistLinkOnMangaSelect = "anilist.link_on_manga_select"
+	AnilistRedirectPort      = "anilist.redirect_port"
+)
+
+const (
+	MyAnimeListEnable   = "myanimelist.enable"
+	MyAnimeListClientID = "myanimelist.client_id"
+)
+
+const (
+	ServerHost  = "server.host"
+	ServerPort  = "server.port"
+	ServerToken = "server.token"
+)
+
+const (
+	OPDSPort = "opds.port"
+)
+
+const (
+	LuaSandbox          = "lua.sandbox"
+	LuaTimeout          = "lua.timeout"
+	LuaInstructionLimit = "lua.instruction_limit"
+	LuaMemoryLimit      = "lua.memory_limit"
+	LuaStackLimit       = "lua.stack_limit"
+	LuaPoolSize         = "lua.pool_size"
 )
 
 const (
@@ -83,9 +111,10 @@ const (
 )
 
 const (
-	InstallerUser   = "installer.user"
-	InstallerRepo   = "installer.repo"
-	InstallerBranch = "installer.branch"
+	InstallerUser     = "installer.user"
+	InstallerRepo     = "installer.repo"
+	InstallerBranch   = "installer.branch"
+	InstallerManifest = "installer.manifest"
 )
 
 const (
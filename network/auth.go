@@ -0,0 +1,48 @@
+package network
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Authorized checks that the request carries the token.
+// The token is accepted from the bearer authorization header, the basic auth password
+// or the token query parameter. Any request is authorized if the token is empty.
+func Authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+
+	given := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		given = strings.TrimPrefix(header, "Bearer ")
+	} else if _, password, ok := r.BasicAuth(); ok {
+		given = password
+	}
+
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// AllowedHost checks that the request is addressed to the loopback or to the host the server listens on,
+// so that web pages can not reach the server by rebinding their domain to the local address.
+// Any host is allowed if the server listens on all interfaces.
+func AllowedHost(r *http.Request, listening string) bool {
+	if ip := net.ParseIP(listening); ip != nil && ip.IsUnspecified() {
+		return true
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") || strings.EqualFold(host, listening) {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
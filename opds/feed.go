@@ -0,0 +1,86 @@
+package opds
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const (
+	typeNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	typeAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	typeOpenSearch  = "application/opensearchdescription+xml"
+
+	relAcquisition = "http://opds-spec.org/acquisition"
+	relImage       = "http://opds-spec.org/image"
+	relThumbnail   = "http://opds-spec.org/image/thumbnail"
+	relStream      = "http://vaemendis.net/opds-pse/stream"
+)
+
+// feed is the OPDS 1.2 catalog feed
+type feed struct {
+	XMLName    xml.Name `xml:"feed"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	XmlnsOPDS  string   `xml:"xmlns:opds,attr"`
+	XmlnsPSE   string   `xml:"xmlns:pse,attr"`
+	ID         string   `xml:"id"`
+	Title      string   `xml:"title"`
+	Updated    string   `xml:"updated"`
+	AuthorName string   `xml:"author>name"`
+	Links      []*link  `xml:"link"`
+	Entries    []*entry `xml:"entry"`
+}
+
+type link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+	// Count is the number of pages for the streaming link
+	Count int `xml:"pse:count,attr,omitempty"`
+}
+
+type content struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type entry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Content *content `xml:"content,omitempty"`
+	Links   []*link  `xml:"link"`
+}
+
+func newFeed(id, title string, updated time.Time, self, kind string) *feed {
+	return &feed{
+		Xmlns:      "http://www.w3.org/2005/Atom",
+		XmlnsOPDS:  "http://opds-spec.org/2010/catalog",
+		XmlnsPSE:   "http://vaemendis.net/opds-pse/ns",
+		ID:         id,
+		Title:      title,
+		Updated:    timestamp(updated),
+		AuthorName: "mangal",
+		Links: []*link{
+			{Rel: "self", Href: self, Type: kind},
+			{Rel: "start", Href: rootPath, Type: typeNavigation},
+			{Rel: "search", Href: openSearchPath, Type: typeOpenSearch},
+		},
+	}
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// openSearch is the OpenSearch description document
+type openSearch struct {
+	XMLName     xml.Name `xml:"OpenSearchDescription"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	ShortName   string   `xml:"ShortName"`
+	Description string   `xml:"Description"`
+	URL         struct {
+		Type     string `xml:"type,attr"`
+		Template string `xml:"template,attr"`
+	} `xml:"Url"`
+}
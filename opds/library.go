@@ -0,0 +1,230 @@
+package opds
+
+import (
+	"encoding/json"
+	"github.com/metafates/mangal/converter/cbz"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"golang.org/x/exp/slices"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mimeTypes of the chapter files that can be acquired
+var mimeTypes = map[string]string{
+	".cbz":  "application/vnd.comicbook+zip",
+	".zip":  "application/zip",
+	".pdf":  "application/pdf",
+	".epub": "application/epub+zip",
+}
+
+// manga is a directory in the downloads
+type manga struct {
+	// Dir is the path of the directory relative to the root
+	Dir      string
+	Name     string
+	Summary  string
+	Cover    string
+	Updated  time.Time
+	Chapters []*chapter
+}
+
+// chapter is a downloaded chapter file
+type chapter struct {
+	// Path of the file relative to the root
+	Path    string
+	Name    string
+	Volume  string
+	Mime    string
+	Size    int64
+	Updated time.Time
+}
+
+// isChapter checks if the file is a chapter that can be acquired
+func isChapter(name string) bool {
+	_, ok := mimeTypes[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
+// scan returns mangas in the root directory.
+// Every directory with chapter files is a manga, volume subdirectories are included.
+func scan(root string) ([]*manga, error) {
+	entries, err := afero.ReadDir(filesystem.Api(), root)
+	if err != nil {
+		return nil, err
+	}
+
+	var mangas []*manga
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		m, err := scanManga(root, entry.Name())
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+
+		if len(m.Chapters) > 0 {
+			mangas = append(mangas, m)
+		}
+	}
+
+	slices.SortFunc(mangas, func(a, b *manga) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+
+	return mangas, nil
+}
+
+func scanManga(root, dir string) (*manga, error) {
+	m := &manga{Dir: dir, Name: dir}
+
+	err := afero.Walk(filesystem.Api(), filepath.Join(root, dir), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		name := info.Name()
+		switch {
+		case isChapter(name):
+			c := &chapter{
+				Path:    filepath.ToSlash(relative),
+				Name:    strings.TrimSuffix(name, filepath.Ext(name)),
+				Mime:    mimeTypes[strings.ToLower(filepath.Ext(name))],
+				Size:    info.Size(),
+				Updated: info.ModTime(),
+			}
+
+			// chapter is in the volume directory
+			if volume := filepath.Dir(relative); volume != dir {
+				c.Volume = filepath.Base(volume)
+			}
+
+			m.Chapters = append(m.Chapters, c)
+			if c.Updated.After(m.Updated) {
+				m.Updated = c.Updated
+			}
+		case strings.HasPrefix(name, "cover.") && cbz.IsImage(name) && filepath.Dir(relative) == dir:
+			m.Cover = filepath.ToSlash(relative)
+		case name == "series.json" && filepath.Dir(relative) == dir:
+			m.readSeriesJSON(path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(m.Chapters, func(a, b *chapter) bool {
+		return a.Path < b.Path
+	})
+
+	return m, nil
+}
+
+// readSeriesJSON takes the name and the summary of the manga from the series.json
+func (m *manga) readSeriesJSON(path string) {
+	contents, err := filesystem.Api().ReadFile(path)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+
+	var series source.SeriesJSON
+	if err = json.Unmarshal(contents, &series); err != nil {
+		log.Warn(err)
+		return
+	}
+
+	m.Name = lo.Ternary(series.Metadata.Name != "", series.Metadata.Name, m.Name)
+	m.Summary = series.Metadata.DescriptionText
+}
+
+// imageTypes of the pages that can be streamed
+var imageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+}
+
+// pageType returns the type of the page image by its name
+func pageType(name string) string {
+	if imageType, ok := imageTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return imageType
+	}
+
+	return "image/jpeg"
+}
+
+// archive is the list of pages of the chapter archive at the moment it was read
+type archive struct {
+	modified time.Time
+	size     int64
+	pages    []string
+}
+
+// archives caches the pages of the chapter archives, so that they are not opened on every request.
+// The archive is read again when its file is modified.
+type archives struct {
+	mutex sync.Mutex
+	read  map[string]*archive
+}
+
+func newArchives() *archives {
+	return &archives{read: make(map[string]*archive)}
+}
+
+// pages returns the names of the pages in the archive
+func (a *archives) pages(local string) ([]string, error) {
+	stat, err := filesystem.Api().Stat(local)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if cached, ok := a.read[local]; ok && cached.modified.Equal(stat.ModTime()) && cached.size == stat.Size() {
+		return cached.pages, nil
+	}
+
+	names, err := cbz.Pages(local)
+	if err != nil {
+		return nil, err
+	}
+
+	a.read[local] = &archive{modified: stat.ModTime(), size: stat.Size(), pages: names}
+	return names, nil
+}
+
+// pages returns the names of the pages of the chapter, if it can be streamed
+func (c *chapter) pages(root string, archives *archives) ([]string, bool) {
+	if ext := strings.ToLower(filepath.Ext(c.Path)); ext != ".cbz" && ext != ".zip" {
+		return nil, false
+	}
+
+	names, err := archives.pages(filepath.Join(root, filepath.FromSlash(c.Path)))
+	if err != nil {
+		log.Warn(err)
+		return nil, false
+	}
+
+	return names, true
+}
@@ -0,0 +1,287 @@
+// Package opds serves the downloaded chapters as the OPDS 1.2 catalog
+// with the OPDS Page Streaming Extension for the archives.
+package opds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/metafates/mangal/converter/cbz"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/network"
+	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rootPath       = "/opds"
+	openSearchPath = rootPath + "/opensearch.xml"
+	searchPath     = rootPath + "/search"
+	mangaPath      = rootPath + "/manga/"
+	filePath       = rootPath + "/file/"
+	pagesPath      = rootPath + "/pages/"
+)
+
+// Handler serves the catalog of the root directory
+type Handler struct {
+	root     string
+	token    string
+	mux      *http.ServeMux
+	archives *archives
+}
+
+// New creates a new catalog handler of the root directory.
+// If token is not empty, every request must be authorized with it.
+func New(root, token string) *Handler {
+	h := &Handler{
+		root:     root,
+		token:    token,
+		mux:      http.NewServeMux(),
+		archives: newArchives(),
+	}
+
+	h.mux.HandleFunc(rootPath, h.handleRoot)
+	h.mux.HandleFunc(openSearchPath, h.handleOpenSearch)
+	h.mux.HandleFunc(searchPath, h.handleSearch)
+	h.mux.HandleFunc(mangaPath, h.handleManga)
+	h.mux.HandleFunc(filePath, h.handleFile)
+	h.mux.HandleFunc(pagesPath, h.handlePage)
+	h.mux.Handle("/", http.RedirectHandler(rootPath, http.StatusFound))
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !network.Authorized(r, h.token) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="mangal"`)
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	log.Infof("%s %s", r.Method, r.URL.Path)
+	h.mux.ServeHTTP(w, r)
+}
+
+// escape escapes every segment of the slash separated path
+func escape(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// local returns the path on the filesystem for the path relative to the root.
+// The result is always inside the root.
+func (h *Handler) local(relative string) string {
+	return filepath.Join(h.root, filepath.FromSlash(path.Clean("/"+relative)))
+}
+
+func writeXML(w http.ResponseWriter, contentType string, value any) {
+	w.Header().Set("Content-Type", contentType+";charset=utf-8")
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		log.Warn(err)
+		return
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		log.Warn(err)
+	}
+}
+
+// mangaEntry is the navigation entry of the manga
+func mangaEntry(m *manga) *entry {
+	e := &entry{
+		ID:      "urn:mangal:manga:" + m.Dir,
+		Title:   m.Name,
+		Updated: timestamp(m.Updated),
+		Content: &content{
+			Type: "text",
+			Text: lo.Ternary(m.Summary != "", m.Summary, util.Quantify(len(m.Chapters), "chapter", "chapters")),
+		},
+		Links: []*link{{Rel: "subsection", Href: mangaPath + escape(m.Dir), Type: typeAcquisition}},
+	}
+
+	return withCover(e, m)
+}
+
+func withCover(e *entry, m *manga) *entry {
+	if m.Cover != "" {
+		coverType := mime.TypeByExtension(path.Ext(m.Cover))
+		e.Links = append(e.Links,
+			&link{Rel: relImage, Href: filePath + escape(m.Cover), Type: coverType},
+			&link{Rel: relThumbnail, Href: filePath + escape(m.Cover), Type: coverType},
+		)
+	}
+
+	return e
+}
+
+// navigation writes the navigation feed of the mangas
+func navigation(w http.ResponseWriter, id, title, self string, mangas []*manga) {
+	var updated time.Time
+	for _, m := range mangas {
+		if m.Updated.After(updated) {
+			updated = m.Updated
+		}
+	}
+
+	f := newFeed(id, title, updated, self, typeNavigation)
+	f.Entries = lo.Map(mangas, func(m *manga, _ int) *entry {
+		return mangaEntry(m)
+	})
+
+	writeXML(w, typeNavigation, f)
+}
+
+// GET /opds
+func (h *Handler) handleRoot(w http.ResponseWriter, r *http.Request) {
+	mangas, err := scan(h.root)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	navigation(w, "urn:mangal:root", "mangal", rootPath, mangas)
+}
+
+// GET /opds/search?q=<query>
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	mangas, err := scan(h.root)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	mangas = lo.Filter(mangas, func(m *manga, _ int) bool {
+		return strings.Contains(strings.ToLower(m.Name), query)
+	})
+
+	navigation(
+		w,
+		"urn:mangal:search:"+query,
+		fmt.Sprintf("Search results for %q", query),
+		searchPath+"?q="+url.QueryEscape(query),
+		mangas,
+	)
+}
+
+// GET /opds/opensearch.xml
+func (h *Handler) handleOpenSearch(w http.ResponseWriter, r *http.Request) {
+	var description openSearch
+	description.Xmlns = "http://a9.com/-/spec/opensearch/1.1/"
+	description.ShortName = "mangal"
+	description.Description = "Search downloaded manga"
+	description.URL.Type = typeNavigation
+	description.URL.Template = searchPath + "?q={searchTerms}"
+
+	writeXML(w, typeOpenSearch, &description)
+}
+
+// GET /opds/manga/<dir>
+func (h *Handler) handleManga(w http.ResponseWriter, r *http.Request) {
+	dir := path.Clean("/" + strings.TrimPrefix(r.URL.Path, mangaPath))[1:]
+	if dir == "" || strings.Contains(dir, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	m, err := scanManga(h.root, dir)
+	if err != nil || len(m.Chapters) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	f := newFeed("urn:mangal:manga:"+m.Dir, m.Name, m.Updated, mangaPath+escape(m.Dir), typeAcquisition)
+	f.Entries = lo.Map(m.Chapters, func(c *chapter, _ int) *entry {
+		e := &entry{
+			ID:      "urn:mangal:chapter:" + c.Path,
+			Title:   strings.TrimSpace(c.Volume + " " + c.Name),
+			Updated: timestamp(c.Updated),
+			Links: []*link{{
+				Rel:  relAcquisition,
+				Href: filePath + escape(c.Path),
+				Type: c.Mime,
+			}},
+		}
+
+		// pages are streamed as they are, so the width is not advertised
+		if pages, ok := c.pages(h.root, h.archives); ok && len(pages) > 0 {
+			e.Links = append(e.Links, &link{
+				Rel:   relStream,
+				Href:  pagesPath + escape(c.Path) + "?page={pageNumber}",
+				Type:  pageType(pages[0]),
+				Count: len(pages),
+			})
+		}
+
+		return withCover(e, m)
+	})
+
+	writeXML(w, typeAcquisition, f)
+}
+
+// GET /opds/file/<path>
+func (h *Handler) handleFile(w http.ResponseWriter, r *http.Request) {
+	local := h.local(strings.TrimPrefix(r.URL.Path, filePath))
+
+	file, err := filesystem.Api().Open(local)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	defer util.Ignore(file.Close)
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if mimeType, ok := mimeTypes[strings.ToLower(filepath.Ext(local))]; ok {
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": stat.Name()}))
+	}
+
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), file)
+}
+
+// GET /opds/pages/<path>?page=<index>, where the page index starts from 0
+func (h *Handler) handlePage(w http.ResponseWriter, r *http.Request) {
+	local := h.local(strings.TrimPrefix(r.URL.Path, pagesPath))
+
+	names, err := h.archives.pages(local)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 0 || page >= len(names) {
+		http.NotFound(w, r)
+		return
+	}
+
+	image, err := cbz.ReadPage(local, names[page])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(image))
+	_, _ = w.Write(image)
+}
@@ -0,0 +1,213 @@
+package opds
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"github.com/metafates/mangal/filesystem"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+}
+
+const testRoot = "/downloads"
+
+// writeArchive creates the zip archive with the pages named after themselves
+func writeArchive(path string, pages ...string) {
+	path = filepath.Join(testRoot, path)
+	So(filesystem.Api().MkdirAll(filepath.Dir(path), os.ModePerm), ShouldBeNil)
+
+	file, err := filesystem.Api().Create(path)
+	So(err, ShouldBeNil)
+
+	writer := zip.NewWriter(file)
+	for _, page := range pages {
+		w, err := writer.Create(page)
+		So(err, ShouldBeNil)
+		_, err = w.Write([]byte(page))
+		So(err, ShouldBeNil)
+	}
+
+	So(writer.Close(), ShouldBeNil)
+	So(file.Close(), ShouldBeNil)
+}
+
+// testLibrary creates the downloads directory with two mangas
+func testLibrary() {
+	So(filesystem.Api().RemoveAll(testRoot), ShouldBeNil)
+
+	write := func(path string, contents []byte) {
+		path = filepath.Join(testRoot, path)
+		So(filesystem.Api().MkdirAll(filepath.Dir(path), os.ModePerm), ShouldBeNil)
+		So(filesystem.Api().WriteFile(path, contents, os.ModePerm), ShouldBeNil)
+	}
+
+	writeArchive("One Piece/Vol. 1/Chapter 1.cbz", "0002.jpg", "0001.jpg", "ComicInfo.xml")
+	writeArchive("One Piece/Vol. 1/Chapter 2.cbz", "0001.png")
+	write("One Piece/cover.jpg", []byte("cover"))
+	write("One Piece/series.json", []byte(`{"metadata": {"name": "ONE PIECE", "description_text": "Pirates"}}`))
+	write("Berserk/Chapter 1.pdf", []byte("pdf"))
+	write("Empty/notes.txt", []byte("nothing"))
+}
+
+func get(handler http.Handler, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder
+}
+
+func getFeed(handler http.Handler, target string) *feed {
+	recorder := get(handler, target)
+	So(recorder.Code, ShouldEqual, http.StatusOK)
+
+	var f feed
+	So(xml.Unmarshal(recorder.Body.Bytes(), &f), ShouldBeNil)
+	return &f
+}
+
+func findLink(e *entry, rel string) *link {
+	for _, l := range e.Links {
+		if l.Rel == rel {
+			return l
+		}
+	}
+
+	return nil
+}
+
+func TestCatalog(t *testing.T) {
+	Convey("Given a catalog of the downloads", t, func() {
+		testLibrary()
+		handler := New(testRoot, "")
+
+		Convey("When getting the root feed", func() {
+			f := getFeed(handler, rootPath)
+
+			Convey("Then mangas with chapters should be listed by name", func() {
+				So(f.Entries, ShouldHaveLength, 2)
+				So(f.Entries[0].Title, ShouldEqual, "Berserk")
+				So(f.Entries[1].Title, ShouldEqual, "ONE PIECE")
+				So(f.Entries[1].Content.Text, ShouldEqual, "Pirates")
+			})
+
+			Convey("Then the manga should link to its chapters and cover", func() {
+				So(findLink(f.Entries[1], "subsection").Href, ShouldEqual, "/opds/manga/One%20Piece")
+				So(findLink(f.Entries[1], relImage).Href, ShouldEqual, "/opds/file/One%20Piece/cover.jpg")
+				So(findLink(f.Entries[0], relImage), ShouldBeNil)
+			})
+		})
+
+		Convey("When getting the manga feed", func() {
+			f := getFeed(handler, "/opds/manga/One%20Piece")
+
+			Convey("Then chapters should be acquirable", func() {
+				So(f.Entries, ShouldHaveLength, 2)
+				So(f.Entries[0].Title, ShouldEqual, "Vol. 1 Chapter 1")
+
+				acquisition := findLink(f.Entries[0], relAcquisition)
+				So(acquisition.Type, ShouldEqual, "application/vnd.comicbook+zip")
+				So(acquisition.Href, ShouldEqual, "/opds/file/One%20Piece/Vol.%201/Chapter%201.cbz")
+			})
+
+			Convey("Then archives should be streamable", func() {
+				stream := findLink(f.Entries[0], relStream)
+				So(stream, ShouldNotBeNil)
+				So(stream.Href, ShouldEndWith, "?page={pageNumber}")
+				So(stream.Type, ShouldEqual, "image/jpeg")
+				So(findLink(f.Entries[1], relStream).Type, ShouldEqual, "image/png")
+			})
+
+			Convey("Then the page count should be written", func() {
+				body := get(handler, "/opds/manga/One%20Piece").Body.String()
+				So(body, ShouldContainSubstring, `pse:count="2"`)
+			})
+		})
+
+		Convey("When the manga has pdf chapters", func() {
+			f := getFeed(handler, "/opds/manga/Berserk")
+
+			Convey("Then they should not be streamable", func() {
+				So(findLink(f.Entries[0], relAcquisition).Type, ShouldEqual, "application/pdf")
+				So(findLink(f.Entries[0], relStream), ShouldBeNil)
+			})
+		})
+
+		Convey("When streaming pages", func() {
+			Convey("Then they should be in order starting from 0", func() {
+				So(get(handler, "/opds/pages/One%20Piece/Vol.%201/Chapter%201.cbz?page=0").Body.String(), ShouldEqual, "0001.jpg")
+				So(get(handler, "/opds/pages/One%20Piece/Vol.%201/Chapter%201.cbz?page=1").Body.String(), ShouldEqual, "0002.jpg")
+				So(get(handler, "/opds/pages/One%20Piece/Vol.%201/Chapter%201.cbz?page=2").Code, ShouldEqual, http.StatusNotFound)
+			})
+
+			Convey("And the archive is replaced", func() {
+				writeArchive("One Piece/Vol. 1/Chapter 1.cbz", "0001.jpg", "0002.jpg", "0003.jpg")
+
+				Convey("Then the new pages should be served", func() {
+					So(get(handler, "/opds/pages/One%20Piece/Vol.%201/Chapter%201.cbz?page=2").Body.String(), ShouldEqual, "0003.jpg")
+				})
+			})
+		})
+
+		Convey("When downloading a chapter", func() {
+			recorder := get(handler, "/opds/file/Berserk/Chapter%201.pdf")
+
+			Convey("Then the file should be served with its mime type", func() {
+				So(recorder.Code, ShouldEqual, http.StatusOK)
+				So(recorder.Header().Get("Content-Type"), ShouldEqual, "application/pdf")
+				So(recorder.Body.String(), ShouldEqual, "pdf")
+			})
+		})
+
+		Convey("When requesting a file outside of the root", func() {
+			write := filesystem.Api().WriteFile("/secret.txt", []byte("secret"), os.ModePerm)
+			So(write, ShouldBeNil)
+
+			// call the handler directly, because the mux would redirect to the cleaned path
+			recorder := httptest.NewRecorder()
+			handler.handleFile(recorder, httptest.NewRequest(http.MethodGet, "/opds/file/../../secret.txt", nil))
+
+			Convey("Then it should not be served", func() {
+				So(recorder.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
+		Convey("When searching", func() {
+			f := getFeed(handler, "/opds/search?q=piece")
+
+			Convey("Then only matching mangas should be listed", func() {
+				So(f.Entries, ShouldHaveLength, 1)
+				So(f.Entries[0].Title, ShouldEqual, "ONE PIECE")
+			})
+		})
+
+		Convey("When getting the OpenSearch description", func() {
+			body := get(handler, openSearchPath).Body.String()
+
+			Convey("Then it should have the search template", func() {
+				So(body, ShouldContainSubstring, `template="/opds/search?q={searchTerms}"`)
+			})
+		})
+
+		Convey("When the token is required", func() {
+			handler = New(testRoot, "secret")
+
+			Convey("Then basic auth password should be accepted", func() {
+				So(get(handler, rootPath).Code, ShouldEqual, http.StatusUnauthorized)
+
+				request := httptest.NewRequest(http.MethodGet, rootPath, nil)
+				request.SetBasicAuth("reader", "secret")
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+				So(recorder.Code, ShouldEqual, http.StatusOK)
+				So(strings.HasPrefix(recorder.Header().Get("Content-Type"), typeNavigation), ShouldBeTrue)
+			})
+		})
+	})
+}
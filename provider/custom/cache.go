@@ -7,10 +7,14 @@ import (
 	"github.com/metafates/mangal/where"
 	"github.com/samber/mo"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// cacher stores values in the cache file, nil cacher stores nothing.
+// It is safe for concurrent use by the calls from different states.
 type cacher[T any] struct {
+	mutex    sync.Mutex
 	internal *gache.Cache[map[string]T]
 }
 
@@ -25,6 +29,13 @@ func newCacher[T any](name string) *cacher[T] {
 }
 
 func (c *cacher[T]) Get(key string) mo.Option[T] {
+	if c == nil {
+		return mo.None[T]()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	data, expired, err := c.internal.Get()
 	if err != nil || expired || data == nil {
 		return mo.None[T]()
@@ -38,6 +49,13 @@ func (c *cacher[T]) Get(key string) mo.Option[T] {
 }
 
 func (c *cacher[T]) Set(key string, t T) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	data, expired, err := c.internal.Get()
 
 	if err != nil {
@@ -7,7 +7,9 @@ import (
 	"strconv"
 )
 
-func (s *luaSource) ChaptersOf(manga *source.Manga) ([]*source.Chapter, error) {
+func (s *luaSource) ChaptersOf(manga *source.Manga) (_ []*source.Chapter, err error) {
+	defer recoverLua(&err)
+
 	if chapters := s.cache.chapters.Get(manga.URL); chapters.IsPresent() {
 		c := chapters.MustGet()
 		for _, chapter := range c {
@@ -17,37 +19,37 @@ func (s *luaSource) ChaptersOf(manga *source.Manga) ([]*source.Chapter, error) {
 		return c, nil
 	}
 
-	_, err := s.call(constant.MangaChaptersFn, lua.LTTable, lua.LString(manga.URL))
-
-	if err != nil {
-		return nil, err
-	}
-
-	table := s.state.CheckTable(-1)
 	chapters := make([]*source.Chapter, 0)
+	err = s.call(constant.MangaChaptersFn, func(state *lua.LState, value lua.LValue) error {
+		checkTable(state, constant.MangaChaptersFn, value).ForEach(func(k lua.LValue, v lua.LValue) {
+			if k.Type() != lua.LTNumber {
+				state.RaiseError(constant.MangaChaptersFn + " was expected to return a table with numbers as keys, got " + k.Type().String() + " as a key")
+			}
 
-	table.ForEach(func(k lua.LValue, v lua.LValue) {
-		if k.Type() != lua.LTNumber {
-			s.state.RaiseError(constant.MangaChaptersFn + " was expected to return a table with numbers as keys, got " + k.Type().String() + " as a key")
-		}
+			if v.Type() != lua.LTTable {
+				state.RaiseError(constant.MangaChaptersFn + " was expected to return a table with tables as values, got " + v.Type().String() + " as a value")
+			}
 
-		if v.Type() != lua.LTTable {
-			s.state.RaiseError(constant.MangaChaptersFn + " was expected to return a table with tables as values, got " + v.Type().String() + " as a value")
-		}
+			index, err := strconv.ParseUint(k.String(), 10, 16)
+			if err != nil {
+				state.RaiseError(constant.MangaChaptersFn + " was expected to return a table with unsigned integers as keys. " + err.Error())
+			}
 
-		index, err := strconv.ParseUint(k.String(), 10, 16)
-		if err != nil {
-			s.state.RaiseError(constant.MangaChaptersFn + " was expected to return a table with unsigned integers as keys. " + err.Error())
-		}
+			chapter, err := chapterFromTable(v.(*lua.LTable), manga, uint16(index))
 
-		chapter, err := chapterFromTable(v.(*lua.LTable), manga, uint16(index))
+			if err != nil {
+				state.RaiseError("%s: chapter #%s: %s", constant.MangaChaptersFn, k.String(), err)
+			}
 
-		if err != nil {
-			s.state.RaiseError(err.Error())
-		}
+			chapters = append(chapters, chapter)
+		})
+
+		return nil
+	}, lua.LString(manga.URL))
 
-		chapters = append(chapters, chapter)
-	})
+	if err != nil {
+		return nil, err
+	}
 
 	_ = s.cache.chapters.Set(manga.URL, chapters)
 	return chapters, nil
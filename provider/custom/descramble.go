@@ -0,0 +1,45 @@
+package custom
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/source"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Descramble calls DescramblePage of the source, if defined, with the downloaded image and the page.
+// The function may return new contents of the image, tiles to reassemble it or nil to keep it as is
+func (s *luaSource) Descramble(page *source.Page) error {
+	if !s.descramblable {
+		return nil
+	}
+
+	// the table is created without the state, since the state is taken from the pool by the call
+	info := &lua.LTable{Metatable: lua.LNil}
+	info.RawSetString("url", lua.LString(page.URL))
+	info.RawSetString("index", lua.LNumber(page.Index))
+	info.RawSetString("extension", lua.LString(page.Extension))
+	info.RawSetString("chapter", lua.LString(page.Chapter.URL))
+
+	return s.call(constant.DescramblePageFn, func(_ *lua.LState, value lua.LValue) error {
+		switch value := value.(type) {
+		case *lua.LNilType:
+			return nil
+		case lua.LString:
+			page.Contents = bytes.NewBufferString(string(value))
+			page.Size = uint64(len(value))
+			return nil
+		case *lua.LTable:
+			tiles, err := tilesFromTable(value)
+			if err != nil {
+				return fmt.Errorf("%s: page #%d: %w", constant.DescramblePageFn, page.Index, err)
+			}
+
+			page.Tiles = tiles
+			return nil
+		default:
+			return fmt.Errorf("%s was expected to return a string, a table or nil, got %s", constant.DescramblePageFn, value.Type())
+		}
+	}, lua.LString(page.Contents.String()), info)
+}
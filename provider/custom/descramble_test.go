@@ -0,0 +1,104 @@
+package custom
+
+import (
+	"bytes"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"os"
+	"testing"
+)
+
+const descrambleFunctions = `
+function SearchManga(query)
+	return {}
+end
+
+function MangaChapters(mangaURL)
+	return {}
+end
+
+function ChapterPages(chapterURL)
+	return {}
+end
+
+function DescramblePage(image, page)
+	if page.index == 1 then
+		return { columns = 2, rows = 1, order = { 2, 1 } }
+	elseif page.index == 2 then
+		return string.reverse(image) .. page.chapter
+	elseif page.index == 3 then
+		return { columns = 2, rows = 1, order = { 1, 1 } }
+	end
+
+	return nil
+end
+`
+
+func TestDescramble(t *testing.T) {
+	Convey("Given a custom source that defines DescramblePage", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.LuaSandbox, false)
+
+		path := "/sources/descramble.lua"
+		So(filesystem.Api().WriteFile(path, []byte(descrambleFunctions), os.ModePerm), ShouldBeNil)
+
+		src, err := LoadSourceWith(path, Options{Validate: true, NoCache: true})
+		So(err, ShouldBeNil)
+
+		page := func(index uint16) *source.Page {
+			return &source.Page{
+				Index:    index,
+				Contents: bytes.NewBufferString("image"),
+				Chapter:  &source.Chapter{URL: "/chapter", Manga: &source.Manga{Source: src}},
+			}
+		}
+
+		descrambler, ok := src.(source.Descrambler)
+		So(ok, ShouldBeTrue)
+
+		Convey("When it returns the tiles", func() {
+			p := page(1)
+			err := descrambler.Descramble(p)
+
+			Convey("Then they should be set on the page", func() {
+				So(err, ShouldBeNil)
+				So(p.Tiles, ShouldResemble, &source.Tiles{Columns: 2, Rows: 1, Order: []int{2, 1}})
+			})
+		})
+
+		Convey("When it returns the new contents", func() {
+			p := page(2)
+			err := descrambler.Descramble(p)
+
+			Convey("Then they should replace the page contents", func() {
+				So(err, ShouldBeNil)
+				So(p.Contents.String(), ShouldEqual, "egami/chapter")
+				So(p.Size, ShouldEqual, len("egami/chapter"))
+				So(p.Tiles, ShouldBeNil)
+			})
+		})
+
+		Convey("When it returns invalid tiles", func() {
+			err := descrambler.Descramble(page(3))
+
+			Convey("Then it should fail with the page index", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "DescramblePage: page #3")
+			})
+		})
+
+		Convey("When it returns nil", func() {
+			p := page(4)
+			err := descrambler.Descramble(p)
+
+			Convey("Then the page should be kept as is", func() {
+				So(err, ShouldBeNil)
+				So(p.Contents.String(), ShouldEqual, "image")
+				So(p.Tiles, ShouldBeNil)
+			})
+		})
+	})
+}
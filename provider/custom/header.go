@@ -0,0 +1,185 @@
+package custom
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/util"
+	"github.com/metafates/mangal/version"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Modules are the names of mangal-lua-libs modules that scrapers can require
+var Modules = []string{
+	"base64", "crypto", "filepath", "goos", "headless", "html", "http", "http_client", "http_util",
+	"humanize", "inspect", "ioutil", "json", "log", "regexp", "runtime", "shellescape", "stats",
+	"storage", "strings", "template", "time", "xmlpath", "yaml",
+}
+
+// Header is the metadata of the scraper from the comments at the top of its file:
+//
+//	-- @name     Example
+//	-- @version  1.0.0
+//	-- @url      https://example.com
+//	-- @author   Someone
+//	-- @license  MIT
+//	-- @lang     en, ja
+//	-- @nsfw     false
+//	-- @require  html, http
+//	-- @mangal   4.0.6
+//	-- @allow    network example.com
+//	-- @concurrent false
+type Header struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// URL of the website that the scraper is for
+	URL     string `json:"url"`
+	Author  string `json:"author"`
+	License string `json:"license"`
+	// Languages of the manga, e.g. "en"
+	Languages []string `json:"languages"`
+	NSFW      bool     `json:"nsfw"`
+	// Requires are the modules that the scraper requires
+	Requires []string `json:"requires"`
+	// MinVersion is the minimal version of mangal that the scraper works with
+	MinVersion string `json:"min_version"`
+	// Permissions that the scraper declares with @allow
+	Permissions Permissions `json:"permissions"`
+	// Concurrent is true if the functions of the scraper can run in several Lua states at once, see key.LuaPoolSize.
+	// Scrapers that keep state in globals between calls opt out with "-- @concurrent false"
+	Concurrent bool `json:"concurrent"`
+}
+
+var headerTagRegex = regexp.MustCompile(`^--\s*@(\w+)\s*(.*)$`)
+
+// list splits the comma or space separated values
+func list(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// first sets the field to the value unless it is already set
+func first(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// enabled checks the value of the boolean tag, the tag without value is enabled
+func enabled(value string) bool {
+	return value == "" || lo.Contains([]string{"true", "yes", "1"}, strings.ToLower(value))
+}
+
+// ParseHeader reads tags from the comments at the top of the scraper.
+// The header ends with the first line of code, the first value of the single tags wins.
+func ParseHeader(path string) (*Header, error) {
+	file, err := filesystem.Api().Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer util.Ignore(file.Close)
+
+	h := Header{Concurrent: true}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		groups := headerTagRegex.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+
+		tag, value := groups[1], strings.TrimSpace(groups[2])
+		switch tag {
+		case "name":
+			first(&h.Name, value)
+		case "version":
+			first(&h.Version, value)
+		case "url":
+			first(&h.URL, value)
+		case "author":
+			first(&h.Author, value)
+		case "license":
+			first(&h.License, value)
+		case "lang", "language", "languages":
+			h.Languages = append(h.Languages, list(value)...)
+		case "nsfw":
+			h.NSFW = enabled(value)
+		case "require", "requires":
+			h.Requires = append(h.Requires, list(value)...)
+		case "concurrent":
+			h.Concurrent = enabled(value)
+		case "mangal":
+			h.MinVersion = strings.TrimSpace(strings.TrimPrefix(value, ">="))
+		case "allow":
+			if err = h.Permissions.allow(strings.Fields(value)); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// the source host is always allowed, so that scrapers without @allow lines keep working
+	if parsed, err := url.Parse(h.URL); err == nil && parsed.Host != "" {
+		h.Permissions.Hosts = append(h.Permissions.Hosts, strings.ToLower(parsed.Hostname()))
+	}
+
+	return &h, nil
+}
+
+// UsesHeadless returns true if the scraper requires or is allowed to use the headless browser
+func (h *Header) UsesHeadless() bool {
+	return h.Permissions.Headless || lo.Contains(h.Requires, "headless")
+}
+
+// Compatible returns an error if the scraper can not work with this version of mangal,
+// its required modules are not available or not permitted in the sandbox.
+func (h *Header) Compatible() error {
+	if h.MinVersion != "" {
+		cmp, err := version.Compare(constant.Version, h.MinVersion)
+		if err != nil {
+			return fmt.Errorf("invalid @mangal version %q", h.MinVersion)
+		}
+
+		if cmp < 0 {
+			return fmt.Errorf("requires mangal %s or newer, current version is %s", h.MinVersion, constant.Version)
+		}
+	}
+
+	for _, module := range h.Requires {
+		if !lo.Contains(Modules, module) {
+			return fmt.Errorf("requires module %q which is not available", module)
+		}
+	}
+
+	if viper.GetBool(key.LuaSandbox) {
+		for _, module := range h.Requires {
+			switch {
+			case module == "headless" && !h.Permissions.Headless:
+				return fmt.Errorf("requires module %q without \"-- @allow %s\"", module, PermissionHeadless)
+			case lo.Contains(filesystemModules, module) && !h.Permissions.Filesystem:
+				return fmt.Errorf("requires module %q without \"-- @allow %s\"", module, PermissionFilesystem)
+			}
+		}
+	}
+
+	return nil
+}
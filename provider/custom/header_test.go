@@ -0,0 +1,112 @@
+package custom
+
+import (
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"os"
+	"testing"
+)
+
+const testHeader = `--------------------------------
+-- @name     Example
+-- @version  1.2.0
+-- @url      https://example.com/manga
+-- @author   Someone
+-- @license  MIT
+-- @lang     en, ja
+-- @nsfw
+-- @require  html
+-- @require  headless, json
+-- @mangal   >= 4.0.0
+-- @allow    headless
+-- @concurrent false
+--------------------------------
+
+-- @name Ignored
+`
+
+func writeTestScraper(name, content string) string {
+	path := "/sources/" + name + ".lua"
+	So(filesystem.Api().WriteFile(path, []byte(content), os.ModePerm), ShouldBeNil)
+	return path
+}
+
+func TestParseHeader(t *testing.T) {
+	Convey("Given the scraper with a header", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.LuaSandbox, true)
+		path := writeTestScraper("example", testHeader)
+
+		Convey("When the header is parsed", func() {
+			header, err := ParseHeader(path)
+
+			Convey("Then all tags should be read until the first line of code", func() {
+				So(err, ShouldBeNil)
+				So(header.Name, ShouldEqual, "Example")
+				So(header.Version, ShouldEqual, "1.2.0")
+				So(header.URL, ShouldEqual, "https://example.com/manga")
+				So(header.Author, ShouldEqual, "Someone")
+				So(header.License, ShouldEqual, "MIT")
+				So(header.Languages, ShouldResemble, []string{"en", "ja"})
+				So(header.NSFW, ShouldBeTrue)
+				So(header.Concurrent, ShouldBeFalse)
+				So(header.Requires, ShouldResemble, []string{"html", "headless", "json"})
+				So(header.MinVersion, ShouldEqual, "4.0.0")
+				So(header.Permissions.Hosts, ShouldResemble, []string{"example.com"})
+				So(header.UsesHeadless(), ShouldBeTrue)
+			})
+
+			Convey("Then it should be compatible", func() {
+				So(header.Compatible(), ShouldBeNil)
+			})
+		})
+
+		Convey("When the scraper requires a newer mangal", func() {
+			path := writeTestScraper("newer", "-- @mangal 999.0.0\n")
+			header, err := ParseHeader(path)
+			So(err, ShouldBeNil)
+
+			Convey("Then it should be concurrent by default", func() {
+				So(header.Concurrent, ShouldBeTrue)
+			})
+
+			Convey("Then it should be incompatible", func() {
+				So(header.Compatible(), ShouldNotBeNil)
+				So(header.Compatible().Error(), ShouldContainSubstring, constant.Version)
+			})
+
+			Convey("Then it should not be loaded", func() {
+				_, err := LoadSource(path, true)
+				So(err, ShouldNotBeNil)
+				So(errs.KindOf(err), ShouldEqual, errs.Lua)
+			})
+		})
+
+		Convey("When the scraper requires an unknown module", func() {
+			header, err := ParseHeader(writeTestScraper("unknown", "-- @require nope\n"))
+			So(err, ShouldBeNil)
+
+			Convey("Then it should be incompatible", func() {
+				So(header.Compatible(), ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the scraper requires a module without the permission", func() {
+			header, err := ParseHeader(writeTestScraper("denied", "-- @require ioutil\n"))
+			So(err, ShouldBeNil)
+
+			Convey("Then it should be incompatible in the sandbox", func() {
+				So(header.Compatible(), ShouldNotBeNil)
+			})
+
+			Convey("Then it should be compatible without the sandbox", func() {
+				viper.Set(key.LuaSandbox, false)
+				So(header.Compatible(), ShouldBeNil)
+			})
+		})
+	})
+}
@@ -1,52 +1,108 @@
 package custom
 
 import (
-	"fmt"
-	libs "github.com/metafates/mangal-lua-libs"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/source"
 	"github.com/metafates/mangal/util"
+	"github.com/spf13/viper"
 	lua "github.com/yuin/gopher-lua"
 	"github.com/yuin/gopher-lua/parse"
+	"net/http"
 )
 
 func IDfromName(name string) string {
 	return name + " custom"
 }
 
+// Options of loading the source
+type Options struct {
+	// Validate checks that the required functions are defined
+	Validate bool
+	// NoCache disables caching of the search results and chapters
+	NoCache bool
+	// Transport wraps the transports of the http clients created by the scraper, e.g. to record requests
+	Transport func(http.RoundTripper) http.RoundTripper
+}
+
 func LoadSource(path string, validate bool) (source.Source, error) {
+	return LoadSourceWith(path, Options{Validate: validate})
+}
+
+// LoadSourceWith loads the source with the options
+func LoadSourceWith(path string, options Options) (source.Source, error) {
 	proto, err := Compile(path)
 	if err != nil {
-		return nil, err
+		return nil, errs.New(errs.Lua, err)
 	}
 
-	state := lua.NewState()
-	libs.Preload(state)
+	header, err := ParseHeader(path)
+	if err != nil {
+		return nil, errs.New(errs.Lua, err)
+	}
 
-	lfunc := state.NewFunctionFromProto(proto)
-	state.Push(lfunc)
-	err = state.PCall(0, lua.MultRet, nil)
+	if err = header.Compatible(); err != nil {
+		return nil, errs.Newf(errs.Lua, "%s: %s", util.FileStem(path), err)
+	}
+
+	// the chunk is compiled once and every state of the pool runs it
+	create := func() (*lua.LState, error) {
+		state := newState(&header.Permissions, options.Transport)
+		state.Push(state.NewFunctionFromProto(proto))
+
+		removeLimits := limitCall(state)
+		err := state.PCall(0, lua.MultRet, nil)
+		removeLimits()
+		if err != nil {
+			state.Close()
+			return nil, errs.New(errs.Lua, err)
+		}
+
+		return state, nil
+	}
+
+	// scrapers that keep state in globals between calls opt out of the pool
+	size := 1
+	if header.Concurrent {
+		size = viper.GetInt(key.LuaPoolSize)
+	}
+
+	pool := newStatePool(size, create)
+	state, err := pool.get()
 	if err != nil {
 		return nil, err
 	}
 
+	defer pool.put(state)
+
 	name := util.FileStem(path)
 
-	if validate {
+	if options.Validate {
 		for _, fn := range mustHave {
 			defined := state.GetGlobal(fn)
 
 			if defined.Type() != lua.LTFunction {
-				return nil, fmt.Errorf("required function %s is not defined in the luaSource %s", fn, name)
+				pool.close()
+				return nil, errs.Newf(errs.Lua, "required function %s is not defined in the luaSource %s", fn, name)
 			}
 		}
 	}
 
-	luaSource, err := newLuaSource(name, state)
+	luaSource, err := newLuaSource(name, pool)
 	if err != nil {
 		return nil, err
 	}
 
+	luaSource.resolvable = state.GetGlobal(constant.MangaFromURLFn).Type() == lua.LTFunction
+	luaSource.descramblable = state.GetGlobal(constant.DescramblePageFn).Type() == lua.LTFunction
+
+	if options.NoCache {
+		luaSource.cache.mangas, luaSource.cache.chapters = nil, nil
+	}
+
+	luaSource.url = header.URL
 	return luaSource, nil
 }
 
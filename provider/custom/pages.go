@@ -6,33 +6,35 @@ import (
 	lua "github.com/yuin/gopher-lua"
 )
 
-func (s *luaSource) PagesOf(chapter *source.Chapter) ([]*source.Page, error) {
-	_, err := s.call(constant.ChapterPagesFn, lua.LTTable, lua.LString(chapter.URL))
+func (s *luaSource) PagesOf(chapter *source.Chapter) (_ []*source.Page, err error) {
+	defer recoverLua(&err)
 
-	if err != nil {
-		return nil, err
-	}
-
-	table := s.state.CheckTable(-1)
 	pages := make([]*source.Page, 0)
+	err = s.call(constant.ChapterPagesFn, func(state *lua.LState, value lua.LValue) error {
+		checkTable(state, constant.ChapterPagesFn, value).ForEach(func(k lua.LValue, v lua.LValue) {
+			if k.Type() != lua.LTNumber {
+				state.RaiseError(constant.ChapterPagesFn + " was expected to return a table with numbers as keys, got " + k.Type().String() + " as a key")
+			}
 
-	table.ForEach(func(k lua.LValue, v lua.LValue) {
-		if k.Type() != lua.LTNumber {
-			s.state.RaiseError(constant.ChapterPagesFn + " was expected to return a table with numbers as keys, got " + k.Type().String() + " as a key")
-		}
+			if v.Type() != lua.LTTable {
+				state.RaiseError(constant.ChapterPagesFn + " was expected to return a table with tables as values, got " + v.Type().String() + " as a value")
+			}
 
-		if v.Type() != lua.LTTable {
-			s.state.RaiseError(constant.ChapterPagesFn + " was expected to return a table with tables as values, got " + v.Type().String() + " as a value")
-		}
+			page, err := pageFromTable(v.(*lua.LTable), chapter)
 
-		page, err := pageFromTable(v.(*lua.LTable), chapter)
+			if err != nil {
+				state.RaiseError("%s: page #%s: %s", constant.ChapterPagesFn, k.String(), err)
+			}
 
-		if err != nil {
-			s.state.RaiseError(err.Error())
-		}
+			pages = append(pages, page)
+		})
 
-		pages = append(pages, page)
-	})
+		return nil
+	}, lua.LString(chapter.URL))
+
+	if err != nil {
+		return nil, err
+	}
 
 	return pages, nil
 }
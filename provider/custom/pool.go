@@ -0,0 +1,105 @@
+package custom
+
+import (
+	"errors"
+	lua "github.com/yuin/gopher-lua"
+	"sync"
+)
+
+// errPoolClosed is returned when the source is called after it was closed
+var errPoolClosed = errors.New("source is closed")
+
+// statePool keeps Lua states with the loaded scraper, so that its functions can be called concurrently.
+// Lua state is not safe for concurrent use, each state runs one call at a time.
+type statePool struct {
+	create func() (*lua.LState, error)
+	// states are the idle states
+	states chan *lua.LState
+	// slots limits the number of the created states
+	slots  chan struct{}
+	mutex  sync.Mutex
+	closed bool
+}
+
+func newStatePool(size int, create func() (*lua.LState, error)) *statePool {
+	if size < 1 {
+		size = 1
+	}
+
+	return &statePool{
+		create: create,
+		states: make(chan *lua.LState, size),
+		slots:  make(chan struct{}, size),
+	}
+}
+
+// get returns an idle state or creates a new one.
+// If the pool is full, it waits for the state to be returned.
+func (p *statePool) get() (*lua.LState, error) {
+	if p.isClosed() {
+		return nil, errPoolClosed
+	}
+
+	select {
+	case state := <-p.states:
+		return state, nil
+	default:
+	}
+
+	select {
+	case state := <-p.states:
+		return state, nil
+	case p.slots <- struct{}{}:
+		state, err := p.create()
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+
+		return state, nil
+	}
+}
+
+// put returns the state to the pool.
+// The state is closed if the pool was closed while it was in use.
+func (p *statePool) put(state *lua.LState) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		p.discard(state)
+		return
+	}
+
+	p.states <- state
+}
+
+// discard closes the state that should not be reused, e.g. after a failed call,
+// so that the next call gets a fresh one
+func (p *statePool) discard(state *lua.LState) {
+	state.Close()
+	<-p.slots
+}
+
+// close closes the idle states, states that are in use are closed when they are returned
+func (p *statePool) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+	for {
+		select {
+		case state := <-p.states:
+			p.discard(state)
+		default:
+			return
+		}
+	}
+}
+
+func (p *statePool) isClosed() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.closed
+}
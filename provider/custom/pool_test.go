@@ -0,0 +1,198 @@
+package custom
+
+import (
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+const poolFunctions = `
+function SearchManga(query)
+	local sum = 0
+	for i = 1, 10000 do
+		sum = sum + i
+	end
+
+	if query == "fail" then
+		error("failed")
+	end
+
+	return { { name = query, url = "https://example.com/" .. query } }
+end
+
+function MangaChapters(mangaURL)
+	return {}
+end
+
+function ChapterPages(chapterURL)
+	return {}
+end
+`
+
+func TestStatePool(t *testing.T) {
+	Convey("Given a pool of two states", t, func() {
+		var created int
+		pool := newStatePool(2, func() (*lua.LState, error) {
+			created++
+			return lua.NewState(), nil
+		})
+
+		Convey("When states are taken and returned", func() {
+			first, err := pool.get()
+			So(err, ShouldBeNil)
+			second, err := pool.get()
+			So(err, ShouldBeNil)
+			pool.put(first)
+			third, err := pool.get()
+			So(err, ShouldBeNil)
+
+			Convey("Then the returned state should be reused", func() {
+				So(third, ShouldEqual, first)
+				So(second, ShouldNotEqual, first)
+				So(created, ShouldEqual, 2)
+			})
+
+			Convey("And a state is discarded", func() {
+				pool.discard(second)
+				fourth, err := pool.get()
+
+				Convey("Then a new state should be created in its place", func() {
+					So(err, ShouldBeNil)
+					So(fourth, ShouldNotEqual, second)
+					So(created, ShouldEqual, 3)
+				})
+			})
+		})
+	})
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	Convey("Given a custom source with the pool of states", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.LuaSandbox, true)
+		viper.Set(key.LuaTimeout, 0)
+		viper.Set(key.LuaInstructionLimit, 0)
+		viper.Set(key.LuaStackLimit, 0)
+		viper.Set(key.LuaMemoryLimit, 0)
+		viper.Set(key.LuaPoolSize, 3)
+
+		path := "/sources/pool.lua"
+		So(filesystem.Api().WriteFile(path, []byte("-- @url https://example.com\n"+poolFunctions), os.ModePerm), ShouldBeNil)
+		src, err := LoadSourceWith(path, Options{Validate: true})
+		So(err, ShouldBeNil)
+
+		Convey("When it is searched concurrently", func() {
+			const searches = 30
+			var (
+				wg       sync.WaitGroup
+				results  = make([][]*source.Manga, searches)
+				failures = make([]error, searches)
+			)
+
+			for i := 0; i < searches; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i], failures[i] = src.Search(strconv.Itoa(i))
+				}(i)
+			}
+
+			wg.Wait()
+
+			Convey("Then every search should get its own results", func() {
+				for i := 0; i < searches; i++ {
+					So(failures[i], ShouldBeNil)
+					So(results[i], ShouldHaveLength, 1)
+					So(results[i][0].Name, ShouldEqual, strconv.Itoa(i))
+				}
+			})
+
+			Convey("Then no more states than the pool size should be created", func() {
+				pool := src.(*luaSource).pool
+				So(len(pool.slots), ShouldBeLessThanOrEqualTo, 3)
+				So(len(pool.states), ShouldEqual, len(pool.slots))
+			})
+		})
+
+		Convey("When it is closed", func() {
+			So(src.(io.Closer).Close(), ShouldBeNil)
+
+			Convey("Then the states should be closed and the calls should fail", func() {
+				pool := src.(*luaSource).pool
+				So(len(pool.states), ShouldEqual, 0)
+				So(len(pool.slots), ShouldEqual, 0)
+
+				_, err := src.Search("closed")
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When a call fails", func() {
+			_, err := src.Search("fail")
+
+			Convey("Then the next calls should still work", func() {
+				So(err, ShouldNotBeNil)
+
+				mangas, err := src.Search("next")
+				So(err, ShouldBeNil)
+				So(mangas, ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestSingleState(t *testing.T) {
+	Convey("Given a custom source that opts out of the concurrency", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.LuaSandbox, true)
+		viper.Set(key.LuaTimeout, 0)
+		viper.Set(key.LuaInstructionLimit, 0)
+		viper.Set(key.LuaStackLimit, 0)
+		viper.Set(key.LuaMemoryLimit, 0)
+		viper.Set(key.LuaPoolSize, 3)
+
+		// the session is set by the search and used by the chapters
+		scraper := `
+session = nil
+
+function SearchManga(query)
+	session = query
+	return {}
+end
+
+function MangaChapters(mangaURL)
+	return { { name = session, url = mangaURL } }
+end
+
+function ChapterPages(chapterURL)
+	return {}
+end
+`
+		path := "/sources/session.lua"
+		So(filesystem.Api().WriteFile(path, []byte("-- @url https://example.com\n-- @concurrent false\n"+scraper), os.ModePerm), ShouldBeNil)
+		src, err := LoadSourceWith(path, Options{Validate: true, NoCache: true})
+		So(err, ShouldBeNil)
+
+		Convey("When its functions are called one after another", func() {
+			_, err := src.Search("token")
+			So(err, ShouldBeNil)
+
+			chapters, err := src.ChaptersOf(&source.Manga{URL: "https://example.com/manga"})
+
+			Convey("Then the globals should be shared between the calls", func() {
+				So(err, ShouldBeNil)
+				So(chapters, ShouldHaveLength, 1)
+				So(chapters[0].Name, ShouldEqual, "token")
+				So(cap(src.(*luaSource).pool.slots), ShouldEqual, 1)
+			})
+		})
+	})
+}
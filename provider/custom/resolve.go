@@ -0,0 +1,52 @@
+package custom
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/source"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Owns returns true if the source defines MangaFromURL
+// and the URL is on the same host as the @url from its header
+func (s *luaSource) Owns(url string) bool {
+	return s.resolvable && source.SameHost(url, s.url)
+}
+
+// Resolve calls MangaFromURL of the source.
+// If the URL is not the URL of the returned manga, it is looked up among its chapters
+func (s *luaSource) Resolve(url string) (*source.Manga, *source.Chapter, error) {
+	if !s.resolvable {
+		return nil, nil, fmt.Errorf("%s does not define %s", s.name, constant.MangaFromURLFn)
+	}
+
+	// the returned table is converted before the state is returned to the pool
+	var manga *source.Manga
+	err := s.call(constant.MangaFromURLFn, func(_ *lua.LState, value lua.LValue) (err error) {
+		switch value.Type() {
+		case lua.LTNil:
+			return fmt.Errorf("%s does not know the url %s", s.name, url)
+		case lua.LTTable:
+			manga, err = mangaFromTable(value.(*lua.LTable), 0)
+			return err
+		default:
+			return fmt.Errorf("%s was expected to return a table or nil, got %s", constant.MangaFromURLFn, value.Type())
+		}
+	}, lua.LString(url))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manga.Source = s
+
+	if source.SameURL(manga.URL, url) {
+		return manga, nil, nil
+	}
+
+	chapter, err := source.FindChapter(manga, url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return manga, chapter, nil
+}
@@ -0,0 +1,344 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	libs "github.com/metafates/mangal-lua-libs"
+	luahttp "github.com/metafates/mangal-lua-libs/http"
+	luaclient "github.com/metafates/mangal-lua-libs/http/client"
+	"github.com/metafates/mangal/key"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+	"net/http"
+	"net/url"
+	"runtime/metrics"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Permission that the scraper can declare in its header with "-- @allow <permission>"
+const (
+	// PermissionNetwork allows requests to the listed hosts, e.g. "-- @allow network example.com cdn.example.com".
+	// Subdomains of the hosts are allowed too, "*" allows any host.
+	PermissionNetwork = "network"
+	// PermissionFilesystem allows modules that read and write files
+	PermissionFilesystem = "filesystem"
+	// PermissionHeadless allows the headless browser. Its requests are not limited by the network hosts
+	PermissionHeadless = "headless"
+)
+
+// filesystemModules are modules of mangal-lua-libs that can access files
+var filesystemModules = []string{"ioutil", "goos", "storage", "filepath", "template", "log"}
+
+// Permissions of the scraper declared in its header
+type Permissions struct {
+	// Hosts that the scraper can send requests to
+	Hosts      []string
+	Filesystem bool
+	Headless   bool
+}
+
+// allowsHost checks if the host or its parent domain is allowed
+func (p *Permissions) allowsHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range p.Hosts {
+		if allowed == "*" || host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allow adds the permission from the header line arguments, e.g. "network example.com"
+func (p *Permissions) allow(fields []string) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("@allow requires a permission")
+	}
+
+	switch permission, args := fields[0], fields[1:]; permission {
+	case PermissionNetwork:
+		if len(args) == 0 {
+			return fmt.Errorf("@allow %s requires hosts", PermissionNetwork)
+		}
+
+		for _, arg := range args {
+			// urls are accepted too, e.g. the same as @url
+			if parsed, err := url.Parse(arg); err == nil && parsed.Host != "" {
+				arg = parsed.Hostname()
+			}
+
+			p.Hosts = append(p.Hosts, strings.ToLower(strings.TrimSuffix(arg, ",")))
+		}
+	case PermissionFilesystem:
+		p.Filesystem = true
+	case PermissionHeadless:
+		p.Headless = true
+	default:
+		return fmt.Errorf("unknown permission %q", permission)
+	}
+
+	return nil
+}
+
+// newState creates a Lua state with mangal-lua-libs preloaded.
+// If sandbox is enabled in the config, the state is limited by the permissions.
+// If transport is not nil, it wraps the transports of the http clients created in the state.
+func newState(permissions *Permissions, transport func(http.RoundTripper) http.RoundTripper) *lua.LState {
+	if !viper.GetBool(key.LuaSandbox) {
+		state := lua.NewState()
+		libs.Preload(state)
+
+		if transport != nil {
+			preload := state.GetField(state.GetGlobal("package"), "preload").(*lua.LTable)
+			for _, module := range []string{"http", "http_client"} {
+				preload.RawSetString(module, state.NewFunction(guardedHTTPLoader(module, nil, transport)))
+			}
+		}
+
+		return state
+	}
+
+	var options lua.Options
+	if limit := viper.GetInt(key.LuaStackLimit); limit > 0 {
+		// the registry is the stack of the values, it does not grow beyond its size
+		options.RegistrySize = limit
+	}
+
+	state := lua.NewState(options)
+	libs.Preload(state)
+
+	preload := state.GetField(state.GetGlobal("package"), "preload").(*lua.LTable)
+
+	deny := func(module, permission string) {
+		preload.RawSetString(module, state.NewFunction(func(L *lua.LState) int {
+			L.RaiseError("module %q requires the permission, add \"-- @allow %s\" to the header of the scraper", module, permission)
+			return 0
+		}))
+	}
+
+	if !permissions.Filesystem {
+		for _, module := range filesystemModules {
+			deny(module, PermissionFilesystem)
+		}
+
+		limitStandardLibrary(state)
+	}
+
+	if !permissions.Headless {
+		deny("headless", PermissionHeadless)
+	}
+
+	for _, module := range []string{"http", "http_client"} {
+		preload.RawSetString(module, state.NewFunction(guardedHTTPLoader(module, permissions, transport)))
+	}
+
+	return state
+}
+
+// limitStandardLibrary removes functions of the Lua standard library that access files or the process
+func limitStandardLibrary(state *lua.LState) {
+	for _, global := range []string{"io", "dofile", "loadfile"} {
+		state.SetGlobal(global, lua.LNil)
+	}
+
+	if os, ok := state.GetGlobal("os").(*lua.LTable); ok {
+		safe := state.NewTable()
+		for _, fn := range []string{"time", "clock", "date", "difftime"} {
+			safe.RawSetString(fn, os.RawGetString(fn))
+		}
+
+		state.SetGlobal("os", safe)
+	}
+
+	// only preloaded modules can be required, not files from package.path
+	if pkg, ok := state.GetGlobal("package").(*lua.LTable); ok {
+		if loaders, ok := pkg.RawGetString("loaders").(*lua.LTable); ok {
+			preloadLoader := loaders.RawGetInt(1)
+			safe := state.NewTable()
+			safe.Append(preloadLoader)
+			pkg.RawSetString("loaders", safe)
+		}
+	}
+}
+
+// guardedHTTPLoader loads the http module where requests can only be sent to the allowed hosts.
+// Nil permissions allow any request.
+func guardedHTTPLoader(module string, permissions *Permissions, transport func(http.RoundTripper) http.RoundTripper) lua.LGFunction {
+	return func(L *lua.LState) int {
+		var n int
+		if module == "http" {
+			n = luahttp.Loader(L)
+		} else {
+			n = luaclient.Loader(L)
+		}
+
+		if permissions != nil && !permissions.Filesystem {
+			if t, ok := L.Get(-1).(*lua.LTable); ok {
+				t.RawSetString("file_request", L.NewFunction(func(L *lua.LState) int {
+					L.RaiseError("file requests require the permission, add \"-- @allow %s\" to the header of the scraper", PermissionFilesystem)
+					return 0
+				}))
+			}
+		}
+
+		// both modules share the client metatable, so it is patched on every load
+		index, ok := L.GetField(L.GetTypeMetatable("http_client_ud"), "__index").(*lua.LTable)
+		if ok {
+			index.RawSetString("do_request", L.NewFunction(func(L *lua.LState) int {
+				if client, ok := L.CheckUserData(1).Value.(*luaclient.LuaClient); ok {
+					guard(client.Client, permissions, transport)
+				}
+
+				return luaclient.DoRequest(L)
+			}))
+		}
+
+		return n
+	}
+}
+
+// hostGuard is the transport that rejects requests to the hosts not allowed by the permissions.
+// Nil permissions allow any host.
+type hostGuard struct {
+	permissions *Permissions
+	base        http.RoundTripper
+}
+
+func (g *hostGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+	if g.permissions != nil && !g.permissions.allowsHost(req.URL.Hostname()) {
+		return nil, fmt.Errorf(
+			"requests to %s are not allowed, add \"-- @allow %s %s\" to the header of the scraper",
+			req.URL.Hostname(),
+			PermissionNetwork,
+			req.URL.Hostname(),
+		)
+	}
+
+	return g.base.RoundTrip(req)
+}
+
+func guard(client *http.Client, permissions *Permissions, transport func(http.RoundTripper) http.RoundTripper) {
+	if _, ok := client.Transport.(*hostGuard); ok {
+		return
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if transport != nil {
+		base = transport(base)
+	}
+
+	client.Transport = &hostGuard{permissions: permissions, base: base}
+}
+
+// memoryCheckInterval is the number of instructions between the checks of the heap size
+const memoryCheckInterval = 1000
+
+// budget is the context that is done when the instruction or memory limit is exhausted.
+// Lua VM checks the context before every instruction, so each check is counted.
+type budget struct {
+	context.Context
+	// remaining instructions, not limited if negative from the start
+	remaining int64
+	// executed instructions
+	executed int64
+	// heap is the size of the heap when the call started, memory is how much it can grow by
+	heap, memory uint64
+	// exceeded is the error of the exhausted limit
+	exceeded atomic.Value
+	done     chan struct{}
+}
+
+var (
+	errInstructionLimit = errors.New("instruction limit exceeded")
+	errMemoryLimit      = errors.New("memory limit exceeded")
+)
+
+// newBudget limits the instructions and the heap growth in bytes, 0 disables the limit
+func newBudget(parent context.Context, instructions int64, memory uint64) *budget {
+	b := &budget{Context: parent, remaining: instructions, memory: memory, done: make(chan struct{})}
+	if instructions <= 0 {
+		b.remaining = -1
+	}
+
+	if memory > 0 {
+		b.heap = heapSize()
+	}
+
+	close(b.done)
+	return b
+}
+
+// heapSize returns the bytes of the heap objects, including the unreachable ones that are not collected yet
+func heapSize() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
+
+func (b *budget) Done() <-chan struct{} {
+	if b.exceeded.Load() != nil {
+		return b.done
+	}
+
+	if b.remaining >= 0 && atomic.AddInt64(&b.remaining, -1) < 0 {
+		b.exceeded.Store(errInstructionLimit)
+		return b.done
+	}
+
+	if b.memory > 0 && atomic.AddInt64(&b.executed, 1)%memoryCheckInterval == 0 {
+		if heap := heapSize(); heap > b.heap && heap-b.heap > b.memory {
+			b.exceeded.Store(errMemoryLimit)
+			return b.done
+		}
+	}
+
+	return b.Context.Done()
+}
+
+func (b *budget) Err() error {
+	if err, ok := b.exceeded.Load().(error); ok {
+		return err
+	}
+
+	return b.Context.Err()
+}
+
+// limitCall sets the time, instruction and memory limits from the config for the next call in the state.
+// The returned function must be called after the call to remove the limits.
+func limitCall(state *lua.LState) func() {
+	if !viper.GetBool(key.LuaSandbox) {
+		return func() {}
+	}
+
+	timeout, instructions := viper.GetInt(key.LuaTimeout), viper.GetInt64(key.LuaInstructionLimit)
+	var memory uint64
+	if megabytes := viper.GetInt(key.LuaMemoryLimit); megabytes > 0 {
+		memory = uint64(megabytes) * 1024 * 1024
+	}
+
+	if timeout <= 0 && instructions <= 0 && memory == 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	}
+
+	if instructions > 0 || memory > 0 {
+		ctx = newBudget(ctx, instructions, memory)
+	}
+
+	state.SetContext(ctx)
+	return func() {
+		state.RemoveContext()
+		cancel()
+	}
+}
@@ -0,0 +1,185 @@
+package custom
+
+import (
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const sandboxFunctions = `
+function SearchManga(query)
+	while true do end
+end
+
+function MangaChapters(mangaURL)
+	return {}
+end
+
+function ChapterPages(chapterURL)
+	local http = require("http")
+	local response, err = http.client():do_request(http.request("GET", chapterURL))
+	if err then
+		error(err)
+	end
+
+	return { { url = response.body, index = 1 } }
+end
+`
+
+func loadTestSource(name, header, code string) (source.Source, error) {
+	path := "/sources/" + name + ".lua"
+	So(filesystem.Api().WriteFile(path, []byte(header+"\n"+code), os.ModePerm), ShouldBeNil)
+	return LoadSource(path, true)
+}
+
+func TestSandbox(t *testing.T) {
+	Convey("Given the sandbox is enabled", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.LuaSandbox, true)
+		viper.Set(key.LuaTimeout, 0)
+		viper.Set(key.LuaInstructionLimit, 100_000)
+		viper.Set(key.LuaStackLimit, 0)
+		viper.Set(key.LuaMemoryLimit, 0)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("https://example.com/page.png"))
+		}))
+		defer server.Close()
+
+		Convey("When the scraper requires the filesystem module without permission", func() {
+			_, err := loadTestSource("files", "-- @url https://example.com", `local ioutil = require("ioutil")`+sandboxFunctions)
+
+			Convey("Then it should fail to load with the hint", func() {
+				So(err, ShouldNotBeNil)
+				So(errs.KindOf(err), ShouldEqual, errs.Lua)
+				So(err.Error(), ShouldContainSubstring, "@allow filesystem")
+			})
+		})
+
+		Convey("When the scraper declares the filesystem permission", func() {
+			_, err := loadTestSource("files", "-- @allow filesystem", `local ioutil = require("ioutil")`+sandboxFunctions)
+
+			Convey("Then it should load", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When the scraper uses io and os libraries", func() {
+			_, err := loadTestSource("stdlib", "", `assert(io == nil and os.execute == nil and os.time ~= nil)`+sandboxFunctions)
+
+			Convey("Then they should be removed except the safe functions", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When the header has an unknown permission", func() {
+			_, err := loadTestSource("unknown", "-- @allow everything", sandboxFunctions)
+
+			Convey("Then it should fail to load", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the scraper runs an infinite loop", func() {
+			src, err := loadTestSource("loop", "", sandboxFunctions)
+			So(err, ShouldBeNil)
+
+			_, err = src.Search("loop")
+
+			Convey("Then it should be stopped by the instruction limit", func() {
+				So(err, ShouldNotBeNil)
+				So(errs.KindOf(err), ShouldEqual, errs.Lua)
+				So(err.Error(), ShouldContainSubstring, errInstructionLimit.Error())
+			})
+		})
+
+		Convey("When the scraper allocates more memory than the limit", func() {
+			viper.Set(key.LuaInstructionLimit, 0)
+			viper.Set(key.LuaMemoryLimit, 16)
+			allocate := `
+local pages = {}
+for i = 1, 10000000 do
+	pages[i] = string.rep("x", 100) .. i
+end
+`
+			_, err := loadTestSource("memory", "", allocate+sandboxFunctions)
+
+			Convey("Then it should be stopped by the memory limit", func() {
+				So(err, ShouldNotBeNil)
+				So(errs.KindOf(err), ShouldEqual, errs.Lua)
+				So(err.Error(), ShouldContainSubstring, errMemoryLimit.Error())
+			})
+		})
+
+		Convey("When the scraper needs more values on the stack than the limit", func() {
+			viper.Set(key.LuaStackLimit, 1024)
+			unpack := `
+local values = {}
+for i = 1, 2048 do
+	values[i] = i
+end
+
+local sum = select("#", unpack(values))
+`
+			_, err := loadTestSource("stack", "", unpack+sandboxFunctions)
+
+			Convey("Then it should fail with the overflow", func() {
+				So(err, ShouldNotBeNil)
+				So(errs.KindOf(err), ShouldEqual, errs.Lua)
+				So(err.Error(), ShouldContainSubstring, "registry overflow")
+			})
+
+			Convey("And the limit is high enough", func() {
+				viper.Set(key.LuaStackLimit, 4096)
+				_, err := loadTestSource("stack", "", unpack+sandboxFunctions)
+
+				Convey("Then it should load", func() {
+					So(err, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("When the scraper sends request to the host that is not allowed", func() {
+			src, err := loadTestSource("denied", "-- @url https://example.com", sandboxFunctions)
+			So(err, ShouldBeNil)
+
+			_, err = src.PagesOf(&source.Chapter{URL: server.URL, Manga: &source.Manga{}})
+
+			Convey("Then the request should be rejected", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "@allow network 127.0.0.1")
+			})
+		})
+
+		Convey("When the scraper sends request to the allowed host", func() {
+			host := strings.TrimPrefix(server.URL, "http://")
+			src, err := loadTestSource("allowed", "-- @allow network "+host[:strings.Index(host, ":")], sandboxFunctions)
+			So(err, ShouldBeNil)
+
+			pages, err := src.PagesOf(&source.Chapter{URL: server.URL, Manga: &source.Manga{}})
+
+			Convey("Then the request should be sent", func() {
+				So(err, ShouldBeNil)
+				So(pages, ShouldHaveLength, 1)
+				So(pages[0].URL, ShouldEqual, "https://example.com/page.png")
+			})
+		})
+
+		Convey("When the sandbox is disabled", func() {
+			viper.Set(key.LuaSandbox, false)
+			_, err := loadTestSource("trusted", "", `local ioutil = require("ioutil")`+sandboxFunctions)
+
+			Convey("Then any module should be available", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
@@ -7,7 +7,9 @@ import (
 	"strconv"
 )
 
-func (s *luaSource) Search(query string) ([]*source.Manga, error) {
+func (s *luaSource) Search(query string) (_ []*source.Manga, err error) {
+	defer recoverLua(&err)
+
 	if mangas := s.cache.mangas.Get(query); mangas.IsPresent() {
 		m := mangas.MustGet()
 		for _, manga := range m {
@@ -17,38 +19,38 @@ func (s *luaSource) Search(query string) ([]*source.Manga, error) {
 		return m, nil
 	}
 
-	_, err := s.call(constant.SearchMangaFn, lua.LTTable, lua.LString(query))
-
-	if err != nil {
-		return nil, err
-	}
-
-	table := s.state.CheckTable(-1)
 	mangas := make([]*source.Manga, 0)
+	err = s.call(constant.SearchMangaFn, func(state *lua.LState, value lua.LValue) error {
+		checkTable(state, constant.SearchMangaFn, value).ForEach(func(k lua.LValue, v lua.LValue) {
+			if k.Type() != lua.LTNumber {
+				state.RaiseError(constant.SearchMangaFn + " was expected to return a table with numbers as keys, got " + k.Type().String() + " as a key")
+			}
 
-	table.ForEach(func(k lua.LValue, v lua.LValue) {
-		if k.Type() != lua.LTNumber {
-			s.state.RaiseError(constant.SearchMangaFn + " was expected to return a table with numbers as keys, got " + k.Type().String() + " as a key")
-		}
+			if v.Type() != lua.LTTable {
+				state.RaiseError(constant.SearchMangaFn + " was expected to return a table with tables as values, got " + v.Type().String() + " as a value")
+			}
 
-		if v.Type() != lua.LTTable {
-			s.state.RaiseError(constant.SearchMangaFn + " was expected to return a table with tables as values, got " + v.Type().String() + " as a value")
-		}
+			index, err := strconv.ParseUint(k.String(), 10, 16)
+			if err != nil {
+				state.RaiseError(constant.SearchMangaFn + " was expected to return a table with unsigned integers as keys. " + err.Error())
+			}
 
-		index, err := strconv.ParseUint(k.String(), 10, 16)
-		if err != nil {
-			s.state.RaiseError(constant.SearchMangaFn + " was expected to return a table with unsigned integers as keys. " + err.Error())
-		}
+			manga, err := mangaFromTable(v.(*lua.LTable), uint16(index))
 
-		manga, err := mangaFromTable(v.(*lua.LTable), uint16(index))
+			if err != nil {
+				state.RaiseError("%s: manga #%s: %s", constant.SearchMangaFn, k.String(), err)
+			}
 
-		if err != nil {
-			s.state.RaiseError(err.Error())
-		}
+			manga.Source = s
+			mangas = append(mangas, manga)
+		})
+
+		return nil
+	}, lua.LString(query))
 
-		manga.Source = s
-		mangas = append(mangas, manga)
-	})
+	if err != nil {
+		return nil, err
+	}
 
 	_ = s.cache.mangas.Set(query, mangas)
 	return mangas, nil
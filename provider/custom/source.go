@@ -2,14 +2,28 @@ package custom
 
 import (
 	"fmt"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/source"
 	lua "github.com/yuin/gopher-lua"
+	"sync"
 )
 
+// opened are the loaded sources that are not closed yet, see CloseAll
+var opened = struct {
+	mutex   sync.Mutex
+	sources map[*luaSource]struct{}
+}{sources: make(map[*luaSource]struct{})}
+
 type luaSource struct {
-	name  string
-	state *lua.LState
-	cache struct {
+	name string
+	// url is the @url from the header of the source
+	url string
+	// resolvable is true if the source defines MangaFromURL
+	resolvable bool
+	// descramblable is true if the source defines DescramblePage
+	descramblable bool
+	pool          *statePool
+	cache         struct {
 		mangas   *cacher[[]*source.Manga]
 		chapters *cacher[[]*source.Chapter]
 	}
@@ -19,10 +33,10 @@ func (s *luaSource) Name() string {
 	return s.name
 }
 
-func newLuaSource(name string, state *lua.LState) (*luaSource, error) {
+func newLuaSource(name string, pool *statePool) (*luaSource, error) {
 	s := &luaSource{
-		name:  name,
-		state: state,
+		name: name,
+		pool: pool,
 	}
 
 	cacheName := func(cacheFor string) string {
@@ -32,29 +46,102 @@ func newLuaSource(name string, state *lua.LState) (*luaSource, error) {
 	s.cache.mangas = newCacher[[]*source.Manga](cacheName("mangas"))
 	s.cache.chapters = newCacher[[]*source.Chapter](cacheName("chapters"))
 
+	opened.mutex.Lock()
+	opened.sources[s] = struct{}{}
+	opened.mutex.Unlock()
+
 	return s, nil
 }
 
-func (s *luaSource) call(fn string, ret lua.LValueType, args ...lua.LValue) (lua.LValue, error) {
-	err := s.state.CallByParam(lua.P{
-		Fn:      s.state.GetGlobal(fn),
+// Close closes the Lua states of the source.
+// Calls in progress are not interrupted, their states are closed when they finish.
+func (s *luaSource) Close() error {
+	s.pool.close()
+
+	opened.mutex.Lock()
+	delete(opened.sources, s)
+	opened.mutex.Unlock()
+
+	return nil
+}
+
+// CloseAll closes all loaded custom sources, it should be called before exiting
+func CloseAll() {
+	opened.mutex.Lock()
+	sources := make([]*luaSource, 0, len(opened.sources))
+	for s := range opened.sources {
+		sources = append(sources, s)
+	}
+	opened.mutex.Unlock()
+
+	for _, s := range sources {
+		_ = s.Close()
+	}
+}
+
+// call calls the function in a state from the pool and passes the returned value to the handle.
+// Errors returned or raised with state.RaiseError by the handle are returned.
+// States of the calls failed in Lua are discarded.
+func (s *luaSource) call(fn string, handle func(state *lua.LState, value lua.LValue) error, args ...lua.LValue) (err error) {
+	state, err := s.pool.get()
+	if err != nil {
+		return err
+	}
+
+	// the state is reused unless the call or the handle failed in Lua
+	failed := true
+	defer func() {
+		if failed {
+			s.pool.discard(state)
+		} else {
+			s.pool.put(state)
+		}
+	}()
+
+	defer recoverLua(&err)
+	defer limitCall(state)()
+
+	err = state.CallByParam(lua.P{
+		Fn:      state.GetGlobal(fn),
 		NRet:    1,
 		Protect: true,
 	}, args...)
 
 	if err != nil {
-		return nil, err
+		return errs.New(errs.Lua, err)
 	}
 
-	val := s.state.Get(-1)
+	value := state.Get(-1)
+	state.Pop(1)
 
-	if val.Type() != ret {
-		s.state.RaiseError(fn + " was expected to return a " + ret.String() + ", got " + val.Type().String())
+	err = handle(state, value)
+	failed = false
+	return err
+}
+
+// checkTable raises an error if the value returned by the function is not a table
+func checkTable(state *lua.LState, fn string, value lua.LValue) *lua.LTable {
+	table, ok := value.(*lua.LTable)
+	if !ok {
+		state.RaiseError(fn + " was expected to return a " + lua.LTTable.String() + ", got " + value.Type().String())
 	}
 
-	return val, nil
+	return table
 }
 
 func (s *luaSource) ID() string {
 	return IDfromName(s.name)
 }
+
+// recoverLua turns errors raised outside of protected calls,
+// e.g. when the returned table is malformed, into returned Lua errors
+func recoverLua(err *error) {
+	if r := recover(); r != nil {
+		apiErr, ok := r.(*lua.ApiError)
+		if !ok {
+			panic(r)
+		}
+
+		*err = errs.New(errs.Lua, apiErr)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	lua "github.com/yuin/gopher-lua"
 	"net/url"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type mapping lo.Tuple4[lua.LValueType, bool, func(string) error, string]
@@ -17,8 +20,13 @@ func translate(
 	table *lua.LTable,
 	mappings map[string]mapping,
 ) (err error) {
-	for field, t := range mappings {
+	// fields are checked in the same order, so that the same table gets the same error
+	fields := lo.Keys(mappings)
+	sort.Strings(fields)
+
+	for _, field := range fields {
 		var (
+			t        = mappings[field]
 			type_    = t.A
 			required = t.B
 			handle   = t.C
@@ -33,7 +41,7 @@ func translate(
 				err = handle(default_)
 			}
 		} else if val.Type() != type_ {
-			err = fmt.Errorf(`field of "%s" must be of type %s`, field, type_)
+			err = fmt.Errorf(`field of "%s" must be of type %s, got %s`, field, type_, val.Type())
 		} else {
 			err = handle(val.String())
 		}
@@ -46,38 +54,282 @@ func translate(
 	return
 }
 
-func mangaFromTable(table *lua.LTable, index uint16) (manga *source.Manga, err error) {
-	manga = &source.Manga{
-		Index:    index,
-		Chapters: []*source.Chapter{},
+// stringMap reads the optional table field with string keys and values, e.g. headers
+func stringMap(table *lua.LTable, field string) (values map[string]string, err error) {
+	val := table.RawGetString(field)
+	if val.Type() == lua.LTNil {
+		return nil, nil
 	}
 
-	mappings := map[string]mapping{
-		"name":    {A: lua.LTString, B: true, C: func(v string) error { manga.Name = v; return nil }},
-		"url":     {A: lua.LTString, B: true, C: func(v string) error { manga.URL = v; return nil }},
-		"summary": {A: lua.LTString, B: false, C: func(v string) error { manga.Metadata.Summary = v; return nil }},
-		"cover": {A: lua.LTString, B: false, C: func(v string) error {
-			if v == "" {
-				return nil
+	t, ok := val.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf(`field of "%s" must be of type %s, got %s`, field, lua.LTTable, val.Type())
+	}
+
+	values = make(map[string]string)
+	t.ForEach(func(k, v lua.LValue) {
+		if err != nil {
+			return
+		}
+
+		if k.Type() != lua.LTString || v.Type() != lua.LTString && v.Type() != lua.LTNumber {
+			err = fmt.Errorf(`field of "%s" must be a table of strings, got %s = %s`, field, k.Type(), v.Type())
+			return
+		}
+
+		values[k.String()] = v.String()
+	})
+
+	return
+}
+
+// stringList reads the optional field that is either a comma separated string or a list of strings,
+// e.g. "Action, Drama" or { "Action", "Drama" }
+func stringList(table *lua.LTable, field string) (values []string, err error) {
+	val := table.RawGetString(field)
+	switch val := val.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LString:
+		for _, value := range strings.Split(string(val), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
 			}
+		}
 
-			_, err := url.Parse(v)
-			if err != nil {
-				return err
+		return values, nil
+	case *lua.LTable:
+		for i := 1; i <= val.Len(); i++ {
+			value := val.RawGetInt(i)
+			if value.Type() != lua.LTString {
+				return nil, fmt.Errorf(`field of "%s" must be a list of strings, got %s at index %d`, field, value.Type(), i)
+			}
+
+			values = append(values, strings.TrimSpace(value.String()))
+		}
+
+		return values, nil
+	default:
+		return nil, fmt.Errorf(`field of "%s" must be a string or a list of strings, got %s`, field, val.Type())
+	}
+}
+
+// number reads the optional field that is a number or a string with a number, e.g. 10.5 or "10.5"
+func number(table *lua.LTable, field string) (string, error) {
+	val := table.RawGetString(field)
+	switch val.Type() {
+	case lua.LTNil:
+		return "", nil
+	case lua.LTNumber:
+		return val.String(), nil
+	case lua.LTString:
+		value := strings.TrimSpace(val.String())
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf(`field of "%s" must be a number, got %q`, field, value)
+		}
+
+		return value, nil
+	default:
+		return "", fmt.Errorf(`field of "%s" must be a number, got %s`, field, val.Type())
+	}
+}
+
+// dateLayouts are the accepted formats of the date strings
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// date reads the optional field that is a date string, e.g. "2022-12-31", or unix time in seconds
+func date(table *lua.LTable, field string) (t time.Time, err error) {
+	val := table.RawGetString(field)
+	switch val := val.(type) {
+	case *lua.LNilType:
+		return
+	case lua.LNumber:
+		return time.Unix(int64(val), 0).UTC(), nil
+	case lua.LString:
+		for _, layout := range dateLayouts {
+			if t, err = time.Parse(layout, string(val)); err == nil {
+				return t, nil
+			}
+		}
+
+		return t, fmt.Errorf(`field of "%s" must be a date like "2006-01-02", got %q`, field, string(val))
+	default:
+		return t, fmt.Errorf(`field of "%s" must be a date string or unix time, got %s`, field, val.Type())
+	}
+}
+
+// tiles reads the optional tile map of the scrambled image, e.g. { columns = 2, rows = 2, order = { 4, 3, 2, 1 } }
+func tiles(table *lua.LTable, field string) (*source.Tiles, error) {
+	val := table.RawGetString(field)
+	switch val := val.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case *lua.LTable:
+		t, err := tilesFromTable(val)
+		if err != nil {
+			return nil, fmt.Errorf(`field of "%s": %w`, field, err)
+		}
+
+		return t, nil
+	default:
+		return nil, fmt.Errorf(`field of "%s" must be of type %s, got %s`, field, lua.LTTable, val.Type())
+	}
+}
+
+func tilesFromTable(table *lua.LTable) (t *source.Tiles, err error) {
+	t = &source.Tiles{}
+
+	for field, value := range map[string]*int{"columns": &t.Columns, "rows": &t.Rows} {
+		val := table.RawGetString(field)
+		if val.Type() != lua.LTNumber {
+			return nil, fmt.Errorf(`field of "%s" must be of type %s, got %s`, field, lua.LTNumber, val.Type())
+		}
+
+		*value = int(val.(lua.LNumber))
+	}
+
+	order, ok := table.RawGetString("order").(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf(`field of "order" must be of type %s, got %s`, lua.LTTable, table.RawGetString("order").Type())
+	}
+
+	for i := 1; i <= order.Len(); i++ {
+		tile, ok := order.RawGetInt(i).(lua.LNumber)
+		if !ok {
+			return nil, fmt.Errorf(`field of "order" must be a list of numbers, got %s at index %d`, order.RawGetInt(i).Type(), i)
+		}
+
+		t.Order = append(t.Order, int(tile))
+	}
+
+	if err = t.Validate(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// statuses maps the accepted manga statuses to the ones used by the metadata
+var statuses = map[string]string{
+	"finished":         "FINISHED",
+	"completed":        "FINISHED",
+	"releasing":        "RELEASING",
+	"ongoing":          "RELEASING",
+	"not_yet_released": "NOT_YET_RELEASED",
+	"cancelled":        "CANCELLED",
+	"hiatus":           "HIATUS",
+}
+
+func parseStatus(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	normalized := strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(strings.TrimSpace(value)))
+	if status, ok := statuses[normalized]; ok {
+		return status, nil
+	}
+
+	allowed := lo.Keys(statuses)
+	sort.Strings(allowed)
+	return "", fmt.Errorf(`field of "status" must be one of %s, got %q`, strings.Join(allowed, ", "), value)
+}
+
+var (
+	languageRegex  = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+	extensionRegex = regexp.MustCompile(`^\.[a-zA-Z0-9]+$`)
+)
+
+func parseCover(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+
+	if _, err := url.Parse(v); err != nil {
+		return "", fmt.Errorf(`field of "cover" must be a url: %w`, err)
+	}
+
+	return v, nil
+}
+
+// mangaMetadata reads the optional manga fields with the given prefix, e.g. "manga_" for the chapter tables
+func mangaMetadata(table *lua.LTable, manga *source.Manga, prefix string) (err error) {
+	mappings := map[string]mapping{
+		prefix + "summary": {A: lua.LTString, B: false, C: func(v string) error {
+			if v != "" {
+				manga.Metadata.Summary = v
 			}
 
-			manga.Metadata.Cover.ExtraLarge = v
 			return nil
 		}},
-		"genres": {A: lua.LTString, B: false, C: func(v string) error {
-			manga.Metadata.Genres = lo.Map(strings.Split(v, ","), func(genre string, _ int) string {
-				return strings.TrimSpace(genre)
-			})
-			return nil
+		prefix + "cover": {A: lua.LTString, B: false, C: func(v string) (err error) {
+			if v, err = parseCover(v); v != "" {
+				manga.Metadata.Cover.ExtraLarge = v
+			}
+
+			return
+		}},
+		prefix + "status": {A: lua.LTString, B: false, C: func(v string) (err error) {
+			if v, err = parseStatus(v); v != "" {
+				manga.Metadata.Status = v
+			}
+
+			return
 		}},
 	}
 
-	err = translate(table, mappings)
+	if err = translate(table, mappings); err != nil {
+		return
+	}
+
+	lists := []lo.Tuple2[[]string, *[]string]{
+		{A: []string{"genres"}, B: &manga.Metadata.Genres},
+		{A: []string{"authors", "author"}, B: &manga.Metadata.Staff.Story},
+		{A: []string{"artists", "artist"}, B: &manga.Metadata.Staff.Art},
+		{A: []string{"alt_titles"}, B: &manga.Metadata.Synonyms},
+	}
+
+	for _, list := range lists {
+		for _, field := range list.A {
+			values, err := stringList(table, prefix+field)
+			if err != nil {
+				return err
+			}
+
+			if values != nil {
+				*list.B = values
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func mangaFromTable(table *lua.LTable, index uint16) (manga *source.Manga, err error) {
+	manga = &source.Manga{
+		Index:    index,
+		Chapters: []*source.Chapter{},
+	}
+
+	mappings := map[string]mapping{
+		"name": {A: lua.LTString, B: true, C: func(v string) error { manga.Name = v; return nil }},
+		"url":  {A: lua.LTString, B: true, C: func(v string) error { manga.URL = v; return nil }},
+	}
+
+	if err = translate(table, mappings); err != nil {
+		return
+	}
+
+	if err = mangaMetadata(table, manga, ""); err != nil {
+		return
+	}
+
+	if manga.Metadata.Cover.Headers, err = stringMap(table, "cover_headers"); err != nil {
+		return
+	}
+
+	manga.Metadata.Cover.Cookies, err = stringMap(table, "cover_cookies")
 	return
 }
 
@@ -89,31 +341,41 @@ func chapterFromTable(table *lua.LTable, manga *source.Manga, index uint16) (cha
 	}
 
 	mappings := map[string]mapping{
-		"name":          {A: lua.LTString, B: true, C: func(v string) error { chapter.Name = v; return nil }},
-		"url":           {A: lua.LTString, B: true, C: func(v string) error { chapter.URL = v; return nil }},
-		"volume":        {A: lua.LTString, B: false, C: func(v string) error { chapter.Volume = v; return nil }},
-		"manga_summary": {A: lua.LTString, B: false, C: func(v string) error { manga.Metadata.Summary = v; return nil }},
-		"manga_genres": {A: lua.LTString, B: false, C: func(v string) error {
-			manga.Metadata.Genres = lo.Map(strings.Split(v, ","), func(genre string, _ int) string {
-				return strings.TrimSpace(genre)
-			})
-			return nil
-		}},
-		"manga_cover": {A: lua.LTString, B: false, C: func(v string) error {
-			if v == "" {
-				return nil
-			}
-			_, err := url.Parse(v)
-			if err != nil {
-				return err
+		"name":   {A: lua.LTString, B: true, C: func(v string) error { chapter.Name = v; return nil }},
+		"url":    {A: lua.LTString, B: true, C: func(v string) error { chapter.URL = v; return nil }},
+		"volume": {A: lua.LTString, B: false, C: func(v string) error { chapter.Volume = v; return nil }},
+		"group":  {A: lua.LTString, B: false, C: func(v string) error { chapter.Group = v; return nil }},
+		"language": {A: lua.LTString, B: false, C: func(v string) error {
+			if v != "" && !languageRegex.MatchString(v) {
+				return fmt.Errorf(`field of "language" must be a language code like "en" or "pt-br", got %q`, v)
 			}
 
-			manga.Metadata.Cover.ExtraLarge = v
+			chapter.Language = v
 			return nil
 		}},
 	}
 
-	err = translate(table, mappings)
+	if err = translate(table, mappings); err != nil {
+		return
+	}
+
+	if chapter.Number, err = number(table, "number"); err != nil {
+		return
+	}
+
+	uploaded, err := date(table, "date")
+	if err != nil {
+		return
+	}
+
+	if !uploaded.IsZero() {
+		chapter.Date.Year, chapter.Date.Month, chapter.Date.Day = uploaded.Year(), int(uploaded.Month()), uploaded.Day()
+	}
+
+	if err = mangaMetadata(table, manga, "manga_"); err != nil {
+		return
+	}
+
 	manga.Chapters = append(manga.Chapters, chapter)
 	return
 }
@@ -128,12 +390,28 @@ func pageFromTable(table *lua.LTable, chapter *source.Chapter) (page *source.Pag
 		"index": {A: lua.LTNumber, B: true, C: func(v string) error {
 			num, err := strconv.ParseUint(v, 10, 16)
 			if err != nil {
-				return err
+				return fmt.Errorf(`field of "index" must be an integer from 0 to %d, got %s`, 1<<16-1, v)
 			}
 
 			page.Index = uint16(num)
 			return nil
 		}},
+		"extension": {A: lua.LTString, B: false, C: func(v string) error {
+			if v == "" {
+				return nil
+			}
+
+			if !strings.HasPrefix(v, ".") {
+				v = "." + v
+			}
+
+			if !extensionRegex.MatchString(v) {
+				return fmt.Errorf(`field of "extension" must be a file extension like ".jpg", got %q`, v)
+			}
+
+			page.Extension = strings.ToLower(v)
+			return nil
+		}},
 	}
 
 	err = translate(table, mappings)
@@ -141,7 +419,22 @@ func pageFromTable(table *lua.LTable, chapter *source.Chapter) (page *source.Pag
 		return
 	}
 
-	page.Extension = filepath.Ext(page.URL)
+	if page.Headers, err = stringMap(table, "headers"); err != nil {
+		return
+	}
+
+	if page.Cookies, err = stringMap(table, "cookies"); err != nil {
+		return
+	}
+
+	if page.Tiles, err = tiles(table, "tiles"); err != nil {
+		return
+	}
+
+	if page.Extension == "" {
+		page.Extension = filepath.Ext(page.URL)
+	}
+
 	chapter.Pages = append(chapter.Pages, page)
 	return
 }
@@ -0,0 +1,204 @@
+package custom
+
+import (
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	lua "github.com/yuin/gopher-lua"
+	"os"
+	"testing"
+)
+
+func TestPageFromTable(t *testing.T) {
+	Convey("Given a Lua state", t, func() {
+		state := lua.NewState()
+		defer state.Close()
+
+		chapter := &source.Chapter{}
+
+		Convey("When the page table has headers and cookies", func() {
+			So(state.DoString(`page = { url = "https://example.com/1.png", index = 1, headers = { Referer = "https://example.com" }, cookies = { session = "secret" } }`), ShouldBeNil)
+			page, err := pageFromTable(state.GetGlobal("page").(*lua.LTable), chapter)
+
+			Convey("Then they should be set on the page", func() {
+				So(err, ShouldBeNil)
+				So(page.Headers, ShouldResemble, map[string]string{"Referer": "https://example.com"})
+				So(page.Cookies, ShouldResemble, map[string]string{"session": "secret"})
+				So(page.Extension, ShouldEqual, ".png")
+			})
+		})
+
+		Convey("When the headers are not a table", func() {
+			So(state.DoString(`page = { url = "https://example.com/1.png", index = 1, headers = "Referer: https://example.com" }`), ShouldBeNil)
+			_, err := pageFromTable(state.GetGlobal("page").(*lua.LTable), chapter)
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, `"headers"`)
+			})
+		})
+
+		Convey("When the manga table has cover headers", func() {
+			So(state.DoString(`manga = { name = "Manga", url = "https://example.com/manga", cover = "https://example.com/cover.png", cover_headers = { Referer = "https://example.com" } }`), ShouldBeNil)
+			manga, err := mangaFromTable(state.GetGlobal("manga").(*lua.LTable), 0)
+
+			Convey("Then they should be set on the cover", func() {
+				So(err, ShouldBeNil)
+				So(manga.Metadata.Cover.Headers, ShouldResemble, map[string]string{"Referer": "https://example.com"})
+				So(manga.Metadata.Cover.Cookies, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestExtendedSchema(t *testing.T) {
+	Convey("Given a Lua state", t, func() {
+		state := lua.NewState()
+		defer state.Close()
+
+		table := func(code string) *lua.LTable {
+			So(state.DoString("value = "+code), ShouldBeNil)
+			return state.GetGlobal("value").(*lua.LTable)
+		}
+
+		Convey("When the manga table has the extended fields", func() {
+			manga, err := mangaFromTable(table(`{
+				name = "Manga",
+				url = "https://example.com/manga",
+				authors = { "Author" },
+				artist = "First, Second",
+				status = "Ongoing",
+				alt_titles = { "Other" },
+			}`), 0)
+
+			Convey("Then they should be set on the metadata", func() {
+				So(err, ShouldBeNil)
+				So(manga.Metadata.Staff.Story, ShouldResemble, []string{"Author"})
+				So(manga.Metadata.Staff.Art, ShouldResemble, []string{"First", "Second"})
+				So(manga.Metadata.Status, ShouldEqual, "RELEASING")
+				So(manga.Metadata.Synonyms, ShouldResemble, []string{"Other"})
+			})
+		})
+
+		Convey("When the manga status is unknown", func() {
+			_, err := mangaFromTable(table(`{ name = "Manga", url = "https://example.com", status = "paused" }`), 0)
+
+			Convey("Then the error should name the field and the value", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, `"status"`)
+				So(err.Error(), ShouldContainSubstring, `"paused"`)
+			})
+		})
+
+		Convey("When the list has not only strings", func() {
+			_, err := mangaFromTable(table(`{ name = "Manga", url = "https://example.com", authors = { "Author", 1 } }`), 0)
+
+			Convey("Then the error should name the field and the index", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `field of "authors" must be a list of strings, got number at index 2`)
+			})
+		})
+
+		Convey("When the chapter table has the extended fields", func() {
+			manga := &source.Manga{}
+			chapter, err := chapterFromTable(table(`{
+				name = "Chapter",
+				url = "https://example.com/chapter",
+				number = 10.5,
+				language = "pt-br",
+				group = "Scans",
+				date = "2022-12-31",
+				manga_authors = "Author",
+			}`), manga, 0)
+
+			Convey("Then they should be set on the chapter", func() {
+				So(err, ShouldBeNil)
+				So(chapter.Number, ShouldEqual, "10.5")
+				So(chapter.ParsedNumber(), ShouldEqual, 10.5)
+				So(chapter.Language, ShouldEqual, "pt-br")
+				So(chapter.Group, ShouldEqual, "Scans")
+				So(chapter.Date.Year, ShouldEqual, 2022)
+				So(chapter.Date.Month, ShouldEqual, 12)
+				So(chapter.Date.Day, ShouldEqual, 31)
+				So(manga.Metadata.Staff.Story, ShouldResemble, []string{"Author"})
+			})
+		})
+
+		Convey("When the chapter fields are invalid", func() {
+			for code, field := range map[string]string{
+				`{ name = "Chapter", url = "https://example.com", number = "ten" }`:       "number",
+				`{ name = "Chapter", url = "https://example.com", date = "yesterday" }`:   "date",
+				`{ name = "Chapter", url = "https://example.com", language = "English" }`: "language",
+				`{ name = 1, url = "https://example.com" }`:                               "name",
+			} {
+				_, err := chapterFromTable(table(code), &source.Manga{}, 0)
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, `"`+field+`"`)
+			}
+		})
+
+		Convey("When the page table has the extension", func() {
+			page, err := pageFromTable(table(`{ url = "https://example.com/image?id=1", index = 1, extension = "WEBP" }`), &source.Chapter{})
+
+			Convey("Then it should be used instead of the url extension", func() {
+				So(err, ShouldBeNil)
+				So(page.Extension, ShouldEqual, ".webp")
+			})
+		})
+
+		Convey("When the page table has the tiles", func() {
+			page, err := pageFromTable(table(`{ url = "https://example.com/1.png", index = 1, tiles = { columns = 2, rows = 1, order = { 2, 1 } } }`), &source.Chapter{})
+
+			Convey("Then they should be set on the page", func() {
+				So(err, ShouldBeNil)
+				So(page.Tiles, ShouldResemble, &source.Tiles{Columns: 2, Rows: 1, Order: []int{2, 1}})
+			})
+		})
+
+		Convey("When the tiles order has not only numbers", func() {
+			_, err := pageFromTable(table(`{ url = "https://example.com/1.png", index = 1, tiles = { columns = 2, rows = 1, order = { 2, "1" } } }`), &source.Chapter{})
+
+			Convey("Then the error should name the field and the index", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `field of "tiles": field of "order" must be a list of numbers, got string at index 2`)
+			})
+		})
+	})
+}
+
+func TestSchemaErrors(t *testing.T) {
+	Convey("Given the scraper that returns an invalid chapter", t, func() {
+		filesystem.SetMemMapFs()
+		viper.Set(key.LuaSandbox, false)
+
+		path := "/sources/invalid.lua"
+		So(filesystem.Api().WriteFile(path, []byte(`
+function SearchManga(query)
+	return {}
+end
+
+function MangaChapters(mangaURL)
+	return { { name = "First", url = mangaURL .. "/1" }, { name = "Second", url = mangaURL .. "/2", date = "soon" } }
+end
+
+function ChapterPages(chapterURL)
+	return {}
+end
+`), os.ModePerm), ShouldBeNil)
+
+		src, err := LoadSourceWith(path, Options{Validate: true, NoCache: true})
+		So(err, ShouldBeNil)
+
+		Convey("When the chapters are requested", func() {
+			_, err := src.ChaptersOf(&source.Manga{URL: "https://example.com"})
+
+			Convey("Then the error should name the function, the chapter and the field", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, `MangaChapters: chapter #2: field of "date"`)
+			})
+		})
+	})
+}
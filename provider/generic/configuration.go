@@ -17,6 +17,25 @@ type Extractor struct {
 	Volume func(*goquery.Selection) string
 	// Cover function to get cover from element found by selector. Used by manga extractor
 	Cover func(*goquery.Selection) string
+	// Headers function to get headers for the image request from element found by selector.
+	// Used by page extractor for pages and by manga extractor for covers. Optional
+	Headers func(*goquery.Selection) map[string]string
+	// Cookies function to get cookies for the image request from element found by selector.
+	// Used by page extractor for pages and by manga extractor for covers. Optional
+	Cookies func(*goquery.Selection) map[string]string
+}
+
+// headers returns headers and cookies from the element if the extractor defines them
+func (e *Extractor) headers(selection *goquery.Selection) (headers, cookies map[string]string) {
+	if e.Headers != nil {
+		headers = e.Headers(selection)
+	}
+
+	if e.Cookies != nil {
+		cookies = e.Cookies(selection)
+	}
+
+	return
 }
 
 // Configuration is a generic scraper configuration that defines behavior of the scraper
@@ -31,12 +50,17 @@ type Configuration struct {
 	// ReverseChapters if true, chapters will be shown in reverse order
 	ReverseChapters bool
 
-	// BaseURL of the source
+	// BaseURL of the source.
+	// URLs on the same host are considered to belong to the source
 	BaseURL string
 	// GenerateSearchURL function to create search URL from the query.
 	// E.g. "one piece" -> "https://manganelo.com/search/story/one%20piece"
 	GenerateSearchURL func(query string) string
 
+	// MangaName function to get name of the manga from its page.
+	// Used when the manga is resolved from the URL. If nil, the first h1 header is used
+	MangaName func(*goquery.Selection) string
+
 	// MangaExtractor is responsible for finding manga elements and extracting required data from them
 	MangaExtractor,
 	// ChapterExtractor is responsible for finding chapter elements and extracting required data from them
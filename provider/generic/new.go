@@ -56,6 +56,7 @@ func New(conf *Configuration) source.Source {
 				Source:   &s,
 			}
 			manga.Metadata.Cover.ExtraLarge = s.config.MangaExtractor.Cover(selection)
+			manga.Metadata.Cover.Headers, manga.Metadata.Cover.Cookies = s.config.MangaExtractor.headers(selection)
 
 			s.mangas[path][i] = &manga
 		})
@@ -83,6 +84,11 @@ func New(conf *Configuration) source.Source {
 		s.chapters[path] = make([]*source.Chapter, elements.Length())
 		manga := e.Request.Ctx.GetAny("manga").(*source.Manga)
 
+		// manga resolved from the URL has no name yet
+		if manga.Name == "" {
+			manga.Name = s.mangaName(e.DOM)
+		}
+
 		elements.Each(func(i int, selection *goquery.Selection) {
 			link := s.config.ChapterExtractor.URL(selection)
 			url := e.Request.AbsoluteURL(link)
@@ -133,6 +139,7 @@ func New(conf *Configuration) source.Source {
 				Chapter:   chapter,
 				Extension: ext,
 			}
+			page.Headers, page.Cookies = s.config.PageExtractor.headers(selection)
 			s.pages[path][i] = &page
 		})
 		chapter.Pages = s.pages[path]
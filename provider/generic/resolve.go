@@ -0,0 +1,45 @@
+package generic
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/metafates/mangal/source"
+	"path/filepath"
+	"strings"
+)
+
+// Owns returns true if the URL is on the same host as the BaseURL
+func (s *Scraper) Owns(url string) bool {
+	return source.SameHost(url, s.config.BaseURL)
+}
+
+// Resolve gets the manga from its URL by scraping its chapters.
+// Chapter URLs can not be resolved, since there is no generic way to find their manga.
+func (s *Scraper) Resolve(url string) (*source.Manga, *source.Chapter, error) {
+	manga := &source.Manga{
+		URL:      url,
+		Chapters: make([]*source.Chapter, 0),
+		ID:       filepath.Base(url),
+		Source:   s,
+	}
+
+	chapters, err := s.ChaptersOf(manga)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(chapters) == 0 || manga.Name == "" {
+		return nil, nil, fmt.Errorf("%s is not a manga page of %s, only manga urls are supported", url, s.Name())
+	}
+
+	return manga, nil, nil
+}
+
+// mangaName extracts name of the manga from its page
+func (s *Scraper) mangaName(html *goquery.Selection) string {
+	if s.config.MangaName != nil {
+		return s.config.MangaName(html)
+	}
+
+	return strings.TrimSpace(html.Find("h1").First().Text())
+}
@@ -0,0 +1,112 @@
+package mangadex
+
+import (
+	"context"
+	"fmt"
+	"github.com/darylhjd/mangodex"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+var urlRegex = regexp.MustCompile(`^https?://(?:www\.)?mangadex\.org/(title|chapter)/([0-9a-fA-F-]{36})`)
+
+// Owns returns true if the URL is a mangadex title or chapter URL
+func (*Mangadex) Owns(url string) bool {
+	return urlRegex.MatchString(url)
+}
+
+// Resolve gets the manga or chapter by the ID from the URL
+func (m *Mangadex) Resolve(url string) (*source.Manga, *source.Chapter, error) {
+	groups := urlRegex.FindStringSubmatch(url)
+	if groups == nil {
+		return nil, nil, fmt.Errorf("not a mangadex url: %s", url)
+	}
+
+	kind, id := groups[1], groups[2]
+	if kind == "title" {
+		manga, err := m.mangaByID(id)
+		return manga, nil, err
+	}
+
+	return m.chapterByID(id)
+}
+
+// mangaByID gets the manga by its ID
+func (m *Mangadex) mangaByID(id string) (*source.Manga, error) {
+	params := url.Values{}
+	params.Add("ids[]", id)
+
+	for _, rating := range []string{mangodex.Safe, mangodex.Suggestive, mangodex.Porn, mangodex.Erotica} {
+		params.Add("contentRating[]", rating)
+	}
+
+	list, err := m.client.Manga.GetMangaList(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list.Data) == 0 {
+		return nil, fmt.Errorf("manga %s not found on mangadex", id)
+	}
+
+	manga := list.Data[0]
+	return &source.Manga{
+		Name:   manga.GetTitle(viper.GetString(key.MangadexLanguage)),
+		URL:    fmt.Sprintf("https://mangadex.org/title/%s", manga.ID),
+		ID:     manga.ID,
+		Source: m,
+	}, nil
+}
+
+// chapterResponse is a response of the chapter endpoint, which is not covered by the client
+type chapterResponse struct {
+	Result string           `json:"result"`
+	Data   mangodex.Chapter `json:"data"`
+}
+
+func (r *chapterResponse) GetResult() string {
+	return r.Result
+}
+
+// chapterByID gets the chapter by its ID along with the manga it belongs to
+func (m *Mangadex) chapterByID(id string) (*source.Manga, *source.Chapter, error) {
+	var response chapterResponse
+	err := m.client.RequestAndDecode(
+		context.Background(),
+		http.MethodGet,
+		fmt.Sprintf("%s/chapter/%s", mangodex.BaseAPI, id),
+		nil,
+		&response,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mangaID string
+	for _, relationship := range response.Data.Relationships {
+		if relationship.Type == mangodex.MangaRel {
+			mangaID = relationship.ID
+			break
+		}
+	}
+
+	if mangaID == "" {
+		return nil, nil, fmt.Errorf("chapter %s does not belong to any manga", id)
+	}
+
+	manga, err := m.mangaByID(mangaID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chapter, err := source.FindChapter(manga, fmt.Sprintf("https://mangadex.org/chapter/%s", id))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return manga, chapter, nil
+}
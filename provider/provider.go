@@ -16,6 +16,10 @@ type Provider struct {
 	Name         string
 	UsesHeadless bool
 	IsCustom     bool
+	// Header of the custom scraper, nil for builtin providers
+	Header *custom.Header
+	// Incompatible is the reason why the custom scraper can not be loaded, nil if it can
+	Incompatible error
 	CreateSource func() (source.Source, error)
 }
 
@@ -43,33 +47,46 @@ func Customs() []*Provider {
 	providers := make([]*Provider, len(paths))
 
 	for i, path := range paths {
-		// Check if source contains line `require("headless")`
-		// if so, set UsesHeadless to true.
-		// This approach is not ideal, but it's the only way to do it without
-		// actually loading the source.
-		usesHeadless, _ := filesystem.Api().FileContainsAnyBytes(path, [][]byte{
-			[]byte("require(\"headless\")"),
-			[]byte("require('headless')"),
-			[]byte("require(headless)"),
-			[]byte("require'headless'"),
-		})
-
 		name := util.FileStem(path)
 		path := path
+
+		header, err := custom.ParseHeader(path)
+		if err != nil {
+			header = &custom.Header{}
+		} else {
+			err = header.Compatible()
+		}
+
 		providers[i] = &Provider{
 			ID:           custom.IDfromName(name),
-			UsesHeadless: usesHeadless,
+			UsesHeadless: header.UsesHeadless() || requiresHeadless(path),
 			IsCustom:     true,
 			Name:         name,
+			Header:       header,
+			Incompatible: err,
 			CreateSource: func() (source.Source, error) {
 				return custom.LoadSource(path, true)
 			},
 		}
 	}
-
 	return providers
 }
 
+// requiresHeadless checks if the source contains line `require("headless")`.
+// Scrapers without the headless tags in the header are detected this way.
+// This approach is not ideal, but it's the only way to do it without
+// actually loading the source.
+func requiresHeadless(path string) bool {
+	usesHeadless, _ := filesystem.Api().FileContainsAnyBytes(path, [][]byte{
+		[]byte("require(\"headless\")"),
+		[]byte("require('headless')"),
+		[]byte("require(headless)"),
+		[]byte("require'headless'"),
+	})
+
+	return usesHeadless
+}
+
 func Get(name string) (*Provider, bool) {
 	for _, provider := range Builtins() {
 		if provider.Name == name {
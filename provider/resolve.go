@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/source"
+	"github.com/metafates/mangal/where"
+	"io"
+	"path/filepath"
+)
+
+// Resolve finds the source that owns the URL and gets the manga from it.
+// If the URL points to a chapter, the chapter is returned as well, otherwise it is nil.
+func Resolve(url string) (*source.Manga, *source.Chapter, error) {
+	for _, provider := range append(Builtins(), Customs()...) {
+		// Avoid loading custom sources that can not resolve URLs anyway
+		if provider.IsCustom {
+			path := filepath.Join(where.Sources(), provider.Name+CustomProviderExtension)
+			defines, _ := filesystem.Api().FileContainsAnyBytes(path, [][]byte{
+				[]byte(constant.MangaFromURLFn),
+			})
+
+			if !defines {
+				continue
+			}
+		}
+
+		src, err := provider.CreateSource()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resolver, ok := src.(source.URLResolver)
+		if !ok || !resolver.Owns(url) {
+			if closer, ok := src.(io.Closer); ok {
+				_ = closer.Close()
+			}
+
+			continue
+		}
+
+		return resolver.Resolve(url)
+	}
+
+	return nil, nil, fmt.Errorf("no source can resolve the url %s", url)
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/where"
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const resolvingSource = `-- @name    resolving
+-- @url     https://example.com
+
+function SearchManga(query)
+	return {}
+end
+
+function MangaChapters(mangaURL)
+	return {
+		{ name = "Chapter 1", url = mangaURL .. "/1" },
+		{ name = "Chapter 2", url = mangaURL .. "/2" },
+	}
+end
+
+function ChapterPages(chapterURL)
+	return {}
+end
+
+function MangaFromURL(url)
+	return { name = "Example", url = "https://example.com/manga" }
+end
+`
+
+func TestResolve(t *testing.T) {
+	Convey("Given a custom source that defines MangaFromURL", t, func() {
+		filesystem.SetMemMapFs()
+		path := filepath.Join(where.Sources(), "resolving"+CustomProviderExtension)
+		So(filesystem.Api().WriteFile(path, []byte(resolvingSource), os.ModePerm), ShouldBeNil)
+
+		Convey("When resolving the manga url", func() {
+			manga, chapter, err := Resolve("https://example.com/manga/")
+
+			Convey("Then the manga should be returned without chapter", func() {
+				So(err, ShouldBeNil)
+				So(manga.Name, ShouldEqual, "Example")
+				So(manga.Source.Name(), ShouldEqual, "resolving")
+				So(chapter, ShouldBeNil)
+			})
+		})
+
+		Convey("When resolving the chapter url", func() {
+			manga, chapter, err := Resolve("https://www.example.com/manga/2")
+
+			Convey("Then the chapter should be found among the manga chapters", func() {
+				So(err, ShouldBeNil)
+				So(manga.Name, ShouldEqual, "Example")
+				So(chapter.Name, ShouldEqual, "Chapter 2")
+			})
+		})
+
+		Convey("When resolving the url of unknown host", func() {
+			_, _, err := Resolve("https://unknown.org/manga")
+
+			Convey("Then error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/history"
+	"github.com/metafates/mangal/inline"
+	"github.com/metafates/mangal/provider"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Provider is a source that can be used
+type Provider struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Custom bool   `json:"custom"`
+}
+
+// Manga identifies the manga in the source
+type Manga struct {
+	Source string `json:"source"`
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	ID     string `json:"id"`
+}
+
+// Chapter is a chapter of the manga
+type Chapter struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	ID     string `json:"id"`
+	Index  uint16 `json:"index"`
+	Volume string `json:"volume"`
+}
+
+func newManga(manga *source.Manga) *Manga {
+	return &Manga{
+		Source: manga.Source.Name(),
+		Name:   manga.Name,
+		URL:    manga.URL,
+		ID:     manga.ID,
+	}
+}
+
+func newChapter(chapter *source.Chapter) *Chapter {
+	return &Chapter{
+		Name:   chapter.Name,
+		URL:    chapter.URL,
+		ID:     chapter.ID,
+		Index:  chapter.Index,
+		Volume: chapter.Volume,
+	}
+}
+
+// manga creates the source manga from the identity
+func (s *Server) manga(identity *Manga) (*source.Manga, error) {
+	if identity.Source == "" || identity.URL == "" {
+		return nil, errors.New("source and url of the manga are required")
+	}
+
+	src, err := s.source(identity.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &source.Manga{
+		Name:     identity.Name,
+		URL:      identity.URL,
+		ID:       identity.ID,
+		Source:   src,
+		Chapters: make([]*source.Chapter, 0),
+	}, nil
+}
+
+// GET /api/providers
+func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	providers := lo.Map(append(provider.Builtins(), provider.Customs()...), func(p *provider.Provider, _ int) *Provider {
+		return &Provider{
+			ID:     p.ID,
+			Name:   p.Name,
+			Custom: p.IsCustom,
+		}
+	})
+
+	writeJSON(w, http.StatusOK, providers)
+}
+
+// GET /api/search?source=<name>&query=<query>
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	name, query := r.URL.Query().Get("source"), r.URL.Query().Get("query")
+	if name == "" || query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("source and query are required"))
+		return
+	}
+
+	src, err := s.source(name)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	mangas, err := src.Search(query)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lo.Map(mangas, func(manga *source.Manga, _ int) *Manga {
+		return newManga(manga)
+	}))
+}
+
+// GET /api/chapters?source=<name>&url=<manga url>&id=<manga id>&name=<manga name>
+func (s *Server) handleChapters(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	params := r.URL.Query()
+	manga, err := s.manga(&Manga{
+		Source: params.Get("source"),
+		Name:   params.Get("name"),
+		URL:    params.Get("url"),
+		ID:     params.Get("id"),
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	chapters, err := manga.Source.ChaptersOf(manga)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lo.Map(chapters, func(chapter *source.Chapter, _ int) *Chapter {
+		return newChapter(chapter)
+	}))
+}
+
+// downloadRequest is the body of the download request
+type downloadRequest struct {
+	Manga *Manga `json:"manga"`
+	// Chapters selector, see inline.ParseSelector
+	Chapters string `json:"chapters"`
+}
+
+// GET /api/downloads lists downloads.
+// POST /api/downloads enqueues a new download.
+func (s *Server) handleDownloads(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, s.downloads.list())
+		return
+	}
+
+	if !isJSON(w, r) {
+		return
+	}
+
+	var request downloadRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.Manga == nil || request.Chapters == "" {
+		writeError(w, http.StatusBadRequest, errors.New("manga and chapters are required"))
+		return
+	}
+
+	selector, err := inline.ParseSelector(request.Chapters)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	manga, err := s.manga(request.Manga)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	chapters, err := manga.Source.ChaptersOf(manga)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	selected, err := selector.Select(chapters)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(selected) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no chapters matched %q", request.Chapters))
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, s.downloads.add(manga, selected))
+}
+
+// GET /api/downloads/<id>
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/downloads/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid download id: %w", err))
+		return
+	}
+
+	download, ok := s.downloads.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("download %d not found", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, download)
+}
+
+// GET /api/history
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	saved, err := history.Get()
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	chapters := lo.Values(saved)
+	slices.SortFunc(chapters, func(a, b *history.SavedChapter) bool {
+		return a.MangaName < b.MangaName
+	})
+
+	writeJSON(w, http.StatusOK, chapters)
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"github.com/metafates/mangal/downloader"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/source"
+	"github.com/samber/lo"
+	"sync"
+	"time"
+)
+
+// Status of the download or its chapter
+type Status string
+
+const (
+	StatusQueued      Status = "queued"
+	StatusDownloading Status = "downloading"
+	StatusDone        Status = "done"
+	StatusFailed      Status = "failed"
+)
+
+// DownloadChapter is the state of a single chapter of the download
+type DownloadChapter struct {
+	*Chapter
+	Status     Status `json:"status"`
+	Pages      int    `json:"pages"`
+	Downloaded int    `json:"downloaded"`
+	Path       string `json:"path,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	chapter *source.Chapter
+}
+
+// Download is the state of the enqueued download
+type Download struct {
+	ID         int                `json:"id"`
+	Manga      *Manga             `json:"manga"`
+	Status     Status             `json:"status"`
+	Chapters   []*DownloadChapter `json:"chapters"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+// queue downloads enqueued chapters one download at a time, in order.
+// Since the downloader depends on the global config, downloads are not run in parallel.
+type queue struct {
+	mutex     sync.Mutex
+	downloads []*Download
+	pending   chan *Download
+}
+
+func newQueue() *queue {
+	q := &queue{pending: make(chan *Download, 64)}
+	go q.work()
+	return q
+}
+
+// add enqueues the chapters of the manga and returns a snapshot of the download
+func (q *queue) add(manga *source.Manga, chapters []*source.Chapter) *Download {
+	q.mutex.Lock()
+
+	download := &Download{
+		ID:        len(q.downloads) + 1,
+		Manga:     newManga(manga),
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+		Chapters: lo.Map(chapters, func(chapter *source.Chapter, _ int) *DownloadChapter {
+			return &DownloadChapter{
+				Chapter: newChapter(chapter),
+				Status:  StatusQueued,
+				chapter: chapter,
+			}
+		}),
+	}
+
+	q.downloads = append(q.downloads, download)
+	snapshot := download.snapshot()
+	q.mutex.Unlock()
+
+	// do not block the request while the worker is busy with the full channel
+	go func() {
+		q.pending <- download
+	}()
+
+	return snapshot
+}
+
+func (q *queue) get(id int) (*Download, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if id < 1 || id > len(q.downloads) {
+		return nil, false
+	}
+
+	return q.downloads[id-1].snapshot(), true
+}
+
+func (q *queue) list() []*Download {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return lo.Map(q.downloads, func(download *Download, _ int) *Download {
+		return download.snapshot()
+	})
+}
+
+// update changes the download state under the lock
+func (q *queue) update(f func()) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	f()
+}
+
+func (q *queue) work() {
+	for download := range q.pending {
+		q.run(download)
+	}
+}
+
+func (q *queue) run(download *Download) {
+	q.update(func() {
+		download.Status = StatusDownloading
+	})
+
+	failed := 0
+	for _, chapter := range download.Chapters {
+		chapter := chapter
+		q.update(func() {
+			chapter.Status = StatusDownloading
+		})
+
+		path, err := downloader.DownloadWith(chapter.chapter, func(string) {}, &downloader.Hooks{
+			OnPages: func(pages []*source.Page) {
+				q.update(func() {
+					chapter.Pages = len(pages)
+				})
+			},
+			OnPage: func(_ *source.Page, err error) {
+				if err == nil {
+					q.update(func() {
+						chapter.Downloaded++
+					})
+				}
+			},
+		})
+
+		q.update(func() {
+			if err != nil {
+				log.Error(err)
+				failed++
+				chapter.Status = StatusFailed
+				chapter.Error = err.Error()
+				return
+			}
+
+			chapter.Status = StatusDone
+			chapter.Path = path
+		})
+	}
+
+	q.update(func() {
+		now := time.Now()
+		download.FinishedAt = &now
+		if failed == len(download.Chapters) {
+			download.Status = StatusFailed
+		} else {
+			download.Status = StatusDone
+		}
+	})
+}
+
+// snapshot copies the download so that it can be encoded outside the lock
+func (d *Download) snapshot() *Download {
+	snapshot := *d
+	snapshot.Chapters = lo.Map(d.Chapters, func(chapter *DownloadChapter, _ int) *DownloadChapter {
+		c := *chapter
+		return &c
+	})
+
+	return &snapshot
+}
@@ -0,0 +1,157 @@
+// Package server implements the REST API of the serve command
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/network"
+	"github.com/metafates/mangal/provider"
+	"github.com/metafates/mangal/source"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Server is the REST API over the sources, downloader and history.
+// All responses are JSON.
+type Server struct {
+	mux   *http.ServeMux
+	token string
+	// host that the server listens on, see network.AllowedHost
+	host string
+
+	sourcesMutex sync.Mutex
+	sources      map[string]source.Source
+
+	downloads *queue
+}
+
+// New creates a new server that listens on the host.
+// If token is not empty, every request must be authorized with it.
+func New(host, token string) *Server {
+	s := &Server{
+		mux:       http.NewServeMux(),
+		token:     token,
+		host:      host,
+		sources:   make(map[string]source.Source),
+		downloads: newQueue(),
+	}
+
+	s.mux.HandleFunc("/api/providers", s.handleProviders)
+	s.mux.HandleFunc("/api/search", s.handleSearch)
+	s.mux.HandleFunc("/api/chapters", s.handleChapters)
+	s.mux.HandleFunc("/api/downloads", s.handleDownloads)
+	s.mux.HandleFunc("/api/downloads/", s.handleDownload)
+	s.mux.HandleFunc("/api/history", s.handleHistory)
+
+	return s
+}
+
+// Handle registers additional handler, e.g. for the web reader
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !network.AllowedHost(r, s.host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("host %s is not allowed", r.Host))
+		return
+	}
+
+	if !network.Authorized(r, s.token) {
+		writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+		return
+	}
+
+	log.Infof("%s %s", r.Method, r.URL.Path)
+	s.mux.ServeHTTP(w, r)
+}
+
+// source returns the source with the given name, creating it on the first use
+func (s *Server) source(name string) (source.Source, error) {
+	s.sourcesMutex.Lock()
+	defer s.sourcesMutex.Unlock()
+
+	if src, ok := s.sources[name]; ok {
+		return src, nil
+	}
+
+	p, ok := provider.Get(name)
+	if !ok {
+		return nil, errs.Newf(errs.SourceNotFound, "source not found: %s", name)
+	}
+
+	src, err := p.CreateSource()
+	if err != nil {
+		return nil, err
+	}
+
+	s.sources[name] = src
+	return src, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Warn(err)
+	}
+}
+
+// errorResponse is the body of the failed request
+type errorResponse struct {
+	Error struct {
+		Kind    string `json:"kind"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	var response errorResponse
+	response.Error.Kind = errs.KindOf(err).String()
+	response.Error.Message = err.Error()
+	writeJSON(w, status, &response)
+}
+
+// statusOf returns http status for the error according to its kind
+func statusOf(err error) int {
+	switch errs.KindOf(err) {
+	case errs.SourceNotFound, errs.NoResults:
+		return http.StatusNotFound
+	case errs.RateLimited:
+		return http.StatusTooManyRequests
+	case errs.Network:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// isJSON writes 415 and returns false if the request body is not JSON.
+// Browsers can send plain text bodies to other origins without asking, but not JSON.
+func isJSON(w http.ResponseWriter, r *http.Request) bool {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "application/json" {
+		return true
+	}
+
+	writeError(w, http.StatusUnsupportedMediaType, errors.New("content type must be application/json"))
+	return false
+}
+
+// allow writes 405 and returns false if the request method is not one of the methods
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	return false
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/errs"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+	viper.Set(key.FormatsUse, constant.FormatCBZ)
+	viper.Set(key.DownloaderChapterNameTemplate, "{chapter}")
+}
+
+type testSource struct{}
+
+func (testSource) Name() string {
+	return "test"
+}
+
+func (testSource) ID() string {
+	return "test"
+}
+
+func (s testSource) Search(query string) ([]*source.Manga, error) {
+	if query == "limit" {
+		return nil, errs.Newf(errs.RateLimited, "too many requests")
+	}
+
+	return []*source.Manga{{Name: "One Piece", URL: "https://example.com/one-piece", Source: s}}, nil
+}
+
+func (testSource) ChaptersOf(manga *source.Manga) ([]*source.Chapter, error) {
+	var chapters []*source.Chapter
+	for i := 1; i <= 3; i++ {
+		chapters = append(chapters, &source.Chapter{
+			Name:  fmt.Sprintf("Chapter %d", i),
+			URL:   fmt.Sprintf("%s/%d", manga.URL, i),
+			Index: uint16(i),
+			Manga: manga,
+		})
+	}
+
+	return chapters, nil
+}
+
+func (testSource) PagesOf(*source.Chapter) ([]*source.Page, error) {
+	return nil, errors.New("no pages")
+}
+
+func newTestServer(token string) *Server {
+	s := New("127.0.0.1", token)
+	s.sources["test"] = testSource{}
+	return s
+}
+
+func request(handler http.Handler, method, target, body string, value any) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Host = "localhost:4280"
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	handler.ServeHTTP(recorder, r)
+
+	if value != nil {
+		_ = json.Unmarshal(recorder.Body.Bytes(), value)
+	}
+
+	return recorder
+}
+
+func TestServer(t *testing.T) {
+	Convey("Given a server", t, func() {
+		s := newTestServer("")
+
+		Convey("When searching", func() {
+			var mangas []*Manga
+			response := request(s, http.MethodGet, "/api/search?source=test&query=one", "", &mangas)
+
+			Convey("Then mangas should be returned", func() {
+				So(response.Code, ShouldEqual, http.StatusOK)
+				So(mangas, ShouldHaveLength, 1)
+				So(mangas[0].Name, ShouldEqual, "One Piece")
+				So(mangas[0].Source, ShouldEqual, "test")
+			})
+		})
+
+		Convey("When the source is rate limited", func() {
+			var response errorResponse
+			recorder := request(s, http.MethodGet, "/api/search?source=test&query=limit", "", &response)
+
+			Convey("Then 429 should be returned with the error kind", func() {
+				So(recorder.Code, ShouldEqual, http.StatusTooManyRequests)
+				So(response.Error.Kind, ShouldEqual, "rate_limited")
+			})
+		})
+
+		Convey("When the source does not exist", func() {
+			recorder := request(s, http.MethodGet, "/api/search?source=nonexistent&query=one", "", nil)
+
+			Convey("Then 404 should be returned", func() {
+				So(recorder.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
+		Convey("When listing chapters", func() {
+			var chapters []*Chapter
+			response := request(s, http.MethodGet, "/api/chapters?source=test&url=https://example.com/one-piece", "", &chapters)
+
+			Convey("Then chapters should be returned", func() {
+				So(response.Code, ShouldEqual, http.StatusOK)
+				So(chapters, ShouldHaveLength, 3)
+				So(chapters[2].Name, ShouldEqual, "Chapter 3")
+			})
+		})
+
+		Convey("When the method is not allowed", func() {
+			recorder := request(s, http.MethodPost, "/api/search", "", nil)
+
+			Convey("Then 405 should be returned", func() {
+				So(recorder.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			})
+		})
+
+		Convey("When the chapters selector is invalid", func() {
+			body := `{"manga": {"source": "test", "url": "https://example.com/one-piece"}, "chapters": "???"}`
+			recorder := request(s, http.MethodPost, "/api/downloads", body, nil)
+
+			Convey("Then 400 should be returned", func() {
+				So(recorder.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Convey("When enqueueing a download", func() {
+			var download Download
+			body := `{"manga": {"source": "test", "url": "https://example.com/one-piece"}, "chapters": "last"}`
+			recorder := request(s, http.MethodPost, "/api/downloads", body, &download)
+
+			Convey("Then it should be accepted with the selected chapters", func() {
+				So(recorder.Code, ShouldEqual, http.StatusAccepted)
+				So(download.ID, ShouldEqual, 1)
+				So(download.Chapters, ShouldHaveLength, 1)
+				So(download.Chapters[0].Name, ShouldEqual, "Chapter 3")
+			})
+
+			Convey("Then its status should be available until it is finished", func() {
+				var status Download
+				deadline := time.Now().Add(5 * time.Second)
+				for status.FinishedAt == nil && time.Now().Before(deadline) {
+					time.Sleep(10 * time.Millisecond)
+					request(s, http.MethodGet, "/api/downloads/1", "", &status)
+				}
+
+				So(status.Status, ShouldEqual, StatusFailed)
+				So(status.Chapters[0].Error, ShouldEqual, "no pages")
+			})
+		})
+
+		Convey("When enqueueing a download with a plain text body", func() {
+			body := `{"manga": {"source": "test", "url": "https://example.com/one-piece"}, "chapters": "last"}`
+			r := httptest.NewRequest(http.MethodPost, "/api/downloads", strings.NewReader(body))
+			r.Host = "localhost:4280"
+			r.Header.Set("Content-Type", "text/plain")
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, r)
+
+			Convey("Then 415 should be returned", func() {
+				So(recorder.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+				So(s.downloads.list(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the request is addressed to another host", func() {
+			r := httptest.NewRequest(http.MethodGet, "/api/history", nil)
+			r.Host = "attacker.example.com:4280"
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, r)
+
+			Convey("Then 403 should be returned", func() {
+				So(recorder.Code, ShouldEqual, http.StatusForbidden)
+			})
+		})
+
+		Convey("When the download does not exist", func() {
+			recorder := request(s, http.MethodGet, "/api/downloads/42", "", nil)
+
+			Convey("Then 404 should be returned", func() {
+				So(recorder.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+
+	Convey("Given a server with the token", t, func() {
+		s := newTestServer("secret")
+
+		Convey("Requests without the token should be unauthorized", func() {
+			So(request(s, http.MethodGet, "/api/providers", "", nil).Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("Requests with the token query parameter should be authorized", func() {
+			So(request(s, http.MethodGet, "/api/providers?token=secret", "", nil).Code, ShouldEqual, http.StatusOK)
+		})
+
+		Convey("Requests with the bearer token should be authorized", func() {
+			recorder := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/providers", nil)
+			r.Host = "localhost:4280"
+			r.Header.Set("Authorization", "Bearer secret")
+			s.ServeHTTP(recorder, r)
+			So(recorder.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
@@ -3,15 +3,19 @@ package source
 import (
 	"fmt"
 	"github.com/dustin/go-humanize"
+	"github.com/metafates/mangal/anilist"
 	"github.com/metafates/mangal/constant"
 	"github.com/metafates/mangal/filesystem"
 	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/style"
 	"github.com/metafates/mangal/util"
+	"github.com/samber/lo"
 	"github.com/samber/mo"
 	"github.com/spf13/viper"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -29,6 +33,14 @@ type Chapter struct {
 	ID string `json:"id" jsonschema:"description=ID of the chapter in the source"`
 	// Volume which the chapter belongs to.
 	Volume string `json:"volume" jsonschema:"description=Volume which the chapter belongs to"`
+	// Number of the chapter set by the source, e.g. "10.5". If empty, it is parsed from the name.
+	Number string `json:"number" jsonschema:"description=Number of the chapter set by the source"`
+	// Language code of the chapter, e.g. "en"
+	Language string `json:"language" jsonschema:"description=Language code of the chapter"`
+	// Group is the scanlation group that translated the chapter
+	Group string `json:"group" jsonschema:"description=Scanlation group that translated the chapter"`
+	// Date when the chapter was uploaded
+	Date date `json:"date" jsonschema:"description=Date when the chapter was uploaded"`
 	// Manga that the chapter belongs to.
 	Manga *Manga `json:"-"`
 	// Pages of the chapter.
@@ -42,9 +54,48 @@ func (c *Chapter) String() string {
 	return c.Name
 }
 
+var (
+	chapterNumberRegex = regexp.MustCompile(`(?i)ch(?:apter|\.)?\s*(\d+(?:\.\d+)?)`)
+	numberRegex        = regexp.MustCompile(`\d+(?:\.\d+)?`)
+)
+
+// ParsedNumber returns the chapter number set by the source or parsed from its name, e.g. 10.5 for "Chapter 10.5".
+// Falls back to the chapter index if the name has no number.
+func (c *Chapter) ParsedNumber() float64 {
+	if number := numberRegex.FindString(c.Number); number != "" {
+		return lo.Must(strconv.ParseFloat(number, 64))
+	}
+
+	if groups := chapterNumberRegex.FindStringSubmatch(c.Name); groups != nil {
+		return lo.Must(strconv.ParseFloat(groups[1], 64))
+	}
+
+	if number := numberRegex.FindString(c.Name); number != "" {
+		return lo.Must(strconv.ParseFloat(number, 64))
+	}
+
+	return float64(c.Index)
+}
+
+// VolumeNumber returns the volume number parsed from the volume name, e.g. 3 for "Vol. 3"
+func (c *Chapter) VolumeNumber() mo.Option[float64] {
+	if number := numberRegex.FindString(c.Volume); number != "" {
+		return mo.Some(lo.Must(strconv.ParseFloat(number, 64)))
+	}
+
+	return mo.None[float64]()
+}
+
 // DownloadPages downloads the Pages contents of the Chapter.
 // Pages needs to be set before calling this function.
 func (c *Chapter) DownloadPages(temp bool, progress func(string)) (err error) {
+	return c.DownloadPagesWith(temp, progress, nil)
+}
+
+// DownloadPagesWith downloads the Pages contents of the Chapter
+// and calls onPage after each page is downloaded, if not nil.
+// Pages needs to be set before calling this function.
+func (c *Chapter) DownloadPagesWith(temp bool, progress func(string), onPage func(page *Page, err error)) (err error) {
 	c.size = 0
 	status := func() string {
 		return fmt.Sprintf(
@@ -71,9 +122,17 @@ func (c *Chapter) DownloadPages(temp bool, progress func(string)) (err error) {
 				return
 			}
 
-			err = page.Download()
+			pageErr := page.Download()
+			if pageErr != nil {
+				err = pageErr
+			}
+
 			c.size += page.Size
 			progress(status())
+
+			if onPage != nil {
+				onPage(page, pageErr)
+			}
 		}
 
 		if viper.GetBool(key.DownloaderAsync) {
@@ -95,8 +154,13 @@ func (c *Chapter) DownloadPages(temp bool, progress func(string)) (err error) {
 }
 
 // formattedName of the chapter according to the template in the config.
-func (c *Chapter) formattedName() (name string) {
-	name = viper.GetString(key.DownloaderChapterNameTemplate)
+func (c *Chapter) formattedName() string {
+	return c.FormatName(viper.GetString(key.DownloaderChapterNameTemplate))
+}
+
+// FormatName replaces variables of the template with the chapter values, e.g. {chapter} with its name.
+func (c *Chapter) FormatName(template string) (name string) {
+	name = template
 
 	var sourceName string
 	if c.Source() != nil {
@@ -140,8 +204,7 @@ func (c *Chapter) IsDownloaded() bool {
 		return c.isDownloaded.MustGet()
 	}
 
-	path, _ := c.path(c.Manga.peekPath(), false)
-	exists, _ := filesystem.Api().Exists(path)
+	exists, _ := filesystem.Api().Exists(c.PeekPath())
 	c.isDownloaded = mo.Some(exists)
 	return exists
 }
@@ -169,22 +232,66 @@ func (c *Chapter) Path(temp bool) (path string, err error) {
 	return c.path(manga, c.Volume != "" && viper.GetBool(key.DownloaderCreateVolumeDir))
 }
 
+// PeekPath returns the same path as Path(false) would, but without creating any directories
+func (c *Chapter) PeekPath() string {
+	path, _ := c.path(c.Manga.peekPath(), false)
+	return path
+}
+
 func (c *Chapter) Source() Source {
 	return c.Manga.Source
 }
 
+// TrackerIndex returns the chapter number on Anilist
+// according to the chapter mapping rules of the manga.
+// Returns false if the chapter should not be tracked.
+func (c *Chapter) TrackerIndex() (int, bool) {
+	return anilist.MapChapter(c.Manga.Name, int(c.Index), c.Volume)
+}
+
+// ComicInfoOptions control the ComicInfo fields that depend on the configuration
+type ComicInfoOptions struct {
+	// AddDate adds the release date of the chapter, or of the manga if the chapter has none
+	AddDate bool
+	// AlternativeDate uses the current date instead of the release date
+	AlternativeDate bool
+	// MapChapters numbers the chapter by the tracker mapping rules of the manga
+	MapChapters bool
+}
+
+// ComicInfo returns the ComicInfo of the chapter with the options from the config
 func (c *Chapter) ComicInfo() *ComicInfo {
+	return c.ComicInfoWith(ComicInfoOptions{
+		AddDate:         viper.GetBool(key.MetadataComicInfoXMLAddDate),
+		AlternativeDate: viper.GetBool(key.MetadataComicInfoXMLAlternativeDate),
+		MapChapters:     true,
+	})
+}
+
+// ComicInfoWith returns the ComicInfo of the chapter with the given options
+func (c *Chapter) ComicInfoWith(options ComicInfoOptions) *ComicInfo {
 	var (
 		day, month, year int
 	)
 
-	if viper.GetBool(key.MetadataComicInfoXMLAddDate) {
-		if viper.GetBool(key.MetadataComicInfoXMLAlternativeDate) {
+	number := int(c.Index)
+	if options.MapChapters {
+		if mapped, ok := c.TrackerIndex(); ok {
+			number = mapped
+		}
+	}
+
+	if options.AddDate {
+		if options.AlternativeDate {
 			// get current date
 			t := time.Now()
 			day = t.Day()
 			month = int(t.Month())
 			year = t.Year()
+		} else if c.Date.Year != 0 {
+			day = c.Date.Day
+			month = c.Date.Month
+			year = c.Date.Year
 		} else {
 			day = c.Manga.Metadata.StartDate.Day
 			month = c.Manga.Metadata.StartDate.Month
@@ -192,28 +299,34 @@ func (c *Chapter) ComicInfo() *ComicInfo {
 		}
 	} // empty dates will be omitted
 
+	translator := strings.Join(c.Manga.Metadata.Staff.Translation, ",")
+	if c.Group != "" {
+		translator = c.Group
+	}
+
 	return &ComicInfo{
 		XmlnsXsd: "http://www.w3.org/2001/XMLSchema",
 		XmlnsXsi: "http://www.w3.org/2001/XMLSchema-instance",
 
-		Title:      c.Name,
-		Series:     c.Manga.Name,
-		Number:     int(c.Index),
-		Web:        c.URL,
-		Genre:      strings.Join(c.Manga.Metadata.Genres, ","),
-		PageCount:  len(c.Pages),
-		Summary:    c.Manga.Metadata.Summary,
-		Count:      c.Manga.Metadata.Chapters,
-		Characters: strings.Join(c.Manga.Metadata.Characters, ","),
-		Year:       year,
-		Month:      month,
-		Day:        day,
-		Writer:     strings.Join(c.Manga.Metadata.Staff.Story, ","),
-		Penciller:  strings.Join(c.Manga.Metadata.Staff.Art, ","),
-		Letterer:   strings.Join(c.Manga.Metadata.Staff.Lettering, ","),
-		Translator: strings.Join(c.Manga.Metadata.Staff.Translation, ","),
-		Tags:       strings.Join(c.Manga.Metadata.Tags, ","),
-		Notes:      "Downloaded with Mangal. https://github.com/metafates/mangal",
-		Manga:      "YesAndRightToLeft",
+		Title:       c.Name,
+		Series:      c.Manga.Name,
+		Number:      number,
+		Web:         c.URL,
+		Genre:       strings.Join(c.Manga.Metadata.Genres, ","),
+		PageCount:   len(c.Pages),
+		Summary:     c.Manga.Metadata.Summary,
+		Count:       c.Manga.Metadata.Chapters,
+		Characters:  strings.Join(c.Manga.Metadata.Characters, ","),
+		Year:        year,
+		Month:       month,
+		Day:         day,
+		Writer:      strings.Join(c.Manga.Metadata.Staff.Story, ","),
+		Penciller:   strings.Join(c.Manga.Metadata.Staff.Art, ","),
+		Letterer:    strings.Join(c.Manga.Metadata.Staff.Lettering, ","),
+		Translator:  translator,
+		Tags:        strings.Join(c.Manga.Metadata.Tags, ","),
+		LanguageISO: c.Language,
+		Notes:       "Downloaded with Mangal. https://github.com/metafates/mangal",
+		Manga:       "YesAndRightToLeft",
 	}
 }
@@ -26,5 +26,7 @@ type ComicInfo struct {
 	Translator string `xml:"Translator,omitempty"`
 	Tags       string `xml:"Tags,omitempty"`
 	Notes      string `xml:"Notes,omitempty"`
-	Manga      string `xml:"Manga,omitempty"`
+	// LanguageISO is the language code of the chapter
+	LanguageISO string `xml:"LanguageISO,omitempty"`
+	Manga       string `xml:"Manga,omitempty"`
 }
@@ -0,0 +1,130 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	_ "golang.org/x/image/webp"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+// Descrambler is implemented by the sources that serve scrambled page images.
+// Descramble is called after the page is downloaded and before it reaches the converter.
+// It may replace the page contents or set the page tiles, which are applied after it.
+type Descrambler interface {
+	Descramble(page *Page) error
+}
+
+// Tiles describe how the scrambled page image is reassembled.
+// The image is split into the grid of Columns x Rows tiles, numbered from 1 left to right, top to bottom.
+// Order lists the numbers of the scrambled tiles for each position of the original image.
+// If the image size is not divisible by the grid, the remaining pixels on the right and bottom edges are kept in place.
+type Tiles struct {
+	Columns int   `json:"columns" jsonschema:"description=Number of the tile columns."`
+	Rows    int   `json:"rows" jsonschema:"description=Number of the tile rows."`
+	Order   []int `json:"order" jsonschema:"description=Numbers of the scrambled tiles for each position of the original image."`
+}
+
+// Validate checks that the order is a permutation of the tiles
+func (t *Tiles) Validate() error {
+	if t.Columns < 1 || t.Rows < 1 {
+		return fmt.Errorf("tiles grid must be at least 1x1, got %dx%d", t.Columns, t.Rows)
+	}
+
+	count := t.Columns * t.Rows
+	if len(t.Order) != count {
+		return fmt.Errorf("tiles order must have %d tiles, got %d", count, len(t.Order))
+	}
+
+	seen := make(map[int]bool, count)
+	for i, tile := range t.Order {
+		if tile < 1 || tile > count {
+			return fmt.Errorf("tile at index %d must be from 1 to %d, got %d", i+1, count, tile)
+		}
+
+		if seen[tile] {
+			return fmt.Errorf("tile at index %d is repeated: %d", i+1, tile)
+		}
+
+		seen[tile] = true
+	}
+
+	return nil
+}
+
+// Apply reassembles the image and returns it encoded together with its format.
+// JPEG images stay JPEG, other formats are encoded as PNG.
+func (t *Tiles) Apply(contents []byte) ([]byte, string, error) {
+	if err := t.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	scrambled, format, err := image.Decode(bytes.NewReader(contents))
+	if err != nil {
+		return nil, "", err
+	}
+
+	bounds := scrambled.Bounds()
+	original := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(original, original.Bounds(), scrambled, bounds.Min, draw.Src)
+
+	width, height := bounds.Dx()/t.Columns, bounds.Dy()/t.Rows
+	tile := func(number int) image.Rectangle {
+		column, row := number%t.Columns, number/t.Columns
+		return image.Rect(column*width, row*height, (column+1)*width, (row+1)*height)
+	}
+
+	for position, number := range t.Order {
+		from := tile(number - 1).Add(bounds.Min)
+		draw.Draw(original, tile(position), scrambled, from.Min, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, original, &jpeg.Options{Quality: 95})
+	} else {
+		format = "png"
+		err = png.Encode(&buf, original)
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), format, nil
+}
+
+// descramble calls the descrambler of the page source, if any, and applies the page tiles
+func (p *Page) descramble() error {
+	if p.Chapter != nil && p.Chapter.Manga != nil {
+		if descrambler, ok := p.Chapter.Manga.Source.(Descrambler); ok {
+			if err := descrambler.Descramble(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.Tiles == nil {
+		return nil
+	}
+
+	contents, format, err := p.Tiles.Apply(p.Contents.Bytes())
+	if err != nil {
+		return fmt.Errorf("page #%d: %w", p.Index, err)
+	}
+
+	p.Contents = bytes.NewBuffer(contents)
+	p.Size = uint64(len(contents))
+
+	switch extension := strings.ToLower(p.Extension); {
+	case format == "png":
+		p.Extension = ".png"
+	case extension != ".jpg" && extension != ".jpeg":
+		p.Extension = ".jpg"
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package source
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// quadrants returns the PNG image of 2x2 tiles of the given colors
+func quadrants(colors ...uint8) []byte {
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	for i, c := range colors {
+		for x := 0; x < 2; x++ {
+			for y := 0; y < 2; y++ {
+				img.SetGray(i%2*2+x, i/2*2+y, color.Gray{Y: c})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestTiles_Apply(t *testing.T) {
+	Convey("Given the scrambled image of 2x2 tiles", t, func() {
+		scrambled := quadrants(40, 30, 20, 10)
+
+		Convey("When the tiles are applied", func() {
+			tiles := &Tiles{Columns: 2, Rows: 2, Order: []int{4, 3, 2, 1}}
+			contents, format, err := tiles.Apply(scrambled)
+
+			Convey("Then the image should be reassembled", func() {
+				So(err, ShouldBeNil)
+				So(format, ShouldEqual, "png")
+
+				img, _, err := image.Decode(bytes.NewReader(contents))
+				So(err, ShouldBeNil)
+
+				for i, expected := range []uint8{10, 20, 30, 40} {
+					So(color.GrayModel.Convert(img.At(i%2*2, i/2*2)).(color.Gray).Y, ShouldEqual, expected)
+				}
+			})
+		})
+
+		Convey("When the order is not a permutation of the tiles", func() {
+			for _, tiles := range []*Tiles{
+				{Columns: 2, Rows: 2, Order: []int{1, 2, 3}},
+				{Columns: 2, Rows: 2, Order: []int{1, 2, 3, 5}},
+				{Columns: 2, Rows: 2, Order: []int{1, 2, 2, 4}},
+				{Columns: 0, Rows: 2},
+			} {
+				_, _, err := tiles.Apply(scrambled)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestPage_Descramble(t *testing.T) {
+	Convey("Given a page with tiles", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(quadrants(40, 30, 20, 10))
+		}))
+		defer server.Close()
+
+		page := &Page{
+			URL:       server.URL + "/1.webp",
+			Extension: ".webp",
+			Chapter:   &Chapter{URL: "https://example.com/chapter"},
+			Tiles:     &Tiles{Columns: 2, Rows: 2, Order: []int{4, 3, 2, 1}},
+		}
+
+		Convey("When it is downloaded", func() {
+			err := page.DownloadWith(http.DefaultClient)
+
+			Convey("Then the contents should be reassembled before they are read", func() {
+				So(err, ShouldBeNil)
+				So(page.Extension, ShouldEqual, ".png")
+				So(page.Size, ShouldEqual, page.Contents.Len())
+
+				img, err := png.Decode(bytes.NewReader(page.Contents.Bytes()))
+				So(err, ShouldBeNil)
+				So(color.GrayModel.Convert(img.At(0, 0)).(color.Gray).Y, ShouldEqual, 10)
+			})
+		})
+	})
+}
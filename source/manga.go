@@ -1,11 +1,15 @@
 package source
 
 import (
+	"errors"
 	"fmt"
 	"github.com/metafates/mangal/anilist"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/filesystem"
 	"github.com/metafates/mangal/key"
 	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/network"
 	"github.com/metafates/mangal/util"
 	"github.com/metafates/mangal/where"
 	"github.com/samber/lo"
@@ -13,6 +17,7 @@ import (
 	"github.com/spf13/viper"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -67,6 +72,10 @@ type Manga struct {
 			Medium string `json:"medium" jsonschema:"description=Medium cover image. The smallest one."`
 			// Color average color of the cover image.
 			Color string `json:"color" jsonschema:"description=Color average color of the cover image."`
+			// Headers to send with the cover request. Set by the source for its own cover images.
+			Headers map[string]string `json:"headers,omitempty" jsonschema:"description=Headers to send with the cover request."`
+			// Cookies to send with the cover request. Set by the source for its own cover images.
+			Cookies map[string]string `json:"cookies,omitempty" jsonschema:"description=Cookies to send with the cover request."`
 		} `json:"cover" jsonschema:"description=Cover images of the manga"`
 		// BannerImage is the banner image of the manga.
 		BannerImage string `json:"bannerImage" jsonschema:"description=BannerImage is the banner image of the manga."`
@@ -177,7 +186,21 @@ func (m *Manga) DownloadCover(overwrite bool, path string, progress func(string)
 		}
 	}
 
-	resp, err := http.Get(cover)
+	req, err := http.NewRequest(http.MethodGet, cover, nil)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	// the source is the referer only for its own covers, e.g. not for the Anilist ones
+	if m.isSourceCover(cover) {
+		req.Header.Set("Referer", m.URL)
+	}
+
+	req.Header.Set("User-Agent", constant.UserAgent)
+	setHeaders(req, m.Metadata.Cover.Headers, m.Metadata.Cover.Cookies)
+
+	resp, err := network.Client.Do(req)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -186,6 +209,7 @@ func (m *Manga) DownloadCover(overwrite bool, path string, progress func(string)
 	defer util.Ignore(resp.Body.Close)
 
 	if resp.StatusCode != http.StatusOK {
+		err = errs.New(errs.Network, errors.New("http error: "+resp.Status))
 		log.Error(err)
 		return err
 	}
@@ -206,6 +230,26 @@ func (m *Manga) DownloadCover(overwrite bool, path string, progress func(string)
 	return nil
 }
 
+// isSourceCover checks if the cover was set by the source, not taken from the metadata of other services.
+// Source covers either have headers or cookies set or are on the same host as the manga.
+func (m *Manga) isSourceCover(cover string) bool {
+	if len(m.Metadata.Cover.Headers) > 0 || len(m.Metadata.Cover.Cookies) > 0 {
+		return true
+	}
+
+	coverURL, err := url.Parse(cover)
+	if err != nil {
+		return false
+	}
+
+	mangaURL, err := url.Parse(m.URL)
+	if err != nil {
+		return false
+	}
+
+	return coverURL.Hostname() != "" && strings.EqualFold(coverURL.Hostname(), mangaURL.Hostname())
+}
+
 func (m *Manga) BindWithAnilist() error {
 	if m.Anilist.IsPresent() {
 		return nil
@@ -273,6 +317,9 @@ func (m *Manga) PopulateMetadata(progress func(string)) error {
 	m.Metadata.Cover.Large = manga.CoverImage.Large
 	m.Metadata.Cover.Medium = manga.CoverImage.Medium
 	m.Metadata.Cover.Color = manga.CoverImage.Color
+	// headers of the source must not be sent to anilist
+	m.Metadata.Cover.Headers = nil
+	m.Metadata.Cover.Cookies = nil
 
 	m.Metadata.BannerImage = manga.BannerImage
 
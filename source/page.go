@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/errs"
 	"github.com/metafates/mangal/log"
 	"github.com/metafates/mangal/network"
 	"github.com/metafates/mangal/util"
 	_ "image/gif"
 	"io"
 	"net/http"
+	"strings"
 )
 
 // Page represents a page in a chapter
@@ -21,6 +23,12 @@ type Page struct {
 	Index uint16 `json:"index" jsonschema:"description=Index of the page in the chapter."`
 	// Extension of the page image.
 	Extension string `json:"extension" jsonschema:"description=Extension of the page image."`
+	// Headers to send with the page request. They override the default Referer and User-Agent.
+	Headers map[string]string `json:"headers,omitempty" jsonschema:"description=Headers to send with the page request. They override the default Referer and User-Agent."`
+	// Cookies to send with the page request.
+	Cookies map[string]string `json:"cookies,omitempty" jsonschema:"description=Cookies to send with the page request."`
+	// Tiles to reassemble the scrambled page image after it is downloaded.
+	Tiles *Tiles `json:"tiles,omitempty" jsonschema:"description=Tiles to reassemble the scrambled page image after it is downloaded."`
 	// Size of the page in bytes
 	Size uint64 `json:"-"`
 	// Contents of the page
@@ -38,11 +46,34 @@ func (p *Page) request() (*http.Request, error) {
 
 	req.Header.Set("Referer", p.Chapter.URL)
 	req.Header.Set("User-Agent", constant.UserAgent)
+	setHeaders(req, p.Headers, p.Cookies)
 	return req, nil
 }
 
+// setHeaders sets the headers and cookies of the request, overriding the headers that are already set
+func setHeaders(req *http.Request, headers, cookies map[string]string) {
+	for name, value := range headers {
+		// the host header is ignored by the client, it is taken from the request field
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+
+		req.Header.Set(name, value)
+	}
+
+	for name, value := range cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
+
 // Download Page contents.
 func (p *Page) Download() error {
+	return p.DownloadWith(network.Client)
+}
+
+// DownloadWith downloads Page contents using the given http client.
+func (p *Page) DownloadWith(client *http.Client) error {
 	if p.URL == "" {
 		log.Warnf("Page #%d has no URL", p.Index)
 		return nil
@@ -55,7 +86,7 @@ func (p *Page) Download() error {
 		return err
 	}
 
-	resp, err := network.Client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -64,13 +95,18 @@ func (p *Page) Download() error {
 	defer util.Ignore(resp.Body.Close)
 
 	if resp.StatusCode != http.StatusOK {
-		err = errors.New("http error: " + resp.Status)
+		kind := errs.Network
+		if resp.StatusCode == http.StatusTooManyRequests {
+			kind = errs.RateLimited
+		}
+
+		err = errs.New(kind, errors.New("http error: "+resp.Status))
 		log.Error(err)
 		return err
 	}
 
 	if resp.ContentLength == 0 {
-		err = errors.New("http error: nothing was returned")
+		err = errs.Newf(errs.Network, "http error: nothing was returned")
 		log.Error(err)
 		return err
 	}
@@ -97,6 +133,11 @@ func (p *Page) Download() error {
 	p.Contents = bytes.NewBuffer(buf)
 	p.Size = uint64(util.Max(contentLength, 0))
 
+	if err = p.descramble(); err != nil {
+		log.Error(err)
+		return err
+	}
+
 	log.Tracef("Page #%d downloaded", p.Index)
 	return nil
 }
@@ -1 +1,124 @@
 package source
+
+import (
+	"github.com/metafates/mangal/filesystem"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// requestEcho responds with the image if the request has the expected headers and cookies
+func requestEcho(received *http.Request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = *r.Clone(r.Context())
+		_, _ = w.Write([]byte("image"))
+	}))
+}
+
+func TestPage_Download(t *testing.T) {
+	Convey("Given a page with headers and cookies", t, func() {
+		var received http.Request
+		server := requestEcho(&received)
+		defer server.Close()
+
+		page := &Page{
+			URL:     server.URL + "/1.png",
+			Chapter: &Chapter{URL: "https://example.com/chapter"},
+			Headers: map[string]string{"Referer": "https://example.com/reader", "Authorization": "Bearer token"},
+			Cookies: map[string]string{"session": "secret"},
+		}
+
+		Convey("When it is downloaded", func() {
+			err := page.DownloadWith(http.DefaultClient)
+
+			Convey("Then the headers should override the defaults and the cookies should be sent", func() {
+				So(err, ShouldBeNil)
+				So(page.Contents.String(), ShouldEqual, "image")
+				So(received.Header.Get("Referer"), ShouldEqual, "https://example.com/reader")
+				So(received.Header.Get("Authorization"), ShouldEqual, "Bearer token")
+				So(received.Header.Get("User-Agent"), ShouldNotBeEmpty)
+
+				cookie, err := received.Cookie("session")
+				So(err, ShouldBeNil)
+				So(cookie.Value, ShouldEqual, "secret")
+			})
+		})
+	})
+
+	Convey("Given a page without headers", t, func() {
+		var received http.Request
+		server := requestEcho(&received)
+		defer server.Close()
+
+		page := &Page{URL: server.URL + "/1.png", Chapter: &Chapter{URL: "https://example.com/chapter"}}
+
+		Convey("When it is downloaded", func() {
+			So(page.DownloadWith(http.DefaultClient), ShouldBeNil)
+
+			Convey("Then the chapter should be the referer", func() {
+				So(received.Header.Get("Referer"), ShouldEqual, "https://example.com/chapter")
+			})
+		})
+	})
+}
+
+func TestManga_DownloadCover(t *testing.T) {
+	Convey("Given a manga with cover headers", t, func() {
+		var received http.Request
+		server := requestEcho(&received)
+		defer server.Close()
+
+		manga := &Manga{Name: "Cover", URL: "https://example.com/manga"}
+		manga.Metadata.Cover.ExtraLarge = server.URL + "/cover.png"
+		manga.Metadata.Cover.Headers = map[string]string{"X-Token": "token"}
+		manga.Metadata.Cover.Cookies = map[string]string{"session": "secret"}
+
+		Convey("When the cover is downloaded", func() {
+			err := manga.DownloadCover(true, "/covers", func(string) {})
+
+			Convey("Then the cover should be requested with them", func() {
+				So(err, ShouldBeNil)
+				So(received.Header.Get("X-Token"), ShouldEqual, "token")
+				So(received.Header.Get("Referer"), ShouldEqual, "https://example.com/manga")
+
+				cookie, err := received.Cookie("session")
+				So(err, ShouldBeNil)
+				So(cookie.Value, ShouldEqual, "secret")
+
+				contents, err := filesystem.Api().ReadFile(filepath.Join("/covers", "cover.png"))
+				So(err, ShouldBeNil)
+				So(string(contents), ShouldEqual, "image")
+			})
+		})
+	})
+
+	Convey("Given a manga with the cover from other service", t, func() {
+		var received http.Request
+		server := requestEcho(&received)
+		defer server.Close()
+
+		manga := &Manga{Name: "Cover", URL: "https://example.com/manga"}
+		manga.Metadata.Cover.ExtraLarge = server.URL + "/cover.png"
+
+		Convey("When the cover is downloaded", func() {
+			err := manga.DownloadCover(true, "/covers", func(string) {})
+
+			Convey("Then the source should not be sent as the referer", func() {
+				So(err, ShouldBeNil)
+				So(received.Header.Get("Referer"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the cover is on the host of the manga", func() {
+			manga.URL = server.URL + "/manga"
+			err := manga.DownloadCover(true, "/covers", func(string) {})
+
+			Convey("Then the manga should be the referer", func() {
+				So(err, ShouldBeNil)
+				So(received.Header.Get("Referer"), ShouldEqual, manga.URL)
+			})
+		})
+	})
+}
@@ -0,0 +1,66 @@
+package source
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLResolver is implemented by sources that can get manga
+// and chapters directly from their URLs without searching.
+type URLResolver interface {
+	Source
+
+	// Owns returns true if the URL belongs to the source
+	Owns(url string) bool
+	// Resolve returns the manga of the URL.
+	// If the URL points to a chapter, the chapter is returned as well, otherwise it is nil.
+	Resolve(url string) (*Manga, *Chapter, error)
+}
+
+// SameURL returns true if both URLs point to the same page.
+// Scheme, "www." prefix, and trailing slashes are ignored.
+func SameURL(a, b string) bool {
+	normalize := func(address string) string {
+		address = strings.TrimSpace(address)
+		if parsed, err := url.Parse(address); err == nil && parsed.Host != "" {
+			address = parsed.Host + parsed.RequestURI()
+		}
+
+		address = strings.TrimPrefix(address, "www.")
+		return strings.TrimRight(address, "/")
+	}
+
+	return normalize(a) == normalize(b)
+}
+
+// SameHost returns true if both URLs have the same host, ignoring "www." prefix
+func SameHost(a, b string) bool {
+	host := func(address string) string {
+		parsed, err := url.Parse(address)
+		if err != nil {
+			return ""
+		}
+
+		return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	}
+
+	hostA := host(a)
+	return hostA != "" && hostA == host(b)
+}
+
+// FindChapter searches the chapters of the manga for the one with the given URL
+func FindChapter(manga *Manga, url string) (*Chapter, error) {
+	chapters, err := manga.Source.ChaptersOf(manga)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chapter := range chapters {
+		if SameURL(chapter.URL, url) {
+			return chapter, nil
+		}
+	}
+
+	return nil, fmt.Errorf("chapter %s not found in %s", url, manga.Name)
+}
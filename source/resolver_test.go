@@ -0,0 +1,39 @@
+package source
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestSameURL(t *testing.T) {
+	Convey("Given two URLs", t, func() {
+		Convey("When they differ only in scheme, www and trailing slash", func() {
+			Convey("Then they should be the same", func() {
+				So(SameURL("https://www.example.com/manga/1/", "http://example.com/manga/1"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When they have different paths", func() {
+			Convey("Then they should not be the same", func() {
+				So(SameURL("https://example.com/manga/1", "https://example.com/manga/2"), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestSameHost(t *testing.T) {
+	Convey("Given two URLs", t, func() {
+		Convey("When they are on the same host", func() {
+			Convey("Then SameHost should be true", func() {
+				So(SameHost("https://www.Example.com/a", "https://example.com/b"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When one of them is not a URL", func() {
+			Convey("Then SameHost should be false", func() {
+				So(SameHost("one piece", "https://example.com"), ShouldBeFalse)
+				So(SameHost("", ""), ShouldBeFalse)
+			})
+		})
+	})
+}
@@ -50,11 +50,15 @@ type statefulBubble struct {
 	selectedManga     *source.Manga
 	selectedChapters  map[*source.Chapter]struct{} // mathematical set
 
+	// resolvedChapter is the chapter that was opened by its URL
+	resolvedChapter mo.Option[*source.Chapter]
+
 	scrapersLoadedChannel       chan []*installer.Scraper
 	scraperInstalledChannel     chan *installer.Scraper
 	sourcesLoadedChannel        chan []source.Source
 	foundMangasChannel          chan []*source.Manga
 	foundChaptersChannel        chan []*source.Chapter
+	resolvedURLChannel          chan *resolvedURL
 	fetchedAnilistMangasChannel chan []*anilist.Manga
 	closestAnilistMangaChannel  chan *anilist.Manga
 	chapterReadChannel          chan struct{}
@@ -176,6 +180,7 @@ func newBubble() *statefulBubble {
 		sourcesLoadedChannel:        make(chan []source.Source),
 		foundMangasChannel:          make(chan []*source.Manga),
 		foundChaptersChannel:        make(chan []*source.Chapter),
+		resolvedURLChannel:          make(chan *resolvedURL),
 		fetchedAnilistMangasChannel: make(chan []*anilist.Manga),
 		closestAnilistMangaChannel:  make(chan *anilist.Manga),
 		chapterReadChannel:          make(chan struct{}),
@@ -187,6 +187,41 @@ func (b *statefulBubble) waitForMangas() tea.Cmd {
 	}
 }
 
+// resolvedURL is the manga and optionally its chapter opened by the URL
+type resolvedURL struct {
+	manga   *source.Manga
+	chapter *source.Chapter
+}
+
+func (b *statefulBubble) resolveURL(url string) tea.Cmd {
+	return func() tea.Msg {
+		log.Info("resolving " + url)
+		b.progressStatus = "Opening " + url
+
+		manga, chapter, err := provider.Resolve(url)
+		if err != nil {
+			log.Error(err)
+			b.errorChannel <- err
+		} else {
+			b.resolvedURLChannel <- &resolvedURL{manga: manga, chapter: chapter}
+		}
+
+		return nil
+	}
+}
+
+func (b *statefulBubble) waitForResolvedURL() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case resolved := <-b.resolvedURLChannel:
+			return resolved
+		case err := <-b.errorChannel:
+			b.lastError = err
+			return err
+		}
+	}
+}
+
 func (b *statefulBubble) getChapters(manga *source.Manga) tea.Cmd {
 	return func() tea.Msg {
 		log.Info("getting chapters of " + manga.Name)
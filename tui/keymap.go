@@ -18,6 +18,7 @@ type statefulKeymap struct {
 	redownloadFailed,
 	confirm,
 	openURL,
+	pasteURL,
 	read,
 	openFolder,
 	back,
@@ -73,6 +74,10 @@ func newStatefulKeymap() *statefulKeymap {
 			keys("o"),
 			help("o", "open url"),
 		),
+		pasteURL: k(
+			keys("ctrl+v"),
+			help("ctrl+v", "paste url"),
+		),
 		read: k(
 			keys("r"),
 			help(style.Fg(color.Orange)("r"), style.Fg(color.Orange)("read")),
@@ -155,7 +160,7 @@ func (k *statefulKeymap) help() ([]key.Binding, []key.Binding) {
 		search := withDescription(k.confirm, "search with selected")
 		return h(k.selectOne, k.selectAll, search), h(k.selectOne, k.selectAll, k.clearSelection, search)
 	case searchState:
-		return to2(h(k.confirm, k.acceptSearchSuggestion, k.forceQuit))
+		return to2(h(k.confirm, k.acceptSearchSuggestion, k.pasteURL, k.forceQuit))
 	case mangasState:
 		return to2(h(k.confirm, k.back, k.openURL))
 	case chaptersState:
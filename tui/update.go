@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
@@ -21,6 +22,8 @@ import (
 	"github.com/samber/mo"
 	"github.com/spf13/viper"
 	"golang.org/x/exp/slices"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -206,6 +209,16 @@ func (b *statefulBubble) updateLoading(msg tea.Msg) (tea.Model, tea.Cmd) {
 		b.newState(scrapersInstallState)
 		b.scrapersInstallC.NewStatusMessage(fmt.Sprintf("Installed %s", msg.Name))
 		return b, b.stopLoading()
+	case *resolvedURL:
+		b.selectedManga = msg.manga
+		b.resolvedChapter = mo.None[*source.Chapter]()
+		if msg.chapter != nil {
+			b.resolvedChapter = mo.Some(msg.chapter)
+		}
+
+		cmds = append(cmds, b.mangasC.SetItems([]list.Item{&listItem{internal: msg.manga}}))
+		b.newState(mangasState)
+		cmds = append(cmds, b.startLoading(), b.getChapters(msg.manga), b.waitForChapters())
 	case []*source.Manga:
 		items := make([]list.Item, len(msg))
 		for i, m := range msg {
@@ -391,6 +404,18 @@ func (b *statefulBubble) updateSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch {
+		case key.Matches(msg, b.keymap.confirm) && isURL(b.inputC.Value()):
+			b.startLoading()
+			b.newState(loadingState)
+			return b, tea.Batch(b.resolveURL(strings.TrimSpace(b.inputC.Value())), b.waitForResolvedURL(), b.spinnerC.Tick)
+		case key.Matches(msg, b.keymap.pasteURL):
+			// paste as usual if clipboard has no url
+			if pasted, err := clipboard.ReadAll(); err == nil && isURL(pasted) {
+				b.inputC.SetValue(strings.TrimSpace(pasted))
+				b.startLoading()
+				b.newState(loadingState)
+				return b, tea.Batch(b.resolveURL(b.inputC.Value()), b.waitForResolvedURL(), b.spinnerC.Tick)
+			}
 		case key.Matches(msg, b.keymap.confirm) && b.inputC.Value() != "":
 			b.startLoading()
 			b.newState(loadingState)
@@ -464,6 +489,13 @@ func (b *statefulBubble) updateMangas(msg tea.Msg) (tea.Model, tea.Cmd) {
 		b.newState(chaptersState)
 		b.stopLoading()
 
+		if resolved, ok := b.resolvedChapter.Get(); ok {
+			b.resolvedChapter = mo.None[*source.Chapter]()
+			cmd = tea.Batch(cmd, b.selectChapterBy(func(chapter *source.Chapter) bool {
+				return source.SameURL(chapter.URL, resolved.URL)
+			}))
+		}
+
 		if viper.GetBool(key2.AnilistLinkOnMangaSelect) {
 			return b, tea.Batch(cmd, b.fetchAndSetAnilist(b.selectedManga), b.waitForAnilistFetchAndSet())
 		}
@@ -742,3 +774,9 @@ func (b *statefulBubble) updateError(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	return b, cmd
 }
+
+// isURL returns true if the text is an absolute http(s) URL
+func isURL(text string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(text))
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
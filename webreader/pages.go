@@ -0,0 +1,133 @@
+package webreader
+
+import (
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/converter/cbz"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"golang.org/x/exp/slices"
+	"path/filepath"
+	"sync"
+)
+
+// pages of the chapter to serve
+type pages interface {
+	// count returns the number of pages
+	count() int
+	// image returns the contents of the page with the given index
+	image(index int) ([]byte, error)
+}
+
+// pagesOf returns pages of the downloaded chapter if it is available,
+// otherwise streams them from the source
+func pagesOf(chapter *source.Chapter) (pages, error) {
+	if viper.GetBool(key.DownloaderReadDownloaded) && chapter.IsDownloaded() {
+		path := chapter.PeekPath()
+
+		switch viper.GetString(key.FormatsUse) {
+		case constant.FormatCBZ, constant.FormatZIP:
+			return newArchivePages(path)
+		case constant.FormatPlain:
+			return newDirPages(path)
+		}
+	}
+
+	return newStreamPages(chapter)
+}
+
+// archivePages are pages from the zip archive
+type archivePages struct {
+	path  string
+	names []string
+}
+
+func newArchivePages(path string) (*archivePages, error) {
+	names, err := cbz.Pages(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &archivePages{path: path, names: names}, nil
+}
+
+func (a *archivePages) count() int {
+	return len(a.names)
+}
+
+func (a *archivePages) image(index int) ([]byte, error) {
+	return cbz.ReadPage(a.path, a.names[index])
+}
+
+// dirPages are pages from the directory, sorted by name
+type dirPages struct {
+	paths []string
+}
+
+func newDirPages(path string) (*dirPages, error) {
+	entries, err := afero.ReadDir(filesystem.Api(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && cbz.IsImage(entry.Name()) {
+			paths = append(paths, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	slices.Sort(paths)
+	return &dirPages{paths: paths}, nil
+}
+
+func (d *dirPages) count() int {
+	return len(d.paths)
+}
+
+func (d *dirPages) image(index int) ([]byte, error) {
+	return filesystem.Api().ReadFile(d.paths[index])
+}
+
+// streamPages are downloaded from the source on demand and kept in memory
+type streamPages struct {
+	pages   []*source.Page
+	mutexes []sync.Mutex
+}
+
+func newStreamPages(chapter *source.Chapter) (*streamPages, error) {
+	pages, err := chapter.Source().PagesOf(chapter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamPages{
+		pages:   pages,
+		mutexes: make([]sync.Mutex, len(pages)),
+	}, nil
+}
+
+func (s *streamPages) count() int {
+	return len(s.pages)
+}
+
+func (s *streamPages) image(index int) ([]byte, error) {
+	s.mutexes[index].Lock()
+	defer s.mutexes[index].Unlock()
+
+	page := s.pages[index]
+	if page.Contents == nil {
+		// failed downloads are retried on the next request
+		if err := page.Download(); err != nil {
+			return nil, err
+		}
+	}
+
+	if page.Contents == nil {
+		return nil, nil
+	}
+
+	return page.Contents.Bytes(), nil
+}
@@ -0,0 +1,288 @@
+// Package webreader implements the built-in reader
+// that serves chapter pages to the browser over the local http server.
+package webreader
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/metafates/mangal/history"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/log"
+	"github.com/metafates/mangal/network"
+	"github.com/metafates/mangal/source"
+	"github.com/spf13/viper"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// Mode of the reader
+const (
+	ModePaged = "paged"
+	ModeStrip = "strip"
+)
+
+// Reader serves the chapters of the manga being read.
+type Reader struct {
+	mutex    sync.Mutex
+	token    string
+	chapters []*source.Chapter
+	current  int
+	pages    map[*source.Chapter]pages
+	mux      *http.ServeMux
+}
+
+// New creates a new reader. If token is not empty, every request must be authorized with it.
+func New(token string) *Reader {
+	r := &Reader{
+		token: token,
+		pages: make(map[*source.Chapter]pages),
+		mux:   http.NewServeMux(),
+	}
+
+	r.mux.HandleFunc("/", r.handleIndex)
+	r.mux.HandleFunc("/api/chapter", r.handleChapter)
+	r.mux.HandleFunc("/api/page", r.handlePage)
+
+	return r
+}
+
+// Read makes the chapter the current one.
+// Other chapters of its manga are available for next and previous navigation.
+func (r *Reader) Read(chapter *source.Chapter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	chapters := chapter.Manga.Chapters
+	if len(chapters) == 0 {
+		chapters = []*source.Chapter{chapter}
+	}
+
+	r.chapters = chapters
+	r.current = 0
+	for i, c := range chapters {
+		if c == chapter || (chapter.URL != "" && source.SameURL(c.URL, chapter.URL)) {
+			r.current = i
+			break
+		}
+	}
+
+	// pages of the previous manga are not needed anymore
+	r.pages = make(map[*source.Chapter]pages)
+}
+
+func (r *Reader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !network.Authorized(req, r.token) {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	r.mux.ServeHTTP(w, req)
+}
+
+// chapter returns the chapter by its position, or the current one if index is empty
+func (r *Reader) chapter(index string) (int, *source.Chapter, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.chapters) == 0 {
+		return 0, nil, errors.New("nothing to read")
+	}
+
+	if index == "" {
+		return r.current, r.chapters[r.current], nil
+	}
+
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(r.chapters) {
+		return 0, nil, fmt.Errorf("invalid chapter: %s", index)
+	}
+
+	return i, r.chapters[i], nil
+}
+
+// pagesOf returns the cached pages of the chapter
+func (r *Reader) pagesOf(chapter *source.Chapter) (pages, error) {
+	r.mutex.Lock()
+	p, ok := r.pages[chapter]
+	r.mutex.Unlock()
+
+	if ok {
+		return p, nil
+	}
+
+	p, err := pagesOf(chapter)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.pages[chapter] = p
+	r.mutex.Unlock()
+
+	return p, nil
+}
+
+func (r *Reader) handleIndex(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(indexHTML)
+}
+
+// chapterInfo is the response of the chapter endpoint
+type chapterInfo struct {
+	Manga  string `json:"manga"`
+	Name   string `json:"name"`
+	Volume string `json:"volume"`
+	Index  int    `json:"index"`
+	Pages  int    `json:"pages"`
+	Prev   bool   `json:"prev"`
+	Next   bool   `json:"next"`
+	Mode   string `json:"mode"`
+}
+
+// GET /api/chapter?chapter=<index>
+func (r *Reader) handleChapter(w http.ResponseWriter, req *http.Request) {
+	index, chapter, err := r.chapter(req.URL.Query().Get("chapter"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	p, err := r.pagesOf(chapter)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if viper.GetBool(key.HistorySaveOnRead) {
+		go func() {
+			if err := history.Save(chapter); err != nil {
+				log.Warn(err)
+			} else {
+				log.Info("history saved")
+			}
+		}()
+	}
+
+	r.mutex.Lock()
+	r.current = index
+	info := &chapterInfo{
+		Manga:  chapter.Manga.Name,
+		Name:   chapter.Name,
+		Volume: chapter.Volume,
+		Index:  index,
+		Pages:  p.count(),
+		Prev:   index > 0,
+		Next:   index < len(r.chapters)-1,
+		Mode:   viper.GetString(key.ReaderBuiltinMode),
+	}
+	r.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(info); err != nil {
+		log.Warn(err)
+	}
+}
+
+// GET /api/page?chapter=<index>&page=<index>
+func (r *Reader) handlePage(w http.ResponseWriter, req *http.Request) {
+	_, chapter, err := r.chapter(req.URL.Query().Get("chapter"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	p, err := r.pagesOf(chapter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil || page < 0 || page >= p.count() {
+		http.Error(w, "invalid page", http.StatusNotFound)
+		return
+	}
+
+	image, err := p.image(page)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(image))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	_, _ = w.Write(image)
+}
+
+var (
+	instance     *Reader
+	instanceURL  string
+	instanceErr  error
+	instanceOnce sync.Once
+	served       = make(chan struct{})
+)
+
+// Open starts the reader server if it is not running, makes the chapter current
+// and returns the URL of the reader.
+// The server is stopped when the program exits.
+func Open(chapter *source.Chapter) (string, error) {
+	instanceOnce.Do(func() {
+		token := viper.GetString(key.ServerToken)
+		address := net.JoinHostPort(viper.GetString(key.ServerHost), strconv.Itoa(viper.GetInt(key.ReaderBuiltinPort)))
+
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			instanceErr = err
+			return
+		}
+
+		instance = New(token)
+		instanceURL = "http://" + listener.Addr().String() + "/"
+		if token != "" {
+			instanceURL += "?token=" + token
+		}
+
+		go func() {
+			defer close(served)
+			if err := http.Serve(listener, instance); err != nil {
+				log.Error(err)
+			}
+		}()
+	})
+
+	if instanceErr != nil {
+		return "", instanceErr
+	}
+
+	instance.Read(chapter)
+	return instanceURL, nil
+}
+
+// Running returns the URL of the reader if it was opened
+func Running() (string, bool) {
+	return instanceURL, instance != nil
+}
+
+// Wait blocks while the reader server is running.
+// Returns immediately if the reader was not opened.
+func Wait() {
+	if instance == nil {
+		return
+	}
+
+	<-served
+}
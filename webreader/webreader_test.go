@@ -0,0 +1,143 @@
+package webreader
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"github.com/metafates/mangal/constant"
+	"github.com/metafates/mangal/filesystem"
+	"github.com/metafates/mangal/key"
+	"github.com/metafates/mangal/source"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	filesystem.SetMemMapFs()
+}
+
+type testSource struct{}
+
+func (testSource) Name() string {
+	return "test"
+}
+
+func (testSource) ID() string {
+	return "test"
+}
+
+func (testSource) Search(string) ([]*source.Manga, error) {
+	panic("")
+}
+
+func (testSource) ChaptersOf(*source.Manga) ([]*source.Chapter, error) {
+	panic("")
+}
+
+func (testSource) PagesOf(*source.Chapter) ([]*source.Page, error) {
+	panic("")
+}
+
+// testChapters returns chapters of the manga that are downloaded as cbz archives with 2 pages each
+func testChapters() []*source.Chapter {
+	manga := &source.Manga{Name: "test", Source: testSource{}}
+	for i := 1; i <= 3; i++ {
+		chapter := &source.Chapter{Name: fmt.Sprintf("Chapter %d", i), Index: uint16(i), Manga: manga}
+		manga.Chapters = append(manga.Chapters, chapter)
+
+		path := chapter.PeekPath()
+		So(filesystem.Api().MkdirAll(filepath.Dir(path), os.ModePerm), ShouldBeNil)
+
+		file, err := filesystem.Api().Create(path)
+		So(err, ShouldBeNil)
+
+		writer := zip.NewWriter(file)
+		for _, name := range []string{"0002.png", "0001.png", "ComicInfo.xml"} {
+			w, err := writer.Create(name)
+			So(err, ShouldBeNil)
+			_, err = w.Write([]byte(name))
+			So(err, ShouldBeNil)
+		}
+
+		So(writer.Close(), ShouldBeNil)
+		So(file.Close(), ShouldBeNil)
+	}
+
+	return manga.Chapters
+}
+
+func get(handler http.Handler, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder
+}
+
+func TestReader(t *testing.T) {
+	Convey("Given a reader of the downloaded chapter", t, func() {
+		viper.Set(key.FormatsUse, constant.FormatCBZ)
+		viper.Set(key.DownloaderChapterNameTemplate, "{chapter}")
+		viper.Set(key.DownloaderReadDownloaded, true)
+		viper.Set(key.HistorySaveOnRead, false)
+		viper.Set(key.ReaderBuiltinMode, ModeStrip)
+
+		chapters := testChapters()
+		reader := New("")
+		reader.Read(chapters[1])
+
+		Convey("When getting the current chapter", func() {
+			recorder := get(reader, "/api/chapter")
+
+			var info chapterInfo
+			So(json.Unmarshal(recorder.Body.Bytes(), &info), ShouldBeNil)
+
+			Convey("Then it should have navigation to both sides", func() {
+				So(recorder.Code, ShouldEqual, http.StatusOK)
+				So(info.Name, ShouldEqual, "Chapter 2")
+				So(info.Index, ShouldEqual, 1)
+				So(info.Prev, ShouldBeTrue)
+				So(info.Next, ShouldBeTrue)
+				So(info.Mode, ShouldEqual, ModeStrip)
+			})
+
+			Convey("Then only images should be counted as pages", func() {
+				So(info.Pages, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When getting the last chapter", func() {
+			var info chapterInfo
+			So(json.Unmarshal(get(reader, "/api/chapter?chapter=2").Body.Bytes(), &info), ShouldBeNil)
+
+			Convey("Then it should not have the next chapter", func() {
+				So(info.Name, ShouldEqual, "Chapter 3")
+				So(info.Next, ShouldBeFalse)
+			})
+		})
+
+		Convey("When getting pages", func() {
+			Convey("Then they should be sorted by name", func() {
+				So(get(reader, "/api/page?chapter=1&page=0").Body.String(), ShouldEqual, "0001.png")
+				So(get(reader, "/api/page?chapter=1&page=1").Body.String(), ShouldEqual, "0002.png")
+			})
+
+			Convey("Then invalid pages should not be found", func() {
+				So(get(reader, "/api/page?chapter=1&page=2").Code, ShouldEqual, http.StatusNotFound)
+				So(get(reader, "/api/page?chapter=5&page=0").Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
+		Convey("When the token is required", func() {
+			reader.token = "secret"
+
+			Convey("Then requests without it should be unauthorized", func() {
+				So(get(reader, "/").Code, ShouldEqual, http.StatusUnauthorized)
+				So(get(reader, "/?token=secret").Code, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+}
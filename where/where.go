@@ -39,10 +39,42 @@ func Sources() string {
 	return mkdir(filepath.Join(Config(), "sources"))
 }
 
+// SourcesBackup path to the previous versions of the updated sources
+// Will create the directory if it doesn't exist
+func SourcesBackup() string {
+	return mkdir(filepath.Join(Config(), "sources_backup"))
+}
+
+// SourceFixtures path to the recorded HTTP fixtures of the sources
+// Will create the directory if it doesn't exist
+func SourceFixtures() string {
+	return mkdir(filepath.Join(Config(), "sources_fixtures"))
+}
+
 func AnilistBinds() string {
 	return filepath.Join(Config(), "anilist.json")
 }
 
+// AnilistMappings path to the file with chapter mapping rules
+func AnilistMappings() string {
+	return filepath.Join(Config(), "anilist_mappings.json")
+}
+
+// AnilistToken path to the file with Anilist access token
+func AnilistToken() string {
+	return filepath.Join(Config(), "anilist_token.json")
+}
+
+// MyAnimeListToken path to the file with MyAnimeList tokens
+func MyAnimeListToken() string {
+	return filepath.Join(Config(), "myanimelist.json")
+}
+
+// Outbox path to the file with pending tracker updates
+func Outbox() string {
+	return filepath.Join(Config(), "outbox.json")
+}
+
 // Logs path
 // Will create the directory if it doesn't exist
 func Logs() string {